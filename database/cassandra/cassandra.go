@@ -22,8 +22,6 @@ func init() {
 }
 
 var (
-	multiStmtDelimiter = []byte(";")
-
 	DefaultMultiStatementMaxSize = 10 * 1 << 20 // 10 MB
 )
 
@@ -197,13 +195,13 @@ func (c *Cassandra) Unlock() error {
 func (c *Cassandra) Run(migration io.Reader) error {
 	if c.config.MultiStatementEnabled {
 		var err error
-		if e := multistmt.Parse(migration, multiStmtDelimiter, c.config.MultiStatementMaxSize, func(m []byte) bool {
-			tq := strings.TrimSpace(string(m))
+		if e := multistmt.Split(migration, multistmt.CQL, c.config.MultiStatementMaxSize, func(s multistmt.Statement) bool {
+			tq := strings.TrimSpace(string(s.Text))
 			if tq == "" {
 				return true
 			}
 			if e := c.session.Query(tq).Exec(); e != nil {
-				err = database.Error{OrigErr: e, Err: "migration failed", Query: m}
+				err = database.Error{OrigErr: e, Err: "migration failed", Query: s.Text, Line: s.Line}
 				return false
 			}
 			return true
@@ -19,8 +19,6 @@ import (
 )
 
 var (
-	multiStmtDelimiter = []byte(";")
-
 	DefaultMigrationsTable       = "schema_migrations"
 	DefaultMigrationsTableEngine = "TinyLog"
 	DefaultMultiStatementMaxSize = 10 * 1 << 20 // 10 MB
@@ -131,13 +129,13 @@ func (ch *ClickHouse) init() error {
 func (ch *ClickHouse) Run(r io.Reader) error {
 	if ch.config.MultiStatementEnabled {
 		var err error
-		if e := multistmt.Parse(r, multiStmtDelimiter, ch.config.MultiStatementMaxSize, func(m []byte) bool {
-			tq := strings.TrimSpace(string(m))
+		if e := multistmt.Split(r, multistmt.ClickHouse, ch.config.MultiStatementMaxSize, func(s multistmt.Statement) bool {
+			tq := strings.TrimSpace(string(s.Text))
 			if tq == "" {
 				return true
 			}
-			if _, e := ch.conn.Exec(string(m)); e != nil {
-				err = database.Error{OrigErr: e, Err: "migration failed", Query: m}
+			if _, e := ch.conn.Exec(string(s.Text)); e != nil {
+				err = database.Error{OrigErr: e, Err: "migration failed", Query: s.Text, Line: s.Line}
 				return false
 			}
 			return true
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directivePrefix starts a directive comment in the header of a migration:
+//  -- migrate:no-transaction
+//  -- migrate:timeout=30m
+//  -- migrate:multi-statement
+//  -- migrate:tags=seed,staging
+//  -- migrate:phase=expand
+//  -- migrate:depends_on=20210101120000,20210102090000
+//  -- migrate:safety-ignore=drop-column
+const directivePrefix = "migrate:"
+
+// directiveNames are the names of the known directives. Other comments
+// starting with "migrate:", e.g. "-- migrate: see ticket 123", are not
+// directives and are ignored.
+var directiveNames = map[string]bool{
+	"no-transaction":  true,
+	"multi-statement": true,
+	"timeout":         true,
+	"tags":            true,
+	"phase":           true,
+	"depends_on":      true,
+	"safety-ignore":   true,
+}
+
+// Phases of expand/contract migrations, see RunOptions.Phase.
+const (
+	// PhaseExpand migrations are applied before a new version of an
+	// application is rolled out, e.g. adding a column.
+	PhaseExpand = "expand"
+
+	// PhaseContract migrations are applied after the rollout, e.g. dropping
+	// a column that is no longer used.
+	PhaseContract = "contract"
+)
+
+// RunOptions holds the per migration options set by directives in the
+// header of a migration, see ParseDirectives.
+type RunOptions struct {
+	// NoTransaction runs the migration outside of a transaction,
+	// e.g. for CREATE INDEX CONCURRENTLY.
+	NoTransaction bool
+
+	// Timeout limits the run time of each statement. Zero keeps the
+	// timeout configured for the driver.
+	Timeout time.Duration
+
+	// MultiStatement splits the migration into statements that are run
+	// one by one.
+	MultiStatement bool
+
+	// Tags are the tags of the migration.
+	Tags []string
+
+	// Phase is PhaseExpand or PhaseContract. It is empty if the migration
+	// doesn't declare a phase.
+	Phase string
+
+	// DependsOn are the versions of the migrations this migration depends
+	// on, see source/graph.
+	DependsOn []uint
+}
+
+// OptionsRunner is an optional interface for drivers that support
+// RunOptions. migrate calls RunWithOptions instead of Run for such drivers.
+type OptionsRunner interface {
+	// RunWithOptions is like Run, but applies options to this migration
+	// only. Options override the driver's configuration.
+	RunWithOptions(migration io.Reader, options RunOptions) error
+}
+
+// ParseDirectives reads the directives in the header of migration, the
+// leading lines that are blank or start with "--". Parsing stops at the first
+// other line. The returned reader yields the complete, unchanged migration.
+func ParseDirectives(migration io.Reader) (RunOptions, io.Reader, error) {
+	var options RunOptions
+	r, err := readHeader(migration, func(directive string) error {
+		if !directiveNames[directiveName(directive)] {
+			return nil
+		}
+		return options.parse(directive)
+	})
+	if err != nil {
+		return RunOptions{}, nil, err
+	}
+	return options, r, nil
+}
+
+// UnknownDirectives returns the comments in the header of migration that look
+// like directives, "migrate:" followed by a single word, but aren't known,
+// e.g. a misspelled "migrate:no-transacton". ParseDirectives ignores them.
+func UnknownDirectives(migration io.Reader) ([]string, error) {
+	var unknown []string
+	_, err := readHeader(migration, func(directive string) error {
+		name := directiveName(directive)
+		// -- migrate:up and -- migrate:down are dbmate's section markers
+		if !directiveNames[name] && name != "up" && name != "down" &&
+			name != "" && !strings.ContainsAny(directive, " \t") {
+			unknown = append(unknown, directivePrefix+directive)
+		}
+		return nil
+	})
+	return unknown, err
+}
+
+// readHeader calls fn with the directive comments in the header of migration,
+// without directivePrefix. It returns a reader yielding the complete migration.
+func readHeader(migration io.Reader, fn func(directive string) error) (io.Reader, error) {
+	var header bytes.Buffer
+	r := bufio.NewReader(migration)
+	for {
+		line, err := r.ReadString('\n')
+		header.WriteString(line)
+		text := strings.TrimSpace(line)
+		if text != "" && !strings.HasPrefix(text, "--") {
+			break
+		}
+		if d := strings.TrimSpace(strings.TrimPrefix(text, "--")); strings.HasPrefix(d, directivePrefix) {
+			if ferr := fn(strings.TrimPrefix(d, directivePrefix)); ferr != nil {
+				return nil, ferr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return io.MultiReader(&header, r), nil
+}
+
+// directiveName returns the name of directive, e.g. timeout for timeout=30m.
+func directiveName(directive string) string {
+	if i := strings.Index(directive, "="); i >= 0 {
+		return strings.TrimSpace(directive[:i])
+	}
+	return strings.TrimSpace(directive)
+}
+
+func (o *RunOptions) parse(directive string) error {
+	name, value := directive, ""
+	if i := strings.Index(directive, "="); i >= 0 {
+		name, value = strings.TrimSpace(directive[:i]), strings.TrimSpace(directive[i+1:])
+	}
+	switch name {
+	case "no-transaction":
+		o.NoTransaction = true
+	case "multi-statement":
+		o.MultiStatement = true
+	case "timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid directive %q: %w", directivePrefix+directive, err)
+		}
+		o.Timeout = timeout
+	case "tags":
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				o.Tags = append(o.Tags, tag)
+			}
+		}
+	case "phase":
+		if value != PhaseExpand && value != PhaseContract {
+			return fmt.Errorf("invalid directive %q: phase must be %q or %q", directivePrefix+directive, PhaseExpand, PhaseContract)
+		}
+		o.Phase = value
+	case "depends_on":
+		for _, dep := range strings.Split(value, ",") {
+			if dep = strings.TrimSpace(dep); dep == "" {
+				continue
+			}
+			version, err := strconv.ParseUint(dep, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid directive %q: %w", directivePrefix+directive, err)
+			}
+			o.DependsOn = append(o.DependsOn, uint(version))
+		}
+	case "safety-ignore":
+		// read by the checks of database/safety, it doesn't change how the
+		// migration runs
+	default:
+		return fmt.Errorf("unknown directive %q", directivePrefix+directive)
+	}
+	return nil
+}
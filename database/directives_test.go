@@ -0,0 +1,111 @@
+package database
+
+import (
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDirectives(t *testing.T) {
+	cases := []struct {
+		name      string
+		migration string
+		expected  RunOptions
+		expectErr bool
+	}{
+		{name: "none", migration: "CREATE TABLE t (id int);"},
+		{name: "empty", migration: ""},
+		{
+			name:      "no-transaction",
+			migration: "-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY i ON t (id);",
+			expected:  RunOptions{NoTransaction: true},
+		},
+		{
+			name:      "all",
+			migration: "-- add index\n--migrate:timeout=30m\n\n-- migrate:multi-statement\n-- migrate:tags=seed, staging\nSELECT 1;",
+			expected:  RunOptions{Timeout: 30 * time.Minute, MultiStatement: true, Tags: []string{"seed", "staging"}},
+		},
+		{
+			name:      "after first statement",
+			migration: "SELECT 1;\n-- migrate:no-transaction\n",
+		},
+		{
+			name:      "invalid timeout",
+			migration: "-- migrate:timeout=soon\nSELECT 1;",
+			expectErr: true,
+		},
+		{
+			name:      "phase",
+			migration: "-- migrate:phase=contract\nALTER TABLE t DROP COLUMN c;",
+			expected:  RunOptions{Phase: PhaseContract},
+		},
+		{
+			name:      "invalid phase",
+			migration: "-- migrate:phase=migrate\nSELECT 1;",
+			expectErr: true,
+		},
+		{
+			name:      "depends_on",
+			migration: "-- migrate:depends_on=20210101120000, 3\nSELECT 1;",
+			expected:  RunOptions{DependsOn: []uint{20210101120000, 3}},
+		},
+		{
+			name:      "invalid depends_on",
+			migration: "-- migrate:depends_on=create_users\nSELECT 1;",
+			expectErr: true,
+		},
+		{
+			name:      "safety-ignore",
+			migration: "-- migrate:safety-ignore=drop-column\nALTER TABLE t DROP COLUMN c;",
+			expected:  RunOptions{},
+		},
+		{
+			name:      "unknown",
+			migration: "-- migrate:no-transacton\nSELECT 1;",
+		},
+		{
+			name:      "prose",
+			migration: "-- migrate: see ticket 123\n-- migrate:no-transaction\nSELECT 1;",
+			expected:  RunOptions{NoTransaction: true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			options, r, err := ParseDirectives(strings.NewReader(c.migration))
+			if c.expectErr {
+				if err == nil {
+					t.Fatal("expected err")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(options, c.expected) {
+				t.Errorf("expected %+v, got %+v", c.expected, options)
+			}
+			b, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != c.migration {
+				t.Errorf("expected migration to be unchanged, got %q", b)
+			}
+		})
+	}
+}
+
+func TestUnknownDirectives(t *testing.T) {
+	migration := "-- migrate:no-transacton\n-- migrate: see ticket 123\n-- migrate:up\n-- migrate:timeout=1m\n-- migrate:tag=seed\nSELECT 1;\n-- migrate:after\n"
+	unknown, err := UnknownDirectives(strings.NewReader(migration))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"migrate:no-transacton", "migrate:tag=seed"}
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("expected %v, got %v", expected, unknown)
+	}
+}
@@ -5,10 +5,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"sync"
 
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
 	iurl "github.com/golang-migrate/migrate/v4/internal/url"
 )
 
@@ -81,6 +84,123 @@ type Driver interface {
 	Drop() error
 }
 
+// RepeatableTracker is an optional interface for drivers that can record
+// the checksums of repeatable migrations, see source.RepeatableReader.
+// Checksums are usually kept in a table next to the version table.
+type RepeatableTracker interface {
+	// RepeatableChecksum returns the checksum recorded for the repeatable
+	// migration with the given identifier, or "" if it was never applied.
+	RepeatableChecksum(identifier string) (checksum string, err error)
+
+	// SetRepeatableChecksum records the checksum of an applied repeatable migration.
+	SetRepeatableChecksum(identifier string, checksum string) error
+}
+
+// Baseliner is an optional interface for drivers that keep a history of
+// applied migrations. Migrate calls Baseline after SetVersion when an existing
+// database is adopted with migrate.Migrate.Baseline.
+type Baseliner interface {
+	// Baseline records a baseline entry for version with a human readable description.
+	Baseline(version int, description string) error
+}
+
+// SkipRecorder is an optional interface for drivers that keep a ledger of
+// skipped migrations. Migrate calls Skip when a migration is not run because
+// its tags are not selected, see migrate.Migrate.WithTags. The version walk
+// continues past skipped migrations, so Migrate consults the ledger to not
+// revert them on the way down and to apply them once their tags are selected.
+// Tag filtering requires this interface.
+// Skipped versions are usually kept in a table next to the version table,
+// which is only created by the first Skip, so that databases migrated without
+// tags don't get the table.
+type SkipRecorder interface {
+	// Skip records that the migration version with the given identifier was skipped.
+	Skip(version int, identifier string) error
+
+	// SkippedVersions returns the versions recorded as skipped, in no particular order.
+	SkippedVersions() (versions []int, err error)
+
+	// Unskip removes version from the skipped versions, once it is applied
+	// or the version moved below it.
+	Unskip(version int) error
+}
+
+// PhaseTracker is an optional interface for drivers that can track the
+// versions of expand/contract migrations applied per phase, see
+// RunOptions.Phase. Versions are usually kept in a table next to the version
+// table, which is only created once a version is applied in a phase.
+type PhaseTracker interface {
+	// PhaseVersions returns the versions applied in phase that the current
+	// version didn't move past yet, in no particular order.
+	PhaseVersions(phase string) (versions []int, err error)
+
+	// SetPhaseApplied records whether version is applied in phase.
+	SetPhaseApplied(phase string, version int, applied bool) error
+}
+
+// AppliedTracker is an optional interface for drivers that can record every
+// applied migration version instead of only the current one. It lets migrate
+// apply migrations ordered by their dependencies, see source/graph, even if a
+// migration following them in that order was applied first.
+type AppliedTracker interface {
+	// AppliedVersions returns the versions recorded as applied, in no particular order.
+	AppliedVersions() (versions []int, err error)
+
+	// SetApplied records version as applied or, if applied is false, removes it.
+	SetApplied(version int, applied bool) error
+}
+
+// SchemaDumper is an optional interface for drivers that can dump the schema
+// of the database, e.g. to squash old migrations into a single baseline.
+type SchemaDumper interface {
+	// DumpSchema writes statements recreating the schema of the database,
+	// without data and without the tables of migrate itself, to w.
+	DumpSchema(w io.Writer) error
+}
+
+// SchemaInspector is an optional interface for drivers that can read the
+// tables, columns, constraints, indexes and views of the database, so that two
+// databases can be compared with schemadiff.Diff.
+type SchemaInspector interface {
+	// InspectSchema returns the schema of the database without the tables of
+	// migrate itself.
+	InspectSchema() (*schemadiff.Schema, error)
+}
+
+// TxBeginner is an optional interface for drivers backed by database/sql.
+// It lets migrations written in Go run in a transaction, see source/gofunc.
+type TxBeginner interface {
+	// BeginTx starts a transaction on the connection used for migrations.
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// Namespacer is an optional interface for drivers that can track the versions
+// of several independent migration sequences (namespaces) side by side.
+// It is required when the source driver implements source.Namespacer.
+type Namespacer interface {
+	// Namespace returns a driver sharing the underlying connection whose
+	// Version and SetVersion operate on the given namespace only.
+	// The root namespace "" must use the regular version table.
+	// Closing the returned driver must not close the shared connection.
+	Namespace(name string) (Driver, error)
+}
+
+// Logger is a leveled logger with key-value pairs, see
+// migrate.StructuredLogger.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// LoggerSetter is an optional interface for drivers that log what they do,
+// e.g. the statements they run. Migrate calls SetLogger with its logger before
+// running a migration.
+type LoggerSetter interface {
+	SetLogger(logger Logger)
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	scheme, err := iurl.SchemeFromURL(url)
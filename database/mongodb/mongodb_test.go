@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -122,6 +123,55 @@ func TestMigrate(t *testing.T) {
 	})
 }
 
+func TestDumpSchema(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := mongoConnectionString(ip, port)
+		p := &Mongo{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+
+		schema := `[{"create":"users","validator":{"name":{"$exists":true}}},
+			{"createIndexes":"users","indexes":[{"key":{"name":1},"name":"users_name","unique":true}]}]`
+		if err := d.Run(strings.NewReader(schema)); err != nil {
+			t.Fatal(err)
+		}
+
+		var b strings.Builder
+		if err := d.(*Mongo).DumpSchema(&b); err != nil {
+			t.Fatal(err)
+		}
+		dump := b.String()
+		for _, expected := range []string{`"create": "users"`, `"validator"`, `"createIndexes": "users"`, `"name": "users_name"`} {
+			if !strings.Contains(dump, expected) {
+				t.Errorf("Incorrect dump was: %s wanted %s", dump, expected)
+			}
+		}
+		if strings.Contains(dump, DefaultMigrationsCollection) || strings.Contains(dump, `"_id_"`) {
+			t.Errorf("Incorrect dump was: %s", dump)
+		}
+
+		// the dump is a migration recreating the schema
+		if err := d.Drop(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(strings.NewReader(dump)); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
 func TestWithAuth(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
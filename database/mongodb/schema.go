@@ -0,0 +1,130 @@
+package mongodb
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/BlackMocca/mongo-go-driver/bson"
+)
+
+// DumpSchema implements database.SchemaDumper. It writes a migration creating
+// the collections and views of the database with their options, e.g. the
+// validator, followed by their indexes, as a JSON array of commands.
+func (m *Mongo) DumpSchema(w io.Writer) error {
+	ctx := context.TODO()
+
+	cursor, err := m.db.ListCollections(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	var collections []bson.D
+	if err := cursor.All(ctx, &collections); err != nil {
+		return err
+	}
+	sort.Slice(collections, func(i, j int) bool {
+		return lookupString(collections[i], "name") < lookupString(collections[j], "name")
+	})
+
+	var cmds []bson.D
+	for _, c := range collections {
+		name := lookupString(c, "name")
+		if name == m.config.MigrationsCollection || name == m.config.Locking.CollectionName || strings.HasPrefix(name, "system.") {
+			continue
+		}
+		create := bson.D{{Key: "create", Value: name}}
+		create = append(create, document(lookup(c, "options"))...)
+		cmds = append(cmds, create)
+
+		if lookupString(c, "type") == "view" {
+			continue
+		}
+		indexes, err := m.indexes(ctx, name)
+		if err != nil {
+			return err
+		}
+		if len(indexes) > 0 {
+			cmds = append(cmds, bson.D{{Key: "createIndexes", Value: name}, {Key: "indexes", Value: indexes}})
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("[")
+	for i, cmd := range cmds {
+		out, err := bson.MarshalExtJSONIndent(cmd, false, false, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  ")
+		b.Write(out)
+	}
+	b.WriteString("\n]\n")
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// indexes returns the index specifications of collection, except the index on _id.
+func (m *Mongo) indexes(ctx context.Context, collection string) (bson.A, error) {
+	cursor, err := m.db.Collection(collection).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []bson.D
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+
+	indexes := bson.A{}
+	for _, spec := range specs {
+		if lookupString(spec, "name") == "_id_" {
+			continue
+		}
+		index := bson.D{}
+		for _, e := range spec {
+			// the index version and namespace are set by the server
+			if e.Key != "v" && e.Key != "ns" {
+				index = append(index, e)
+			}
+		}
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return lookupString(indexes[i].(bson.D), "name") < lookupString(indexes[j].(bson.D), "name")
+	})
+	return indexes, nil
+}
+
+func lookup(d bson.D, key string) interface{} {
+	for _, e := range d {
+		if e.Key == key {
+			return e.Value
+		}
+	}
+	return nil
+}
+
+// document returns the embedded document v as bson.D, sorting the keys if
+// it was decoded as bson.M.
+func document(v interface{}) bson.D {
+	switch v := v.(type) {
+	case bson.D:
+		return v
+	case bson.M:
+		d := make(bson.D, 0, len(v))
+		for key, value := range v {
+			d = append(d, bson.E{Key: key, Value: value})
+		}
+		sort.Slice(d, func(i, j int) bool { return d[i].Key < d[j].Key })
+		return d
+	}
+	return nil
+}
+
+func lookupString(d bson.D, key string) string {
+	s, _ := lookup(d, key).(string)
+	return s
+}
@@ -0,0 +1,493 @@
+package multistmt
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Dialect describes the lexical rules Split follows to find the statements of
+// a migration. Delimiters inside string literals, quoted identifiers and
+// comments never end a statement.
+type Dialect struct {
+	// DollarQuoting enables $$ ... $$ and $tag$ ... $tag$ string constants.
+	DollarQuoting bool
+
+	// EscapeStrings enables E'...' string constants with backslash escapes.
+	EscapeStrings bool
+
+	// BackslashEscapes makes a backslash escape the next character in all
+	// string literals.
+	BackslashEscapes bool
+
+	// NestedComments allows /* ... */ comments to nest.
+	NestedComments bool
+
+	// HashComments starts a comment at #.
+	HashComments bool
+
+	// SlashComments starts a comment at //.
+	SlashComments bool
+
+	// Backticks quotes identifiers with `.
+	Backticks bool
+
+	// Brackets quotes identifiers with [ and ].
+	Brackets bool
+
+	// DelimiterCommand enables the DELIMITER command of the mysql client,
+	// which changes the delimiter for the following statements. The custom
+	// delimiter isn't part of the statements.
+	DelimiterCommand bool
+
+	// BatchSeparator, if set, is a keyword on a line of its own that
+	// separates batches, e.g. GO. Batches are split on it only, ; doesn't
+	// end a batch. The separator isn't part of the batches.
+	BatchSeparator string
+
+	// Blocks keeps compound statements like BEGIN ... END, CASE ... END
+	// and BEGIN BATCH ... APPLY BATCH together.
+	Blocks bool
+}
+
+var (
+	// Postgres follows PostgreSQL's rules, including dollar quoting and
+	// BEGIN ATOMIC ... END function bodies.
+	Postgres = Dialect{DollarQuoting: true, EscapeStrings: true, NestedComments: true, Blocks: true}
+
+	// MySQL follows MySQL's rules, including the DELIMITER command and
+	// BEGIN ... END bodies of procedures and triggers.
+	MySQL = Dialect{BackslashEscapes: true, HashComments: true, Backticks: true, DelimiterCommand: true, Blocks: true}
+
+	// TSQL splits T-SQL batches on GO.
+	TSQL = Dialect{Brackets: true, BatchSeparator: "GO"}
+
+	// CQL follows Cassandra's rules, including BEGIN BATCH ... APPLY BATCH.
+	CQL = Dialect{DollarQuoting: true, SlashComments: true, Blocks: true}
+
+	// ClickHouse follows ClickHouse's rules.
+	ClickHouse = Dialect{BackslashEscapes: true, HashComments: true, Backticks: true}
+)
+
+// Statement is a single statement of a multi-statement migration.
+type Statement struct {
+	// Text is the statement without leading white space. It includes the
+	// terminating ;, but not a custom delimiter or batch separator.
+	Text []byte
+
+	// Line is the line of the migration Text starts at, starting at 1.
+	Line uint
+}
+
+// StatementHandler handles a single statement found by Split and returns
+// whether or not further statements should be handled.
+type StatementHandler func(s Statement) bool
+
+// Split splits the migration read from reader into statements following the
+// lexical rules of dialect. Statements consisting of white space and comments
+// only are skipped. bufio.ErrTooLong is returned for statements larger than
+// maxStatementSize.
+func Split(reader io.Reader, dialect Dialect, maxStatementSize int, h StatementHandler) error {
+	src, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s := &splitter{src: src, d: dialect, delimiter: ";", line: 1, max: maxStatementSize, h: h}
+	return s.split()
+}
+
+type splitter struct {
+	src       []byte
+	d         Dialect
+	max       int
+	h         StatementHandler
+	delimiter string
+
+	// pos is the current offset, start the offset of the current statement.
+	pos, start int
+
+	// depth is the number of open blocks.
+	depth int
+
+	// prev is the previous significant token of the current statement,
+	// upper case for words. It's "" at the start of a statement and ";"
+	// after a statement inside a block.
+	prev string
+
+	// line is the line at offset lineOff.
+	line    uint
+	lineOff int
+
+	stop bool
+}
+
+func (s *splitter) split() error {
+	for s.pos < len(s.src) && !s.stop {
+		if s.pos == 0 || s.src[s.pos-1] == '\n' {
+			if ok, err := s.lineCommand(); ok || err != nil {
+				if err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		c := s.src[s.pos]
+		switch {
+		case s.atLineComment(s.pos):
+			s.skipLine()
+		case c == '/' && s.peek(1) == '*':
+			s.skipBlockComment()
+		case c == '\'':
+			backslash := s.d.BackslashEscapes
+			if s.d.EscapeStrings && s.pos > 0 && (s.src[s.pos-1] == 'E' || s.src[s.pos-1] == 'e') &&
+				(s.pos < 2 || !isIdent(s.src[s.pos-2])) {
+				backslash = true
+			}
+			s.skipQuoted('\'', '\'', backslash)
+			s.prev = "'"
+		case c == '"':
+			s.skipQuoted('"', '"', s.d.BackslashEscapes)
+			s.prev = `"`
+		case c == '`' && s.d.Backticks:
+			s.skipQuoted('`', '`', false)
+			s.prev = "`"
+		case c == '[' && s.d.Brackets:
+			s.skipQuoted('[', ']', false)
+			s.prev = "["
+		case c == '$' && s.d.DollarQuoting && s.dollarTag() != "":
+			s.skipDollarQuoted(s.dollarTag())
+			s.prev = "$"
+		case s.d.BatchSeparator == "" && s.atDelimiter():
+			if err := s.delimit(); err != nil {
+				return err
+			}
+		case isIdentStart(c):
+			s.word()
+		default:
+			if !isSpace(c) {
+				s.prev = string(c)
+			}
+			s.pos++
+		}
+	}
+	if s.stop {
+		return nil
+	}
+	return s.emit(len(s.src), len(s.src))
+}
+
+// lineCommand handles the batch separator and the DELIMITER command, which
+// are only recognized on a line of their own.
+func (s *splitter) lineCommand() (bool, error) {
+	end := bytes.IndexByte(s.src[s.pos:], '\n')
+	next := len(s.src)
+	if end >= 0 {
+		end += s.pos
+		next = end + 1
+	} else {
+		end = len(s.src)
+	}
+	line := strings.TrimSpace(string(s.src[s.pos:end]))
+
+	if s.d.BatchSeparator != "" && strings.EqualFold(line, s.d.BatchSeparator) {
+		if err := s.emit(s.pos, next); err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+
+	if s.d.DelimiterCommand && len(line) > len("DELIMITER ") && strings.EqualFold(line[:len("DELIMITER ")], "DELIMITER ") &&
+		len(bytes.TrimSpace(s.src[s.start:s.pos])) == 0 {
+		s.delimiter = strings.TrimSpace(line[len("DELIMITER "):])
+		s.pos, s.start = next, next
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *splitter) atDelimiter() bool {
+	if !bytes.HasPrefix(s.src[s.pos:], []byte(s.delimiter)) {
+		return false
+	}
+	return s.delimiter != ";" || s.depth == 0
+}
+
+// delimit ends the current statement at the delimiter, or a statement inside
+// a block if the delimiter is ; and a block is open.
+func (s *splitter) delimit() error {
+	if s.delimiter == ";" {
+		return s.emit(s.pos+1, s.trailing(s.pos+1))
+	}
+	return s.emit(s.pos, s.trailing(s.pos+len(s.delimiter)))
+}
+
+// trailing returns the offset after the white space and comments following a
+// statement at i up to the end of the line, so that they don't start the next
+// statement. It returns i if the line holds another statement.
+func (s *splitter) trailing(i int) int {
+	j := i
+	for {
+		for j < len(s.src) && (s.src[j] == ' ' || s.src[j] == '\t' || s.src[j] == '\r') {
+			j++
+		}
+		switch {
+		case j == len(s.src):
+			return j
+		case s.src[j] == '\n':
+			return j + 1
+		case s.atLineComment(j):
+			if end := bytes.IndexByte(s.src[j:], '\n'); end >= 0 {
+				return j + end + 1
+			}
+			return len(s.src)
+		case s.src[j] == '/' && j+1 < len(s.src) && s.src[j+1] == '*':
+			pos := s.pos
+			s.pos = j
+			s.skipBlockComment()
+			j, s.pos = s.pos, pos
+			if bytes.IndexByte(s.src[i:j], '\n') >= 0 {
+				// a block comment spanning several lines belongs to the
+				// next statement
+				return i
+			}
+		default:
+			return i
+		}
+	}
+}
+
+// emit hands src[start:end] to the handler and continues at next.
+func (s *splitter) emit(end, next int) error {
+	text := s.src[s.start:end]
+	offset := s.start
+	for len(text) > 0 && isSpace(text[0]) {
+		text = text[1:]
+		offset++
+	}
+	line := s.lineAt(offset)
+	empty := s.prev == ""
+	s.pos, s.start = next, next
+	s.prev = ""
+	s.depth = 0
+
+	if empty {
+		// white space and comments only
+		return nil
+	}
+	if s.max > 0 && len(text) > s.max {
+		return bufio.ErrTooLong
+	}
+	if !s.h(Statement{Text: text, Line: line}) {
+		s.stop = true
+	}
+	return nil
+}
+
+// lineAt returns the line of offset. Offsets must not decrease.
+func (s *splitter) lineAt(offset int) uint {
+	s.line += uint(bytes.Count(s.src[s.lineOff:offset], []byte("\n")))
+	s.lineOff = offset
+	return s.line
+}
+
+// word reads an identifier or keyword and tracks blocks.
+func (s *splitter) word() {
+	start := s.pos
+	for s.pos < len(s.src) && isIdent(s.src[s.pos]) {
+		if s.pos > start && s.delimiter != ";" && bytes.HasPrefix(s.src[s.pos:], []byte(s.delimiter)) {
+			// a custom delimiter may follow a word directly, e.g. END$$
+			break
+		}
+		s.pos++
+	}
+	w := strings.ToUpper(string(s.src[start:s.pos]))
+	if s.d.Blocks {
+		s.block(w)
+	}
+	s.prev = w
+}
+
+func (s *splitter) block(w string) {
+	switch w {
+	case "BEGIN":
+		next := s.nextToken()
+		if s.prev == "" || s.prev == ";" {
+			// BEGIN starts a transaction at the start of a top level
+			// statement, unless it starts a batch
+			if s.depth > 0 || next == "BATCH" || next == "UNLOGGED" || next == "COUNTER" {
+				s.depth++
+			}
+			return
+		}
+		if isIdentifierBefore(s.prev) || isIdentifierAfter(next) {
+			return
+		}
+		s.depth++
+	case "CASE":
+		s.depth++
+	case "END":
+		if s.depth == 0 {
+			return
+		}
+		switch next := s.nextToken(); next {
+		case "IF", "LOOP", "WHILE", "REPEAT":
+			// closes a control statement, which didn't open a block
+		default:
+			if !isIdentifierBefore(s.prev) {
+				s.depth--
+			}
+		}
+	case "APPLY":
+		if s.depth > 0 && s.nextToken() == "BATCH" {
+			s.depth--
+		}
+	}
+}
+
+// nextToken returns the next word in upper case or the next non-space
+// character, without moving pos.
+func (s *splitter) nextToken() string {
+	i := s.pos
+	for i < len(s.src) && isSpace(s.src[i]) {
+		i++
+	}
+	if i == len(s.src) {
+		return ""
+	}
+	if !isIdentStart(s.src[i]) {
+		return string(s.src[i])
+	}
+	j := i
+	for j < len(s.src) && isIdent(s.src[j]) {
+		j++
+	}
+	return strings.ToUpper(string(s.src[i:j]))
+}
+
+// isIdentifierBefore reports whether a keyword following prev is used as
+// column or table name, e.g. in (begin int, end int).
+func isIdentifierBefore(prev string) bool {
+	switch prev {
+	case ",", "(", ".", "COLUMN", "ADD":
+		return true
+	}
+	return false
+}
+
+// isIdentifierAfter reports whether a keyword followed by next is used as
+// column or table name, e.g. in SET begin = 1.
+func isIdentifierAfter(next string) bool {
+	switch next {
+	case ",", ")", ".", "=":
+		return true
+	}
+	return false
+}
+
+func (s *splitter) peek(n int) byte {
+	if s.pos+n < len(s.src) {
+		return s.src[s.pos+n]
+	}
+	return 0
+}
+
+// atLineComment reports whether a comment up to the end of the line starts
+// at i.
+func (s *splitter) atLineComment(i int) bool {
+	if i+1 >= len(s.src) {
+		return s.d.HashComments && i < len(s.src) && s.src[i] == '#'
+	}
+	c, next := s.src[i], s.src[i+1]
+	return c == '-' && next == '-' ||
+		c == '#' && s.d.HashComments ||
+		c == '/' && next == '/' && s.d.SlashComments
+}
+
+func (s *splitter) skipLine() {
+	for s.pos < len(s.src) && s.src[s.pos] != '\n' {
+		s.pos++
+	}
+}
+
+func (s *splitter) skipBlockComment() {
+	depth := 0
+	for s.pos < len(s.src) {
+		switch {
+		case s.src[s.pos] == '/' && s.peek(1) == '*':
+			if depth == 0 || s.d.NestedComments {
+				depth++
+			}
+			s.pos += 2
+		case s.src[s.pos] == '*' && s.peek(1) == '/':
+			depth--
+			s.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			s.pos++
+		}
+	}
+}
+
+// skipQuoted skips a literal from open to close. A doubled close character
+// escapes it.
+func (s *splitter) skipQuoted(open, close byte, backslash bool) {
+	s.pos++
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		switch {
+		case backslash && c == '\\':
+			s.pos += 2
+		case c == close && s.peek(1) == close:
+			s.pos += 2
+		case c == close:
+			s.pos++
+			return
+		default:
+			s.pos++
+		}
+	}
+}
+
+// dollarTag returns the tag starting at pos, e.g. $$ or $body$, or "" if
+// there is none, e.g. for a positional parameter like $1.
+func (s *splitter) dollarTag() string {
+	if s.pos > 0 && isIdent(s.src[s.pos-1]) {
+		return ""
+	}
+	i := s.pos + 1
+	if i < len(s.src) && isIdentStart(s.src[i]) {
+		for i < len(s.src) && isIdent(s.src[i]) && s.src[i] != '$' {
+			i++
+		}
+	}
+	if i < len(s.src) && s.src[i] == '$' {
+		return string(s.src[s.pos : i+1])
+	}
+	return ""
+}
+
+func (s *splitter) skipDollarQuoted(tag string) {
+	s.pos += len(tag)
+	if i := bytes.Index(s.src[s.pos:], []byte(tag)); i >= 0 {
+		s.pos += i + len(tag)
+		return
+	}
+	s.pos = len(s.src)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdent(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c == '$'
+}
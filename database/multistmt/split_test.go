@@ -0,0 +1,127 @@
+package multistmt_test
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/golang-migrate/migrate/v4/database/multistmt"
+)
+
+func TestSplit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		dialect       multistmt.Dialect
+		migration     string
+		expected      []string
+		expectedLines []uint
+	}{
+		{name: "simple", dialect: multistmt.Postgres,
+			migration: "CREATE TABLE a (id int);\nCREATE TABLE b (id int);\n",
+			expected:  []string{"CREATE TABLE a (id int);", "CREATE TABLE b (id int);"}, expectedLines: []uint{1, 2}},
+		{name: "no trailing delimiter", dialect: multistmt.Postgres,
+			migration: "SELECT 1;\n\n  SELECT 2",
+			expected:  []string{"SELECT 1;", "SELECT 2"}, expectedLines: []uint{1, 3}},
+		{name: "string literal", dialect: multistmt.Postgres,
+			migration: "INSERT INTO t VALUES ('a;b', 'it''s;');\nSELECT 1;",
+			expected:  []string{"INSERT INTO t VALUES ('a;b', 'it''s;');", "SELECT 1;"}, expectedLines: []uint{1, 2}},
+		{name: "escape string", dialect: multistmt.Postgres,
+			migration: "SELECT E'\\';';SELECT 1;",
+			expected:  []string{"SELECT E'\\';';", "SELECT 1;"}, expectedLines: []uint{1, 1}},
+		{name: "comments", dialect: multistmt.Postgres,
+			migration: "-- comment;\nSELECT 1; /* a; /* nested; */ b; */\nSELECT 2;\n-- trailing;\n",
+			expected:  []string{"-- comment;\nSELECT 1;", "SELECT 2;"}, expectedLines: []uint{1, 3}},
+		{name: "trailing comment", dialect: multistmt.Postgres,
+			migration: "SELECT 1; -- first\nSELECT 2; /* second */ -- 2\n/* third\n*/ SELECT 3;",
+			expected:  []string{"SELECT 1;", "SELECT 2;", "/* third\n*/ SELECT 3;"}, expectedLines: []uint{1, 2, 3}},
+		{name: "dollar quoting", dialect: multistmt.Postgres,
+			migration: "CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;\nDO $$ BEGIN PERFORM 1; END $$;\nSELECT $1;",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS trigger AS $body$\nBEGIN\n  RETURN NEW;\nEND;\n$body$ LANGUAGE plpgsql;",
+				"DO $$ BEGIN PERFORM 1; END $$;",
+				"SELECT $1;",
+			}, expectedLines: []uint{1, 6, 7}},
+		{name: "begin atomic", dialect: multistmt.Postgres,
+			migration: "CREATE FUNCTION f() RETURNS int LANGUAGE sql\nBEGIN ATOMIC\n  SELECT 1;\nEND;\nBEGIN;\nSELECT CASE WHEN true THEN 1 END;\nCOMMIT;",
+			expected: []string{
+				"CREATE FUNCTION f() RETURNS int LANGUAGE sql\nBEGIN ATOMIC\n  SELECT 1;\nEND;",
+				"BEGIN;",
+				"SELECT CASE WHEN true THEN 1 END;",
+				"COMMIT;",
+			}, expectedLines: []uint{1, 5, 6, 7}},
+		{name: "columns named begin and end", dialect: multistmt.Postgres,
+			migration: "CREATE TABLE t (id int, begin int, end int);\nSELECT 1;",
+			expected:  []string{"CREATE TABLE t (id int, begin int, end int);", "SELECT 1;"}, expectedLines: []uint{1, 2}},
+		{name: "mysql trigger", dialect: multistmt.MySQL,
+			migration: "CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  IF NEW.x < 0 THEN\n    SET NEW.x = 0;\n  END IF;\nEND;\nSELECT `a;b` FROM t; # comment;\nSELECT 'it\\'s;';",
+			expected: []string{
+				"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW\nBEGIN\n  IF NEW.x < 0 THEN\n    SET NEW.x = 0;\n  END IF;\nEND;",
+				"SELECT `a;b` FROM t;",
+				"SELECT 'it\\'s;';",
+			}, expectedLines: []uint{1, 7, 8}},
+		{name: "mysql delimiter", dialect: multistmt.MySQL,
+			migration: "DELIMITER //\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND//\nDELIMITER ;\nCALL p();",
+			expected: []string{
+				"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND",
+				"CALL p();",
+			}, expectedLines: []uint{2, 7}},
+		{name: "mysql dollar delimiter", dialect: multistmt.MySQL,
+			migration: "DELIMITER $$\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND$$\nDELIMITER ;\nCALL p();",
+			expected: []string{
+				"CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND",
+				"CALL p();",
+			}, expectedLines: []uint{2, 7}},
+		{name: "tsql go", dialect: multistmt.TSQL,
+			migration: "CREATE TABLE [a;b] (id int);\nINSERT INTO [a;b] VALUES (1);\nGO\nCREATE PROCEDURE p AS SELECT 'GO';\ngo\n",
+			expected: []string{
+				"CREATE TABLE [a;b] (id int);\nINSERT INTO [a;b] VALUES (1);\n",
+				"CREATE PROCEDURE p AS SELECT 'GO';\n",
+			}, expectedLines: []uint{1, 4}},
+		{name: "cql batch", dialect: multistmt.CQL,
+			migration: "BEGIN BATCH\n  INSERT INTO t (id) VALUES (1);\n  INSERT INTO t (id) VALUES (2);\nAPPLY BATCH;\n// comment;\nSELECT * FROM t;",
+			expected: []string{
+				"BEGIN BATCH\n  INSERT INTO t (id) VALUES (1);\n  INSERT INTO t (id) VALUES (2);\nAPPLY BATCH;",
+				"// comment;\nSELECT * FROM t;",
+			}, expectedLines: []uint{1, 5}},
+		{name: "clickhouse", dialect: multistmt.ClickHouse,
+			migration: "CREATE TABLE `a;b` (s String DEFAULT 'x;y') ENGINE = Memory;\n# comment;\nSELECT 1;",
+			expected: []string{
+				"CREATE TABLE `a;b` (s String DEFAULT 'x;y') ENGINE = Memory;",
+				"# comment;\nSELECT 1;",
+			}, expectedLines: []uint{1, 2}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stmts := make([]string, 0, len(tc.expected))
+			lines := make([]uint, 0, len(tc.expected))
+			err := multistmt.Split(strings.NewReader(tc.migration), tc.dialect, maxMigrationSize, func(s multistmt.Statement) bool {
+				stmts = append(stmts, string(s.Text))
+				lines = append(lines, s.Line)
+				return true
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, stmts)
+			assert.Equal(t, tc.expectedLines, lines)
+		})
+	}
+}
+
+func TestSplitDiscontinue(t *testing.T) {
+	stmts := make([]string, 0, 1)
+	err := multistmt.Split(strings.NewReader("statement one; statement two"), multistmt.Postgres, maxMigrationSize, func(s multistmt.Statement) bool {
+		stmts = append(stmts, string(s.Text))
+		return false
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"statement one;"}, stmts)
+}
+
+func TestSplitTooLong(t *testing.T) {
+	err := multistmt.Split(strings.NewReader("SELECT 1; SELECT 12345;"), multistmt.Postgres, 10, func(s multistmt.Statement) bool {
+		return true
+	})
+	assert.Equal(t, bufio.ErrTooLong, err)
+}
@@ -13,6 +13,7 @@ import (
 	nurl "net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 import (
@@ -22,6 +23,7 @@ import (
 
 import (
 	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/multistmt"
 )
 
 func init() {
@@ -30,6 +32,12 @@ func init() {
 
 var DefaultMigrationsTable = "schema_migrations"
 
+var (
+	// multiStmtMaxSize limits the size of a single statement of a migration
+	// with the multi-statement directive.
+	multiStmtMaxSize = 10 * 1 << 20 // 10 MB
+)
+
 var (
 	ErrDatabaseDirty    = fmt.Errorf("database is dirty")
 	ErrNilConfig        = fmt.Errorf("no config")
@@ -310,14 +318,49 @@ func (m *Mysql) Unlock() error {
 }
 
 func (m *Mysql) Run(migration io.Reader) error {
+	return m.RunWithOptions(migration, database.RunOptions{})
+}
+
+// RunWithOptions is part of database.OptionsRunner interface implementation.
+// MySQL commits DDL statements implicitly and migrations never run in a
+// transaction, so NoTransaction has no effect.
+func (m *Mysql) RunWithOptions(migration io.Reader, options database.RunOptions) error {
+	if options.MultiStatement {
+		var err error
+		if e := multistmt.Split(migration, multistmt.MySQL, multiStmtMaxSize, func(s multistmt.Statement) bool {
+			if err = m.runStatement(s.Text, s.Line, options.Timeout); err != nil {
+				return false
+			}
+			return true
+		}); e != nil {
+			return e
+		}
+		return err
+	}
+
 	migr, err := ioutil.ReadAll(migration)
 	if err != nil {
 		return err
 	}
+	return m.runStatement(migr, 0, options.Timeout)
+}
 
-	query := string(migr[:])
-	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
-		return database.Error{OrigErr: err, Err: "migration failed", Query: migr}
+// runStatement runs statement. line is the line of the migration the
+// statement starts at, or 0 if statement is the whole migration.
+func (m *Mysql) runStatement(statement []byte, line uint, timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	query := string(statement[:])
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	if _, err := m.conn.ExecContext(ctx, query); err != nil {
+		return database.Error{OrigErr: err, Err: "migration failed", Query: statement, Line: line}
 	}
 
 	return nil
@@ -480,3 +523,239 @@ func readBool(input string) (value bool, valid bool) {
 	// Not a valid bool value
 	return
 }
+
+func (m *Mysql) repeatableTable() string {
+	return m.config.MigrationsTable + "_repeatable"
+}
+
+func (m *Mysql) ensureRepeatableTable() error {
+	query := "CREATE TABLE IF NOT EXISTS `" + m.repeatableTable() + "` (identifier varchar(255) not null primary key, checksum varchar(64) not null, applied_at timestamp not null default current_timestamp)"
+	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// RepeatableChecksum implements database.RepeatableTracker.
+func (m *Mysql) RepeatableChecksum(identifier string) (checksum string, err error) {
+	if err := m.ensureRepeatableTable(); err != nil {
+		return "", err
+	}
+
+	query := "SELECT checksum FROM `" + m.repeatableTable() + "` WHERE identifier = ?"
+	err = m.conn.QueryRowContext(context.Background(), query, identifier).Scan(&checksum)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", nil
+	case err != nil:
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	default:
+		return checksum, nil
+	}
+}
+
+// SetRepeatableChecksum implements database.RepeatableTracker.
+func (m *Mysql) SetRepeatableChecksum(identifier string, checksum string) error {
+	if err := m.ensureRepeatableTable(); err != nil {
+		return err
+	}
+
+	query := "REPLACE INTO `" + m.repeatableTable() + "` (identifier, checksum) VALUES (?, ?)"
+	if _, err := m.conn.ExecContext(context.Background(), query, identifier, checksum); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (m *Mysql) phaseTable() string {
+	return m.config.MigrationsTable + "_phases"
+}
+
+func (m *Mysql) ensurePhaseTable() error {
+	query := "CREATE TABLE IF NOT EXISTS `" + m.phaseTable() + "` (phase varchar(255) not null, version bigint not null, applied_at timestamp not null default current_timestamp, primary key (phase, version))"
+	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// PhaseVersions implements database.PhaseTracker. The phase table is only
+// created by SetPhaseApplied, a missing table has no versions.
+func (m *Mysql) PhaseVersions(phase string) (versions []int, err error) {
+	if exists, err := m.tableExists(m.phaseTable()); err != nil || !exists {
+		return nil, err
+	}
+
+	query := "SELECT version FROM `" + m.phaseTable() + "` WHERE phase = ?"
+	rows, err := m.conn.QueryContext(context.Background(), query, phase)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetPhaseApplied implements database.PhaseTracker.
+func (m *Mysql) SetPhaseApplied(phase string, version int, applied bool) error {
+	if !applied {
+		if exists, err := m.tableExists(m.phaseTable()); err != nil || !exists {
+			return err
+		}
+	} else if err := m.ensurePhaseTable(); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM `" + m.phaseTable() + "` WHERE phase = ? AND version = ?"
+	if applied {
+		query = "REPLACE INTO `" + m.phaseTable() + "` (phase, version) VALUES (?, ?)"
+	}
+	if _, err := m.conn.ExecContext(context.Background(), query, phase, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (m *Mysql) appliedTable() string {
+	return m.config.MigrationsTable + "_applied"
+}
+
+func (m *Mysql) ensureAppliedTable() error {
+	query := "CREATE TABLE IF NOT EXISTS `" + m.appliedTable() + "` (version bigint not null primary key, applied_at timestamp not null default current_timestamp)"
+	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// AppliedVersions implements database.AppliedTracker.
+func (m *Mysql) AppliedVersions() (versions []int, err error) {
+	if err := m.ensureAppliedTable(); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT version FROM `" + m.appliedTable() + "`"
+	rows, err := m.conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetApplied implements database.AppliedTracker.
+func (m *Mysql) SetApplied(version int, applied bool) error {
+	if err := m.ensureAppliedTable(); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM `" + m.appliedTable() + "` WHERE version = ?"
+	if applied {
+		query = "REPLACE INTO `" + m.appliedTable() + "` (version) VALUES (?)"
+	}
+	if _, err := m.conn.ExecContext(context.Background(), query, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// tableExists reports whether the table name exists in the current database.
+func (m *Mysql) tableExists(name string) (bool, error) {
+	query := `SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = (SELECT DATABASE()) AND table_name = ?`
+	var count int
+	if err := m.conn.QueryRowContext(context.Background(), query, name).Scan(&count); err != nil {
+		return false, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return count > 0, nil
+}
+
+func (m *Mysql) skippedTable() string {
+	return m.config.MigrationsTable + "_skipped"
+}
+
+func (m *Mysql) ensureSkippedTable() error {
+	query := "CREATE TABLE IF NOT EXISTS `" + m.skippedTable() + "` (version bigint not null primary key, identifier varchar(255) not null, skipped_at timestamp not null default current_timestamp)"
+	if _, err := m.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// Skip implements database.SkipRecorder.
+func (m *Mysql) Skip(version int, identifier string) error {
+	if err := m.ensureSkippedTable(); err != nil {
+		return err
+	}
+
+	query := "REPLACE INTO `" + m.skippedTable() + "` (version, identifier) VALUES (?, ?)"
+	if _, err := m.conn.ExecContext(context.Background(), query, version, identifier); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// SkippedVersions implements database.SkipRecorder. The ledger table is
+// only created by Skip, a missing table has no skipped versions.
+func (m *Mysql) SkippedVersions() (versions []int, err error) {
+	if exists, err := m.tableExists(m.skippedTable()); err != nil || !exists {
+		return nil, err
+	}
+
+	query := "SELECT version FROM `" + m.skippedTable() + "`"
+	rows, err := m.conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Unskip implements database.SkipRecorder.
+func (m *Mysql) Unskip(version int) error {
+	if exists, err := m.tableExists(m.skippedTable()); err != nil || !exists {
+		return err
+	}
+
+	query := "DELETE FROM `" + m.skippedTable() + "` WHERE version = ?"
+	if _, err := m.conn.ExecContext(context.Background(), query, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// BeginTx implements database.TxBeginner.
+func (m *Mysql) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return m.conn.BeginTx(ctx, opts)
+}
@@ -0,0 +1,141 @@
+// +build go1.9
+
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+)
+
+import (
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+var (
+	// autoIncrementRe matches the next value of an AUTO_INCREMENT column, which
+	// changes with the data and not with the schema.
+	autoIncrementRe = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
+	// viewDefinerRe matches the algorithm, definer and security of a view, which
+	// depend on the user who created it.
+	viewDefinerRe = regexp.MustCompile(`^CREATE .*?VIEW`)
+)
+
+// DumpSchema implements database.SchemaDumper. It writes the SHOW CREATE TABLE
+// statement of every table, followed by the views, ordered by name. Foreign key
+// checks are disabled while the tables are created.
+func (m *Mysql) DumpSchema(w io.Writer) error {
+	tables, views, err := m.schemaTables()
+	if err != nil {
+		return err
+	}
+
+	if len(tables) > 0 {
+		if _, err := io.WriteString(w, "SET foreign_key_checks = 0;\n\n"); err != nil {
+			return err
+		}
+	}
+	for _, t := range tables {
+		statement, err := m.showCreate("TABLE", t)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n\n", autoIncrementRe.ReplaceAllString(statement, "")); err != nil {
+			return err
+		}
+	}
+	if len(tables) > 0 {
+		if _, err := io.WriteString(w, "SET foreign_key_checks = 1;\n\n"); err != nil {
+			return err
+		}
+	}
+
+	for _, v := range views {
+		statement, err := m.showCreate("VIEW", v)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n\n", viewDefinerRe.ReplaceAllString(statement, "CREATE VIEW")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// schemaTables returns the tables and views of the database, except the
+// tables used by migrate itself.
+func (m *Mysql) schemaTables() (tables []string, views []string, err error) {
+	skip := make(map[string]bool)
+	for _, suffix := range []string{"", "_repeatable", "_phases", "_applied", "_skipped"} {
+		skip[m.config.MigrationsTable+suffix] = true
+	}
+
+	query := `SHOW FULL TABLES`
+	rows, err := m.conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			return nil, nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		switch {
+		case skip[name]:
+		case tableType == "VIEW":
+			views = append(views, name)
+		default:
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	sort.Strings(tables)
+	sort.Strings(views)
+	return tables, views, nil
+}
+
+// showCreate returns the CREATE statement of the table or view name.
+func (m *Mysql) showCreate(kind string, name string) (statement string, err error) {
+	query := "SHOW CREATE " + kind + " `" + name + "`"
+	rows, err := m.conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	// SHOW CREATE VIEW returns the character set and collation, too
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	values := make([]interface{}, len(columns))
+	var ignored string
+	for i := range values {
+		values[i] = &ignored
+	}
+	values[1] = &statement
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		return "", &database.Error{Err: "no " + kind + " " + name, Query: []byte(query)}
+	}
+	if err := rows.Scan(values...); err != nil {
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return statement, nil
+}
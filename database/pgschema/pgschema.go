@@ -0,0 +1,241 @@
+// Package pgschema dumps the schema of a PostgreSQL database from its system
+// catalogs. It is shared by the postgres and pgx drivers.
+package pgschema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// schemaObject is a statement of the schema dump with the name of the table it belongs to.
+type schemaObject struct {
+	table     string
+	statement string
+}
+
+// Dump reads the enum types, functions, sequences, tables, partitions,
+// constraints, indexes, views and triggers of schema from the system catalogs
+// and writes them as SQL to w. Objects of the tables in skip are left out.
+// The search path of conn is set to schema while dumping, so names of the
+// schema are not qualified and the dump can be applied to another schema.
+// Names of other schemas stay qualified.
+func Dump(ctx context.Context, conn *sql.Conn, w io.Writer, schema string, skip map[string]bool) (err error) {
+	var version int
+	if err := conn.QueryRowContext(ctx, `SELECT current_setting('server_version_num')::int`).Scan(&version); err != nil {
+		return &database.Error{OrigErr: err, Err: "can't read server version"}
+	}
+
+	var searchPath string
+	if err := conn.QueryRowContext(ctx, `SELECT current_setting('search_path')`).Scan(&searchPath); err != nil {
+		return &database.Error{OrigErr: err, Err: "can't read search path"}
+	}
+	query := `SELECT set_config('search_path', quote_ident($1), false)`
+	if _, err := conn.ExecContext(ctx, query, schema); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		query := `SELECT set_config('search_path', $1, false)`
+		if _, errReset := conn.ExecContext(ctx, query, searchPath); err == nil && errReset != nil {
+			err = &database.Error{OrigErr: errReset, Query: []byte(query)}
+		}
+	}()
+
+	var objects []schemaObject
+	for _, query := range schemaQueries(version) {
+		o, err := querySchema(ctx, conn, query, schema)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, o...)
+	}
+
+	for _, o := range objects {
+		if skip[o.table] {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(o.statement)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateTables returns the names of the tables used by migrate itself if
+// they are kept in schema, the schema being dumped or inspected.
+func MigrateTables(schema string, migrationsSchema string, migrationsTable string) map[string]bool {
+	if migrationsSchema != schema {
+		return nil
+	}
+	tables := make(map[string]bool)
+	for _, suffix := range []string{"", "_namespaces", "_repeatable", "_phases", "_applied", "_skipped"} {
+		tables[migrationsTable+suffix] = true
+	}
+	return tables
+}
+
+func querySchema(ctx context.Context, conn *sql.Conn, query string, schema string) (objects []schemaObject, err error) {
+	rows, err := conn.QueryContext(ctx, query, schema)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var o schemaObject
+		if err := rows.Scan(&o.table, &o.statement); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// schemaQueries returns the queries dumping the schema of server version, in
+// the order the statements must be run. Every query takes the schema name as
+// $1 and returns the table an object belongs to and its statement.
+func schemaQueries(version int) []string {
+	// identity columns and partitions are supported by PostgreSQL 10 and
+	// later, generated columns by PostgreSQL 12 and later. Constraints of
+	// partitions are inherited from their parent since PostgreSQL 11,
+	// triggers since PostgreSQL 13.
+	identity, generated := `''`, `false`
+	partitionBy, notPartition := `''`, `true`
+	localConstraint, localTrigger := `true`, `true`
+	if version >= 100000 {
+		identity = `CASE a.attidentity
+			WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+			WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+			ELSE '' END`
+		partitionBy = `CASE WHEN c.relkind = 'p' THEN ' PARTITION BY ' || pg_get_partkeydef(c.oid) ELSE '' END`
+		notPartition = `NOT c.relispartition`
+	}
+	if version >= 110000 {
+		localConstraint = `con.conparentid = 0`
+	}
+	if version >= 120000 {
+		generated = `a.attgenerated = 's'`
+	}
+	if version >= 130000 {
+		localTrigger = `t.tgparentid = 0`
+	}
+
+	// partitions in the order they were created, so partitioned partitions
+	// come before their partitions
+	partitions := `SELECT '' WHERE false`
+	if version >= 100000 {
+		partitions = `SELECT c.relname, format('CREATE TABLE %I PARTITION OF %s %s%s;',
+				c.relname, i.inhparent::regclass, pg_get_expr(c.relpartbound, c.oid), ` + partitionBy + `)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_inherits i ON i.inhrelid = c.oid
+			WHERE c.relkind IN ('r', 'p') AND c.relispartition AND n.nspname = $1
+			ORDER BY c.oid`
+	}
+
+	return []string{
+		// enum types
+		`SELECT '', format('CREATE TYPE %I AS ENUM (%s);', t.typname,
+				string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder))
+			FROM pg_type t
+			JOIN pg_namespace n ON n.oid = t.typnamespace
+			JOIN pg_enum e ON e.enumtypid = t.oid
+			WHERE n.nspname = $1
+			GROUP BY t.typname
+			ORDER BY t.typname`,
+
+		// functions, their bodies are checked once the tables exist
+		`SELECT '', 'SET check_function_bodies = false;'
+			WHERE EXISTS (SELECT 1 FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace WHERE n.nspname = $1)`,
+		`SELECT '', pg_get_functiondef(p.oid) || ';'
+			FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_aggregate a WHERE a.aggfnoid = p.oid)
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = p.oid AND d.deptype = 'e')
+			ORDER BY p.proname, p.oid`,
+
+		// sequences that are not created by identity columns
+		`SELECT '', format('CREATE SEQUENCE %I;', c.relname)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind = 'S' AND n.nspname = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.objid = c.oid AND d.deptype = 'i')
+			ORDER BY c.relname`,
+
+		// tables
+		`SELECT c.relname, format(E'CREATE TABLE %I (\n%s\n)%s;', c.relname, string_agg(
+				format('    %I %s', a.attname, format_type(a.atttypid, a.atttypmod)) ||
+				CASE
+					WHEN ` + generated + ` THEN ' GENERATED ALWAYS AS (' || pg_get_expr(ad.adbin, ad.adrelid) || ') STORED'
+					WHEN ad.adbin IS NOT NULL THEN ' DEFAULT ' || pg_get_expr(ad.adbin, ad.adrelid)
+					ELSE ''
+				END ||
+				` + identity + ` ||
+				CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END,
+				E',\n' ORDER BY a.attnum), ` + partitionBy + `)
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+			LEFT JOIN pg_attrdef ad ON ad.adrelid = c.oid AND ad.adnum = a.attnum
+			WHERE c.relkind IN ('r', 'p') AND ` + notPartition + ` AND n.nspname = $1
+			GROUP BY c.oid, c.relname, c.relkind
+			ORDER BY c.relname`,
+
+		// partitions, their columns are the ones of the partitioned table
+		partitions,
+
+		// sequences owned by columns
+		`SELECT t.relname, format('ALTER SEQUENCE %I OWNED BY %I.%I;', s.relname, t.relname, a.attname)
+			FROM pg_class s
+			JOIN pg_namespace n ON n.oid = s.relnamespace
+			JOIN pg_depend d ON d.objid = s.oid AND d.deptype = 'a' AND d.refclassid = 'pg_class'::regclass
+			JOIN pg_class t ON t.oid = d.refobjid
+			JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = d.refobjsubid
+			WHERE s.relkind = 'S' AND n.nspname = $1
+			ORDER BY s.relname`,
+
+		// constraints, foreign keys last
+		`SELECT c.relname, format('ALTER TABLE ONLY %I ADD CONSTRAINT %I %s;', c.relname, con.conname, pg_get_constraintdef(con.oid))
+			FROM pg_constraint con
+			JOIN pg_class c ON c.oid = con.conrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND con.contype IN ('p', 'u', 'c', 'x', 'f')
+			AND con.conislocal AND ` + localConstraint + `
+			ORDER BY con.contype = 'f', c.relname, con.conname`,
+
+		// indexes that don't belong to a constraint or to a partitioned index
+		`SELECT t.relname, pg_get_indexdef(i.indexrelid) || ';'
+			FROM pg_index i
+			JOIN pg_class t ON t.oid = i.indrelid
+			JOIN pg_class ic ON ic.oid = i.indexrelid
+			JOIN pg_namespace n ON n.oid = t.relnamespace
+			WHERE n.nspname = $1
+			AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+			AND NOT EXISTS (SELECT 1 FROM pg_inherits inh WHERE inh.inhrelid = i.indexrelid)
+			ORDER BY t.relname, ic.relname`,
+
+		// views in the order they were created, so views they select from come first
+		`SELECT c.relname, format(E'CREATE %sVIEW %I AS\n%s',
+				CASE c.relkind WHEN 'm' THEN 'MATERIALIZED ' ELSE '' END, c.relname, pg_get_viewdef(c.oid, true))
+			FROM pg_class c
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE c.relkind IN ('v', 'm') AND n.nspname = $1
+			ORDER BY c.oid`,
+
+		// triggers
+		`SELECT c.relname, pg_get_triggerdef(t.oid) || ';'
+			FROM pg_trigger t
+			JOIN pg_class c ON c.oid = t.tgrelid
+			JOIN pg_namespace n ON n.oid = c.relnamespace
+			WHERE n.nspname = $1 AND NOT t.tgisinternal AND ` + localTrigger + `
+			ORDER BY c.relname, t.tgname`,
+	}
+}
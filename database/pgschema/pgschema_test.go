@@ -0,0 +1,37 @@
+package pgschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMigrateTables(t *testing.T) {
+	expected := map[string]bool{
+		"schema_migrations":            true,
+		"schema_migrations_namespaces": true,
+		"schema_migrations_repeatable": true,
+		"schema_migrations_phases":     true,
+		"schema_migrations_applied":    true,
+		"schema_migrations_skipped":    true,
+	}
+	if tables := MigrateTables("public", "public", "schema_migrations"); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Incorrect tables was: %v wanted %v", tables, expected)
+	}
+	if tables := MigrateTables("public", "migrate", "schema_migrations"); tables != nil {
+		t.Errorf("Incorrect tables of another schema was: %v wanted none", tables)
+	}
+}
+
+func TestSchemaQueries(t *testing.T) {
+	for _, version := range []int{90600, 100000, 110000, 120000, 130000} {
+		for _, query := range schemaQueries(version) {
+			if !strings.Contains(query, "$1") && !strings.Contains(query, "WHERE false") {
+				t.Errorf("Query of version %v doesn't take the schema name: %v", version, query)
+			}
+		}
+	}
+	if partitions := schemaQueries(90600)[5]; partitions != `SELECT '' WHERE false` {
+		t.Errorf("Incorrect partitions query before PostgreSQL 10 was: %v", partitions)
+	}
+}
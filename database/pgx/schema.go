@@ -0,0 +1,22 @@
+package pgx
+
+import (
+	"context"
+	"io"
+
+	"github.com/golang-migrate/migrate/v4/database/pgschema"
+)
+
+// DumpSchema implements database.SchemaDumper. It reads the enum types,
+// functions, sequences, tables, partitions, constraints, indexes, views and
+// triggers of the schema from the system catalogs and writes them as SQL,
+// see pgschema.Dump.
+func (p *Postgres) DumpSchema(w io.Writer) error {
+	return pgschema.Dump(context.Background(), p.conn, w, p.config.SchemaName, p.migrateTables())
+}
+
+// migrateTables returns the names of the tables used by migrate itself if
+// they are kept in the dumped schema.
+func (p *Postgres) migrateTables() map[string]bool {
+	return pgschema.MigrateTables(p.config.SchemaName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+}
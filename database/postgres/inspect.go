@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+)
+
+// serialTypes maps the types of columns defaulting to their own sequence to
+// the serial types creating them.
+var serialTypes = map[string]string{
+	"smallint": "smallserial",
+	"integer":  "serial",
+	"bigint":   "bigserial",
+}
+
+// InspectSchema implements database.SchemaInspector. It reads the tables,
+// constraints, indexes and views of the schema from the system catalogs.
+// Columns filled by their own sequence are read as serial columns, other
+// sequences are not compared.
+func (p *Postgres) InspectSchema() (*schemadiff.Schema, error) {
+	ctx := context.Background()
+	s := &schemadiff.Schema{Dialect: schemadiff.Postgres}
+	skip := p.migrateTables()
+	// tables holds the index of each table in s.Tables
+	tables := make(map[string]int)
+
+	// columns
+	err := p.inspect(ctx, `SELECT c.relname, a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+			pg_get_expr(ad.adbin, ad.adrelid),
+			pg_get_serial_sequence(format('%I.%I', n.nspname, c.relname), a.attname) IS NOT NULL
+				AND COALESCE(pg_get_expr(ad.adbin, ad.adrelid) LIKE 'nextval(%', false)
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_attribute a ON a.attrelid = c.oid AND a.attnum > 0 AND NOT a.attisdropped
+		LEFT JOIN pg_attrdef ad ON ad.adrelid = c.oid AND ad.adnum = a.attnum
+		WHERE c.relkind IN ('r', 'p') AND n.nspname = $1
+		ORDER BY c.relname, a.attnum`, func(rows *sql.Rows) error {
+		var tableName string
+		var col schemadiff.Column
+		var def sql.NullString
+		var serial bool
+		if err := rows.Scan(&tableName, &col.Name, &col.Type, &col.NotNull, &def, &serial); err != nil {
+			return err
+		}
+		if skip[tableName] {
+			return nil
+		}
+		col.Default = def.String
+		if serialType, ok := serialTypes[col.Type]; ok && serial {
+			col.Type, col.Default = serialType, ""
+		}
+		i, ok := tables[tableName]
+		if !ok {
+			i = len(s.Tables)
+			tables[tableName] = i
+			s.Tables = append(s.Tables, schemadiff.Table{Name: tableName})
+		}
+		s.Tables[i].Columns = append(s.Tables[i].Columns, col)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// constraints
+	err = p.inspect(ctx, `SELECT c.relname, con.conname, pg_get_constraintdef(con.oid)
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND con.contype IN ('p', 'u', 'c', 'x', 'f')
+		ORDER BY c.relname, con.conname`, func(rows *sql.Rows) error {
+		var tableName string
+		var con schemadiff.Constraint
+		if err := rows.Scan(&tableName, &con.Name, &con.Definition); err != nil {
+			return err
+		}
+		if i, ok := tables[tableName]; ok {
+			s.Tables[i].Constraints = append(s.Tables[i].Constraints, con)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// indexes that don't belong to a constraint
+	err = p.inspect(ctx, `SELECT t.relname, ic.relname, pg_get_indexdef(i.indexrelid)
+		FROM pg_index i
+		JOIN pg_class t ON t.oid = i.indrelid
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = t.relnamespace
+		WHERE n.nspname = $1 AND t.relkind IN ('r', 'p')
+		AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))
+		ORDER BY t.relname, ic.relname`, func(rows *sql.Rows) error {
+		var tableName string
+		var index schemadiff.Index
+		if err := rows.Scan(&tableName, &index.Name, &index.Definition); err != nil {
+			return err
+		}
+		if i, ok := tables[tableName]; ok {
+			s.Tables[i].Indexes = append(s.Tables[i].Indexes, index)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// views in the order they were created, so views they select from come first
+	err = p.inspect(ctx, `SELECT c.relname, c.relkind = 'm', format(E'CREATE %sVIEW %I AS\n%s',
+			CASE c.relkind WHEN 'm' THEN 'MATERIALIZED ' ELSE '' END, c.relname, pg_get_viewdef(c.oid, true))
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relkind IN ('v', 'm') AND n.nspname = $1
+		ORDER BY c.oid`, func(rows *sql.Rows) error {
+		var view schemadiff.View
+		if err := rows.Scan(&view.Name, &view.Materialized, &view.Definition); err != nil {
+			return err
+		}
+		view.Definition = strings.TrimSuffix(strings.TrimSpace(view.Definition), ";")
+		s.Views = append(s.Views, view)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// inspect runs query with the schema name as $1 and calls scan for each row.
+func (p *Postgres) inspect(ctx context.Context, query string, scan func(rows *sql.Rows) error) (err error) {
+	rows, err := p.conn.QueryContext(ctx, query, p.config.SchemaName)
+	if err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+	return rows.Err()
+}
@@ -28,8 +28,6 @@ func init() {
 }
 
 var (
-	multiStmtDelimiter = []byte(";")
-
 	DefaultMigrationsTable       = "schema_migrations"
 	DefaultMultiStatementMaxSize = 10 * 1 << 20 // 10 MB
 )
@@ -59,8 +57,14 @@ type Postgres struct {
 	db       *sql.DB
 	isLocked bool
 
+	// namespace is set on drivers returned by Namespace
+	namespace string
+
 	// Open and WithInstance need to guarantee that config is never nil
 	config *Config
+
+	// logger is set by Migrate, see database.LoggerSetter
+	logger database.Logger
 }
 
 func WithInstance(instance *sql.DB, config *Config) (database.Driver, error) {
@@ -204,6 +208,10 @@ func (p *Postgres) Open(url string) (database.Driver, error) {
 }
 
 func (p *Postgres) Close() error {
+	if p.namespace != "" {
+		// the connection is shared with the parent driver
+		return nil
+	}
 	connErr := p.conn.Close()
 	dbErr := p.db.Close()
 	if connErr != nil || dbErr != nil {
@@ -218,7 +226,7 @@ func (p *Postgres) Lock() error {
 		return database.ErrLocked
 	}
 
-	aid, err := database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+	aid, err := p.advisoryLockID()
 	if err != nil {
 		return err
 	}
@@ -233,12 +241,19 @@ func (p *Postgres) Lock() error {
 	return nil
 }
 
+func (p *Postgres) advisoryLockID() (string, error) {
+	if p.namespace != "" {
+		return database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName, p.namespace)
+	}
+	return database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+}
+
 func (p *Postgres) Unlock() error {
 	if !p.isLocked {
 		return nil
 	}
 
-	aid, err := database.GenerateAdvisoryLockId(p.config.DatabaseName, p.config.migrationsSchemaName, p.config.migrationsTableName)
+	aid, err := p.advisoryLockID()
 	if err != nil {
 		return err
 	}
@@ -252,10 +267,21 @@ func (p *Postgres) Unlock() error {
 }
 
 func (p *Postgres) Run(migration io.Reader) error {
-	if p.config.MultiStatementEnabled {
+	return p.RunWithOptions(migration, database.RunOptions{})
+}
+
+// RunWithOptions is part of database.OptionsRunner interface implementation.
+// A migration sent as one query runs in an implicit transaction, so
+// NoTransaction runs its statements one by one like MultiStatement.
+func (p *Postgres) RunWithOptions(migration io.Reader, options database.RunOptions) error {
+	timeout := p.config.StatementTimeout
+	if options.Timeout != 0 {
+		timeout = options.Timeout
+	}
+	if p.config.MultiStatementEnabled || options.MultiStatement || options.NoTransaction {
 		var err error
-		if e := multistmt.Parse(migration, multiStmtDelimiter, p.config.MultiStatementMaxSize, func(m []byte) bool {
-			if err = p.runStatement(m); err != nil {
+		if e := multistmt.Split(migration, multistmt.Postgres, p.config.MultiStatementMaxSize, func(s multistmt.Statement) bool {
+			if err = p.runStatement(s.Text, s.Line, timeout); err != nil {
 				return false
 			}
 			return true
@@ -268,28 +294,31 @@ func (p *Postgres) Run(migration io.Reader) error {
 	if err != nil {
 		return err
 	}
-	return p.runStatement(migr)
+	return p.runStatement(migr, 0, timeout)
 }
 
-func (p *Postgres) runStatement(statement []byte) error {
+// runStatement runs statement. line is the line of the migration the
+// statement starts at, or 0 if statement is the whole migration.
+func (p *Postgres) runStatement(statement []byte, line uint, timeout time.Duration) error {
 	ctx := context.Background()
-	if p.config.StatementTimeout != 0 {
+	if timeout != 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, p.config.StatementTimeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 	query := string(statement)
 	if strings.TrimSpace(query) == "" {
 		return nil
 	}
+	startTime := time.Now()
 	if _, err := p.conn.ExecContext(ctx, query); err != nil {
 		if pgErr, ok := err.(*pq.Error); ok {
-			var line uint
+			var errLine uint
 			var col uint
 			var lineColOK bool
 			if pgErr.Position != "" {
 				if pos, err := strconv.ParseUint(pgErr.Position, 10, 64); err == nil {
-					line, col, lineColOK = computeLineFromPos(query, int(pos))
+					errLine, col, lineColOK = computeLineFromPos(query, int(pos))
 				}
 			}
 			message := fmt.Sprintf("migration failed: %s", pgErr.Message)
@@ -299,13 +328,45 @@ func (p *Postgres) runStatement(statement []byte) error {
 			if pgErr.Detail != "" {
 				message = fmt.Sprintf("%s, %s", message, pgErr.Detail)
 			}
-			return database.Error{OrigErr: err, Err: message, Query: statement, Line: line}
+			return database.Error{OrigErr: err, Err: message, Query: statement, Line: migrationLine(line, errLine, lineColOK)}
 		}
-		return database.Error{OrigErr: err, Err: "migration failed", Query: statement}
+		return database.Error{OrigErr: err, Err: "migration failed", Query: statement, Line: line}
 	}
+	p.logStatement(line, time.Since(startTime))
 	return nil
 }
 
+// SetLogger implements database.LoggerSetter.
+func (p *Postgres) SetLogger(logger database.Logger) {
+	p.logger = logger
+}
+
+// logStatement logs that the statement starting at line, or the whole
+// migration if line is 0, ran in duration.
+func (p *Postgres) logStatement(line uint, duration time.Duration) {
+	if p.logger == nil {
+		return
+	}
+	msg := fmt.Sprintf("Ran migration (%v)", duration)
+	if line > 0 {
+		msg = fmt.Sprintf("Ran statement at line %d (%v)", line, duration)
+	}
+	p.logger.Debug(msg, "driver", "postgres", "line", line, "duration", duration)
+}
+
+// migrationLine returns the line of the migration an error occurred at, given
+// the line the statement starts at (0 for the whole migration) and the line
+// within the statement, if known.
+func migrationLine(start, line uint, ok bool) uint {
+	if start == 0 {
+		return line
+	}
+	if !ok {
+		return start
+	}
+	return start + line - 1
+}
+
 func computeLineFromPos(s string, pos int) (line uint, col uint, ok bool) {
 	// replace crlf with lf
 	s = strings.Replace(s, "\r\n", "\n", -1)
@@ -342,6 +403,10 @@ func runesLastIndex(input []rune, target rune) int {
 }
 
 func (p *Postgres) SetVersion(version int, dirty bool) error {
+	if p.namespace != "" {
+		return p.setNamespaceVersion(version, dirty)
+	}
+
 	tx, err := p.conn.BeginTx(context.Background(), &sql.TxOptions{})
 	if err != nil {
 		return &database.Error{OrigErr: err, Err: "transaction start failed"}
@@ -376,6 +441,10 @@ func (p *Postgres) SetVersion(version int, dirty bool) error {
 }
 
 func (p *Postgres) Version() (version int, dirty bool, err error) {
+	if p.namespace != "" {
+		return p.namespaceVersion()
+	}
+
 	query := `SELECT version, dirty FROM ` + pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName) + ` LIMIT 1`
 	err = p.conn.QueryRowContext(context.Background(), query).Scan(&version, &dirty)
 	switch {
@@ -478,3 +547,372 @@ func (p *Postgres) ensureVersionTable() (err error) {
 
 	return nil
 }
+
+// Namespace implements database.Namespacer. The versions of all namespaces
+// but the root one are kept in the "<migrations table>_namespaces" table,
+// one row per namespace.
+func (p *Postgres) Namespace(name string) (database.Driver, error) {
+	if name == "" {
+		return p, nil
+	}
+
+	px := &Postgres{
+		conn:      p.conn,
+		db:        p.db,
+		config:    p.config,
+		namespace: name,
+	}
+	if err := px.ensureNamespaceTable(); err != nil {
+		return nil, err
+	}
+	return px, nil
+}
+
+func (p *Postgres) namespaceTable() string {
+	return pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName+"_namespaces")
+}
+
+// ensureNamespaceTable creates the namespace versions table if it doesn't exist.
+func (p *Postgres) ensureNamespaceTable() (err error) {
+	if err = p.Lock(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if e := p.Unlock(); e != nil {
+			if err == nil {
+				err = e
+			} else {
+				err = multierror.Append(err, e)
+			}
+		}
+	}()
+
+	query := `CREATE TABLE IF NOT EXISTS ` + p.namespaceTable() + ` (namespace text not null primary key, version bigint not null, dirty boolean not null)`
+	if _, err = p.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	return nil
+}
+
+func (p *Postgres) setNamespaceVersion(version int, dirty bool) error {
+	tx, err := p.conn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+
+	query := `DELETE FROM ` + p.namespaceTable() + ` WHERE namespace = $1`
+	if _, err := tx.Exec(query, p.namespace); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			err = multierror.Append(err, errRollback)
+		}
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	if version >= 0 || (version == database.NilVersion && dirty) {
+		query = `INSERT INTO ` + p.namespaceTable() + ` (namespace, version, dirty) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(query, p.namespace, version, dirty); err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				err = multierror.Append(err, errRollback)
+			}
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+
+	return nil
+}
+
+func (p *Postgres) namespaceVersion() (version int, dirty bool, err error) {
+	query := `SELECT version, dirty FROM ` + p.namespaceTable() + ` WHERE namespace = $1 LIMIT 1`
+	err = p.conn.QueryRowContext(context.Background(), query, p.namespace).Scan(&version, &dirty)
+	switch {
+	case err == sql.ErrNoRows:
+		return database.NilVersion, false, nil
+
+	case err != nil:
+		return 0, false, &database.Error{OrigErr: err, Query: []byte(query)}
+
+	default:
+		return version, dirty, nil
+	}
+}
+
+func (p *Postgres) repeatableTable() string {
+	return pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName+"_repeatable")
+}
+
+// repeatableKey prefixes identifier with the namespace, so that repeatable
+// migrations of different namespaces don't collide.
+func (p *Postgres) repeatableKey(identifier string) string {
+	if p.namespace != "" {
+		return p.namespace + "/" + identifier
+	}
+	return identifier
+}
+
+func (p *Postgres) ensureRepeatableTable() error {
+	query := `CREATE TABLE IF NOT EXISTS ` + p.repeatableTable() + ` (identifier text not null primary key, checksum text not null, applied_at timestamp with time zone not null default now())`
+	if _, err := p.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// RepeatableChecksum implements database.RepeatableTracker.
+func (p *Postgres) RepeatableChecksum(identifier string) (checksum string, err error) {
+	if err := p.ensureRepeatableTable(); err != nil {
+		return "", err
+	}
+
+	query := `SELECT checksum FROM ` + p.repeatableTable() + ` WHERE identifier = $1`
+	err = p.conn.QueryRowContext(context.Background(), query, p.repeatableKey(identifier)).Scan(&checksum)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", nil
+	case err != nil:
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	default:
+		return checksum, nil
+	}
+}
+
+// SetRepeatableChecksum implements database.RepeatableTracker.
+func (p *Postgres) SetRepeatableChecksum(identifier string, checksum string) error {
+	if err := p.ensureRepeatableTable(); err != nil {
+		return err
+	}
+
+	tx, err := p.conn.BeginTx(context.Background(), &sql.TxOptions{})
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+
+	query := `DELETE FROM ` + p.repeatableTable() + ` WHERE identifier = $1`
+	if _, err := tx.Exec(query, p.repeatableKey(identifier)); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			err = multierror.Append(err, errRollback)
+		}
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	query = `INSERT INTO ` + p.repeatableTable() + ` (identifier, checksum) VALUES ($1, $2)`
+	if _, err := tx.Exec(query, p.repeatableKey(identifier), checksum); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			err = multierror.Append(err, errRollback)
+		}
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+
+	return nil
+}
+
+func (p *Postgres) phaseTable() string {
+	return pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName+"_phases")
+}
+
+// phaseKey prefixes phase with the namespace, so that the phases of
+// different namespaces don't collide.
+func (p *Postgres) phaseKey(phase string) string {
+	if p.namespace != "" {
+		return p.namespace + "/" + phase
+	}
+	return phase
+}
+
+func (p *Postgres) ensurePhaseTable() error {
+	query := `CREATE TABLE IF NOT EXISTS ` + p.phaseTable() + ` (phase text not null, version bigint not null, applied_at timestamp with time zone not null default now(), primary key (phase, version))`
+	if _, err := p.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// PhaseVersions implements database.PhaseTracker. The phase table is only
+// created by SetPhaseApplied, a missing table has no versions.
+func (p *Postgres) PhaseVersions(phase string) (versions []int, err error) {
+	if exists, err := p.tableExists(p.config.migrationsTableName + "_phases"); err != nil || !exists {
+		return nil, err
+	}
+
+	query := `SELECT version FROM ` + p.phaseTable() + ` WHERE phase = $1`
+	rows, err := p.conn.QueryContext(context.Background(), query, p.phaseKey(phase))
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetPhaseApplied implements database.PhaseTracker.
+func (p *Postgres) SetPhaseApplied(phase string, version int, applied bool) error {
+	if !applied {
+		if exists, err := p.tableExists(p.config.migrationsTableName + "_phases"); err != nil || !exists {
+			return err
+		}
+	} else if err := p.ensurePhaseTable(); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM ` + p.phaseTable() + ` WHERE phase = $1 AND version = $2`
+	if applied {
+		query = `INSERT INTO ` + p.phaseTable() + ` (phase, version) VALUES ($1, $2) ON CONFLICT (phase, version) DO NOTHING`
+	}
+	if _, err := p.conn.ExecContext(context.Background(), query, p.phaseKey(phase), version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (p *Postgres) appliedTable() string {
+	return pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName+"_applied")
+}
+
+func (p *Postgres) ensureAppliedTable() error {
+	query := `CREATE TABLE IF NOT EXISTS ` + p.appliedTable() + ` (version bigint not null primary key, applied_at timestamp with time zone not null default now())`
+	if _, err := p.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// AppliedVersions implements database.AppliedTracker.
+func (p *Postgres) AppliedVersions() (versions []int, err error) {
+	if err := p.ensureAppliedTable(); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT version FROM ` + p.appliedTable()
+	rows, err := p.conn.QueryContext(context.Background(), query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetApplied implements database.AppliedTracker.
+func (p *Postgres) SetApplied(version int, applied bool) error {
+	if err := p.ensureAppliedTable(); err != nil {
+		return err
+	}
+
+	query := `DELETE FROM ` + p.appliedTable() + ` WHERE version = $1`
+	if applied {
+		query = `INSERT INTO ` + p.appliedTable() + ` (version) VALUES ($1) ON CONFLICT (version) DO NOTHING`
+	}
+	if _, err := p.conn.ExecContext(context.Background(), query, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// tableExists reports whether the table name exists in the migrations schema.
+func (p *Postgres) tableExists(name string) (bool, error) {
+	query := `SELECT COUNT(1) FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2 LIMIT 1`
+	var count int
+	if err := p.conn.QueryRowContext(context.Background(), query, p.config.migrationsSchemaName, name).Scan(&count); err != nil {
+		return false, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return count == 1, nil
+}
+
+func (p *Postgres) skippedTable() string {
+	return pq.QuoteIdentifier(p.config.migrationsSchemaName) + `.` + pq.QuoteIdentifier(p.config.migrationsTableName+"_skipped")
+}
+
+func (p *Postgres) ensureSkippedTable() error {
+	query := `CREATE TABLE IF NOT EXISTS ` + p.skippedTable() + ` (namespace text not null, version bigint not null, identifier text not null, skipped_at timestamp with time zone not null default now(), primary key (namespace, version))`
+	if _, err := p.conn.ExecContext(context.Background(), query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// Skip implements database.SkipRecorder.
+func (p *Postgres) Skip(version int, identifier string) error {
+	if err := p.ensureSkippedTable(); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO ` + p.skippedTable() + ` (namespace, version, identifier) VALUES ($1, $2, $3) ON CONFLICT (namespace, version) DO UPDATE SET identifier = EXCLUDED.identifier, skipped_at = now()`
+	if _, err := p.conn.ExecContext(context.Background(), query, p.namespace, version, identifier); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// SkippedVersions implements database.SkipRecorder. The ledger table is
+// only created by Skip, a missing table has no skipped versions.
+func (p *Postgres) SkippedVersions() (versions []int, err error) {
+	if exists, err := p.tableExists(p.config.migrationsTableName + "_skipped"); err != nil || !exists {
+		return nil, err
+	}
+
+	query := `SELECT version FROM ` + p.skippedTable() + ` WHERE namespace = $1`
+	rows, err := p.conn.QueryContext(context.Background(), query, p.namespace)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Unskip implements database.SkipRecorder.
+func (p *Postgres) Unskip(version int) error {
+	if exists, err := p.tableExists(p.config.migrationsTableName + "_skipped"); err != nil || !exists {
+		return err
+	}
+
+	query := `DELETE FROM ` + p.skippedTable() + ` WHERE namespace = $1 AND version = $2`
+	if _, err := p.conn.ExecContext(context.Background(), query, p.namespace, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// BeginTx implements database.TxBeginner.
+func (p *Postgres) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.conn.BeginTx(ctx, opts)
+}
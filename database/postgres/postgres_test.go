@@ -20,6 +20,7 @@ import (
 	"github.com/dhui/dktest"
 
 	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
 	dt "github.com/golang-migrate/migrate/v4/database/testing"
 	"github.com/golang-migrate/migrate/v4/dktesting"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -165,6 +166,194 @@ func TestMultipleStatements(t *testing.T) {
 	})
 }
 
+func TestDumpSchema(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		migration := `CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL UNIQUE);
+			CREATE TABLE orders (id serial PRIMARY KEY, user_id int REFERENCES users (id), total numeric(10,2) DEFAULT 0);
+			CREATE INDEX orders_user_id ON orders (user_id);
+			CREATE VIEW order_totals AS SELECT user_id, sum(total) AS total FROM orders GROUP BY user_id;`
+		if err := d.Run(strings.NewReader(migration)); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.SetVersion(1, false); err != nil {
+			t.Fatal(err)
+		}
+
+		var schema strings.Builder
+		if err := d.(*Postgres).DumpSchema(&schema); err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{
+			"CREATE SEQUENCE users_id_seq;",
+			"CREATE TABLE orders (\n    id integer DEFAULT nextval('orders_id_seq'::regclass) NOT NULL,",
+			"ALTER TABLE ONLY users ADD CONSTRAINT users_email_key UNIQUE (email);",
+			"ALTER TABLE ONLY orders ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id);",
+			"CREATE INDEX orders_user_id ON orders USING btree (user_id);",
+			"CREATE VIEW order_totals AS",
+		} {
+			if !strings.Contains(schema.String(), expected) {
+				t.Errorf("expected schema to contain %q, got:\n%v", expected, schema.String())
+			}
+		}
+		if strings.Contains(schema.String(), DefaultMigrationsTable) {
+			t.Errorf("expected schema without migrations table, got:\n%v", schema.String())
+		}
+
+		// the dump recreates the same schema
+		if err := d.Drop(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(strings.NewReader(schema.String())); err != nil {
+			t.Fatal(err)
+		}
+		var recreated strings.Builder
+		if err := d.(*Postgres).DumpSchema(&recreated); err != nil {
+			t.Fatal(err)
+		}
+		if recreated.String() != schema.String() {
+			t.Errorf("expected recreated schema to be equal, got:\n%v", recreated.String())
+		}
+	})
+}
+
+func TestDumpSchemaPartitions(t *testing.T) {
+	// primary keys of partitioned tables are supported by PostgreSQL 11 and later
+	dktesting.ParallelTest(t, specs[3:], func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		migration := `CREATE TABLE events (id int, created_at date NOT NULL, PRIMARY KEY (id, created_at)) PARTITION BY RANGE (created_at);
+			CREATE TABLE events_2021 PARTITION OF events FOR VALUES FROM ('2021-01-01') TO ('2022-01-01');
+			CREATE TABLE events_default PARTITION OF events DEFAULT;
+			CREATE INDEX events_created_at ON events (created_at);`
+		if err := d.Run(strings.NewReader(migration)); err != nil {
+			t.Fatal(err)
+		}
+
+		var schema strings.Builder
+		if err := d.(*Postgres).DumpSchema(&schema); err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range []string{
+			") PARTITION BY RANGE (created_at);",
+			"CREATE TABLE events_2021 PARTITION OF events FOR VALUES FROM ('2021-01-01') TO ('2022-01-01');",
+			"CREATE TABLE events_default PARTITION OF events DEFAULT;",
+		} {
+			if !strings.Contains(schema.String(), expected) {
+				t.Errorf("expected schema to contain %q, got:\n%v", expected, schema.String())
+			}
+		}
+		for _, unexpected := range []string{"CREATE TABLE events_2021 (", "ALTER TABLE ONLY events_2021", "ON events_2021", "public."} {
+			if strings.Contains(schema.String(), unexpected) {
+				t.Errorf("expected schema without %q, got:\n%v", unexpected, schema.String())
+			}
+		}
+
+		// the dump recreates the same schema
+		if err := d.Drop(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(strings.NewReader(schema.String())); err != nil {
+			t.Fatal(err)
+		}
+		var recreated strings.Builder
+		if err := d.(*Postgres).DumpSchema(&recreated); err != nil {
+			t.Fatal(err)
+		}
+		if recreated.String() != schema.String() {
+			t.Errorf("expected recreated schema to be equal, got:\n%v", recreated.String())
+		}
+	})
+}
+
+func TestInspectSchema(t *testing.T) {
+	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
+		ip, port, err := c.FirstPort()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		addr := pgConnectionString(ip, port)
+		p := &Postgres{}
+		d, err := p.Open(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() {
+			if err := d.Close(); err != nil {
+				t.Error(err)
+			}
+		}()
+		empty, err := d.(*Postgres).InspectSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(empty.Tables) != 0 {
+			t.Fatalf("expected no tables, got: %+v", empty.Tables)
+		}
+
+		migration := `CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL UNIQUE);
+			CREATE TABLE orders (id bigserial PRIMARY KEY, user_id int REFERENCES users (id), total numeric(10,2) DEFAULT 0);
+			CREATE INDEX orders_user_id ON orders (user_id);
+			CREATE VIEW order_totals AS SELECT user_id, sum(total) AS total FROM orders GROUP BY user_id;`
+		if err := d.Run(strings.NewReader(migration)); err != nil {
+			t.Fatal(err)
+		}
+		schema, err := d.(*Postgres).InspectSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		up, err := schemadiff.Diff(empty, schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the diff recreates the same schema
+		if err := d.Drop(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(strings.NewReader(strings.Join(up, "\n"))); err != nil {
+			t.Fatal(err)
+		}
+		recreated, err := d.(*Postgres).InspectSchema()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff, err := schemadiff.Diff(schema, recreated); err != nil || len(diff) != 0 {
+			t.Errorf("expected recreated schema to be equal, got: %v (%v)", diff, err)
+		}
+	})
+}
+
 func TestMultipleStatementsInMultiStatementMode(t *testing.T) {
 	dktesting.ParallelTest(t, specs, func(t *testing.T, c dktest.ContainerInfo) {
 		ip, port, err := c.FirstPort()
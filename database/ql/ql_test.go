@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -79,3 +80,50 @@ func TestMigrate(t *testing.T) {
 	}
 	dt.TestMigrate(t, m)
 }
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	if err := os.Mkdir(migrations, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for file, content := range map[string]string{
+		"1_pets.up.sql":       "CREATE TABLE pets (name string);",
+		"1_pets.down.sql":     "DROP TABLE pets;",
+		"2_name.up.sql":       "CREATE UNIQUE INDEX pets_name ON pets (name);",
+		"2_name.down.sql":     "DROP INDEX pets_name;",
+		"3_predator.up.sql":   "ALTER TABLE pets ADD predator bool;",
+		"3_predator.down.sql": "ALTER TABLE pets DROP COLUMN predator;",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(migrations, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dt.TestMigrationsRoundTrip(t, "file://"+migrations, "ql://"+filepath.Join(dir, "ql.db"))
+}
+
+func TestDumpSchema(t *testing.T) {
+	p := &Ql{}
+	d, err := p.Open("ql://" + filepath.Join(t.TempDir(), "ql.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if err := d.Run(strings.NewReader("CREATE TABLE pets (name string, age int); CREATE UNIQUE INDEX pets_name ON pets (name);")); err != nil {
+		t.Fatal(err)
+	}
+
+	var schema strings.Builder
+	if err := d.(*Ql).DumpSchema(&schema); err != nil {
+		t.Fatal(err)
+	}
+	expected := "CREATE TABLE pets (name string, age int64);\n\nCREATE UNIQUE INDEX pets_name ON pets (name);\n\n"
+	if schema.String() != expected {
+		t.Errorf("Incorrect schema was: %q wanted %q", schema.String(), expected)
+	}
+}
@@ -0,0 +1,82 @@
+package ql
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// DumpSchema implements database.SchemaDumper. It writes the CREATE TABLE
+// statements recorded in __Table and the indexes listed in __Index.
+func (m *Ql) DumpSchema(w io.Writer) error {
+	var tables []string
+	query := `SELECT Name, Schema FROM __Table ORDER BY Name`
+	if err := m.querySchema(query, func(rows *sql.Rows) error {
+		var name, schema string
+		if err := rows.Scan(&name, &schema); err != nil {
+			return err
+		}
+		if !m.isSchemaTable(name) {
+			tables = append(tables, strings.TrimSuffix(strings.TrimSpace(schema), ";")+";")
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var indexes []string
+	query = `SELECT TableName, Name, ColumnName, IsUnique FROM __Index ORDER BY TableName, Name`
+	if err := m.querySchema(query, func(rows *sql.Rows) error {
+		var table, name, column string
+		var unique bool
+		if err := rows.Scan(&table, &name, &column, &unique); err != nil {
+			return err
+		}
+		if m.isSchemaTable(table) {
+			return nil
+		}
+		kind := "INDEX"
+		if unique {
+			kind = "UNIQUE INDEX"
+		}
+		indexes = append(indexes, fmt.Sprintf("CREATE %s %s ON %s (%s);", kind, name, table, column))
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, statement := range append(tables, indexes...) {
+		if _, err := fmt.Fprintf(w, "%s\n\n", statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isSchemaTable reports whether table belongs to the database or to migrate
+// instead of to the schema.
+func (m *Ql) isSchemaTable(table string) bool {
+	return strings.HasPrefix(table, "__") || table == m.config.MigrationsTable
+}
+
+func (m *Ql) querySchema(query string, scan func(rows *sql.Rows) error) (err error) {
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,310 @@
+// Package safety finds statements of PostgreSQL migrations that lock busy
+// tables for a long time or break running application versions, e.g.
+// CREATE INDEX without CONCURRENTLY or ALTER COLUMN ... SET NOT NULL.
+//
+// Statements on tables created by the same migration are not reported, as
+// these tables are empty and unused. Findings are suppressed by a directive
+// in the comments preceding a statement:
+//  -- migrate:safety-ignore=drop-column,set-not-null
+// The directive applies to all statements if it is in the header of the
+// migration, see database.ParseDirectives. Without rules, it suppresses all
+// findings.
+package safety
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database/multistmt"
+)
+
+// Rules reported by Check.
+const (
+	// NonConcurrentIndex is reported for CREATE INDEX, DROP INDEX and REINDEX
+	// without CONCURRENTLY and for PRIMARY KEY and UNIQUE constraints that
+	// build their index while locking the table.
+	NonConcurrentIndex = "non-concurrent-index"
+
+	// AlterColumnType is reported for ALTER COLUMN ... TYPE, which rewrites
+	// the table unless the types are binary coercible.
+	AlterColumnType = "alter-column-type"
+
+	// SetNotNull is reported for ALTER COLUMN ... SET NOT NULL, which scans
+	// the table while locking it.
+	SetNotNull = "set-not-null"
+
+	// DropColumn is reported for DROP COLUMN, which breaks application
+	// versions still reading the column.
+	DropColumn = "drop-column"
+
+	// TableRewrite is reported for statements rewriting a table, e.g. ADD
+	// COLUMN with a DEFAULT before PostgreSQL 11, VACUUM FULL and CLUSTER.
+	TableRewrite = "table-rewrite"
+
+	// ConstraintValidation is reported for FOREIGN KEY and CHECK constraints
+	// that are added without NOT VALID and are validated while locking the
+	// table.
+	ConstraintValidation = "constraint-validation"
+)
+
+// Rules are all rules reported by Check.
+var Rules = []string{NonConcurrentIndex, AlterColumnType, SetNotNull, DropColumn, TableRewrite, ConstraintValidation}
+
+// ignoreDirective suppresses findings, see the package documentation.
+const ignoreDirective = "migrate:safety-ignore"
+
+// maxStatementSize is the size of the largest statement Check reads.
+const maxStatementSize = 10 * 1 << 20 // 10 MB
+
+// Finding is a dangerous statement found by Check.
+type Finding struct {
+	// Line is the line of the migration the statement starts at.
+	Line uint
+	// Rule is one of Rules.
+	Rule    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d: %s (%s)", f.Line, f.Message, f.Rule)
+}
+
+// Check returns the findings of the statements of the PostgreSQL migration.
+func Check(migration io.Reader) ([]Finding, error) {
+	c := &checker{created: make(map[string]bool)}
+	var err error
+	first := true
+	if e := multistmt.Split(migration, multistmt.Postgres, maxStatementSize, func(s multistmt.Statement) bool {
+		tokens, comments := tokenize(string(s.Text))
+		var ignored map[string]bool
+		if ignored, err = ignoredRules(comments); err != nil {
+			return false
+		}
+		if first {
+			c.header = ignored
+			first = false
+		}
+		c.line = s.Line
+		c.ignored = ignored
+		c.check(tokens)
+		return true
+	}); e != nil {
+		return nil, e
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.findings, nil
+}
+
+// ignoredRules returns the rules suppressed by the directives in comments,
+// with "" standing for all rules.
+func ignoredRules(comments []string) (map[string]bool, error) {
+	var ignored map[string]bool
+	for _, comment := range comments {
+		d := strings.TrimSpace(comment)
+		if !strings.HasPrefix(d, ignoreDirective) {
+			continue
+		}
+		if ignored == nil {
+			ignored = make(map[string]bool)
+		}
+		value := strings.TrimPrefix(d, ignoreDirective)
+		if value == "" {
+			ignored[""] = true
+			continue
+		}
+		if !strings.HasPrefix(value, "=") {
+			return nil, fmt.Errorf("invalid directive %q", d)
+		}
+		for _, rule := range strings.Split(value[1:], ",") {
+			rule = strings.TrimSpace(rule)
+			if !isRule(rule) {
+				return nil, fmt.Errorf("invalid directive %q: unknown rule %q", d, rule)
+			}
+			ignored[rule] = true
+		}
+	}
+	return ignored, nil
+}
+
+func isRule(rule string) bool {
+	for _, r := range Rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+type checker struct {
+	// created are the tables created by the migration so far.
+	created map[string]bool
+	// header and ignored are the rules ignored by the migration and by the
+	// current statement.
+	header, ignored map[string]bool
+	line            uint
+	findings        []Finding
+}
+
+func (c *checker) report(rule string, format string, args ...interface{}) {
+	if c.header[""] || c.header[rule] || c.ignored[""] || c.ignored[rule] {
+		return
+	}
+	c.findings = append(c.findings, Finding{Line: c.line, Rule: rule, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) check(t tokens) {
+	switch {
+	case t.is(0, "CREATE"):
+		c.checkCreate(t)
+	case t.is(0, "DROP") && t.is(1, "INDEX"):
+		if !t.is(2, "CONCURRENTLY") {
+			c.report(NonConcurrentIndex, "DROP INDEX without CONCURRENTLY locks the table of the index")
+		}
+	case t.is(0, "REINDEX"):
+		if t.find(0, "CONCURRENTLY") < 0 {
+			c.report(NonConcurrentIndex, "REINDEX without CONCURRENTLY locks the table against writes")
+		}
+	case t.is(0, "VACUUM"):
+		if t.is(1, "FULL") || (t.punct(1, '(') && t.find(1, "FULL") >= 0) {
+			c.report(TableRewrite, "VACUUM FULL rewrites tables while locking them")
+		}
+	case t.is(0, "CLUSTER"):
+		c.report(TableRewrite, "CLUSTER rewrites tables while locking them")
+	case t.is(0, "ALTER") && t.is(1, "TABLE"):
+		c.checkAlterTable(t)
+	}
+}
+
+func (c *checker) checkCreate(t tokens) {
+	i := 1
+	if t.is(i, "UNIQUE") {
+		i++
+	}
+	if t.is(i, "INDEX") {
+		if t.is(i+1, "CONCURRENTLY") {
+			return
+		}
+		on := t.find(i+1, "ON")
+		if on < 0 {
+			return
+		}
+		on++
+		if t.is(on, "ONLY") {
+			on++
+		}
+		if table, _ := t.name(on); !c.created[table] {
+			c.report(NonConcurrentIndex, "CREATE INDEX without CONCURRENTLY locks table %s against writes while the index is built", table)
+		}
+		return
+	}
+
+	for t.is(i, "GLOBAL") || t.is(i, "LOCAL") || t.is(i, "TEMP") || t.is(i, "TEMPORARY") || t.is(i, "UNLOGGED") {
+		i++
+	}
+	if !t.is(i, "TABLE") {
+		return
+	}
+	i++
+	if t.is(i, "IF") && t.is(i+1, "NOT") && t.is(i+2, "EXISTS") {
+		i += 3
+	}
+	table, _ := t.name(i)
+	c.created[table] = true
+}
+
+func (c *checker) checkAlterTable(t tokens) {
+	i := 2
+	if t.is(i, "IF") && t.is(i+1, "EXISTS") {
+		i += 2
+	}
+	if t.is(i, "ONLY") {
+		i++
+	}
+	table, i := t.name(i)
+	if table == "" || c.created[table] {
+		return
+	}
+	if t.punct(i, '*') {
+		i++
+	}
+	for _, action := range t[i:].split(',') {
+		c.checkAlterAction(table, action)
+	}
+}
+
+func (c *checker) checkAlterAction(table string, a tokens) {
+	switch {
+	case a.is(0, "ADD"):
+		i := 1
+		if a.is(i, "CONSTRAINT") {
+			i += 2
+		}
+		switch {
+		case a.is(i, "PRIMARY") || a.is(i, "UNIQUE") || a.is(i, "EXCLUDE"):
+			if !a.seq("USING", "INDEX") {
+				c.report(NonConcurrentIndex, "adding a %s constraint builds its index while locking table %s, create a unique index CONCURRENTLY and add the constraint USING INDEX", constraintKind(a[i]), table)
+			}
+			return
+		case a.is(i, "FOREIGN") || a.is(i, "CHECK"):
+			if !a.seq("NOT", "VALID") {
+				c.report(ConstraintValidation, "adding a %s constraint scans table %s while locking it, add it NOT VALID and VALIDATE CONSTRAINT it in another migration", constraintKind(a[i]), table)
+			}
+			return
+		}
+
+		if a.is(i, "COLUMN") {
+			i++
+		}
+		if a.is(i, "IF") && a.is(i+1, "NOT") && a.is(i+2, "EXISTS") {
+			i += 3
+		}
+		column, _ := a.name(i)
+		switch {
+		case a.find(i, "SERIAL") >= 0 || a.find(i, "BIGSERIAL") >= 0 || a.find(i, "SMALLSERIAL") >= 0 || a.find(i, "GENERATED") >= 0:
+			c.report(TableRewrite, "adding the generated column %s rewrites table %s while locking it", column, table)
+		case a.find(i, "DEFAULT") >= 0:
+			c.report(TableRewrite, "adding column %s with a DEFAULT rewrites table %s before PostgreSQL 11, and with a volatile default on all versions", column, table)
+		}
+		if a.find(i, "PRIMARY") >= 0 || a.find(i, "UNIQUE") >= 0 {
+			c.report(NonConcurrentIndex, "adding column %s with a PRIMARY KEY or UNIQUE constraint builds an index while locking table %s", column, table)
+		}
+	case a.is(0, "DROP"):
+		i := 1
+		if a.is(i, "CONSTRAINT") {
+			return
+		}
+		if a.is(i, "COLUMN") {
+			i++
+		}
+		if a.is(i, "IF") && a.is(i+1, "EXISTS") {
+			i += 2
+		}
+		column, _ := a.name(i)
+		c.report(DropColumn, "dropping column %s of table %s breaks application versions still reading it, stop using the column before dropping it", column, table)
+	case a.is(0, "ALTER"):
+		i := 1
+		if a.is(i, "COLUMN") {
+			i++
+		}
+		column, i := a.name(i)
+		switch {
+		case a.is(i, "TYPE") || a.is(i, "SET") && a.is(i+1, "DATA") && a.is(i+2, "TYPE"):
+			c.report(AlterColumnType, "changing the type of column %s locks table %s and rewrites it unless the types are binary coercible", column, table)
+		case a.is(i, "SET") && a.is(i+1, "NOT") && a.is(i+2, "NULL"):
+			c.report(SetNotNull, "SET NOT NULL scans table %s while locking it, validate a CHECK (%s IS NOT NULL) NOT VALID constraint first", table, column)
+		}
+	case a.is(0, "SET") && (a.is(1, "TABLESPACE") || a.is(1, "LOGGED") || a.is(1, "UNLOGGED")):
+		c.report(TableRewrite, "SET %s rewrites table %s while locking it", a[1].word, table)
+	}
+}
+
+// constraintKind returns the kind of constraint starting with t, e.g. PRIMARY KEY.
+func constraintKind(t token) string {
+	if t.word == "PRIMARY" || t.word == "FOREIGN" {
+		return t.word + " KEY"
+	}
+	return t.word
+}
@@ -0,0 +1,89 @@
+package safety
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		migration string
+		expected  []string
+	}{
+		{
+			name:      "safe",
+			migration: "CREATE INDEX CONCURRENTLY users_email ON users (email);\nDROP INDEX CONCURRENTLY users_name;\nALTER TABLE users ADD COLUMN nickname text;\nALTER TABLE users ADD CONSTRAINT users_org FOREIGN KEY (org_id) REFERENCES orgs (id) NOT VALID;\nALTER TABLE users DROP CONSTRAINT users_check;",
+		},
+		{
+			name:      "index",
+			migration: "CREATE UNIQUE INDEX users_email ON ONLY public.users (email);\nDROP INDEX users_name;\nREINDEX TABLE users;\nALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY (id);\nALTER TABLE users ADD PRIMARY KEY USING INDEX users_id;",
+			expected:  []string{"1 non-concurrent-index", "2 non-concurrent-index", "3 non-concurrent-index", "4 non-concurrent-index"},
+		},
+		{
+			name:      "columns",
+			migration: "ALTER TABLE users\n  ALTER COLUMN name TYPE varchar(100),\n  ALTER email SET NOT NULL,\n  DROP COLUMN IF EXISTS nickname,\n  ADD COLUMN created_at timestamptz DEFAULT now(),\n  ADD id2 bigserial;",
+			expected:  []string{"1 alter-column-type", "1 set-not-null", "1 drop-column", "1 table-rewrite", "1 table-rewrite"},
+		},
+		{
+			name:      "rewrite",
+			migration: "VACUUM (FULL, ANALYZE) users;\nCLUSTER users USING users_pkey;\nALTER TABLE users SET TABLESPACE fast;\nALTER TABLE users ADD CHECK (age > 0);",
+			expected:  []string{"1 table-rewrite", "2 table-rewrite", "3 table-rewrite", "4 constraint-validation"},
+		},
+		{
+			name:      "new table",
+			migration: "CREATE TABLE IF NOT EXISTS \"Users\" (id int);\nCREATE INDEX users_id ON \"Users\" (id);\nALTER TABLE \"Users\" ADD COLUMN name text NOT NULL DEFAULT '', DROP COLUMN id;\nCREATE INDEX other_id ON users (id);",
+			expected:  []string{"4 non-concurrent-index"},
+		},
+		{
+			name:      "literals and comments",
+			migration: "-- DROP INDEX users_name;\nINSERT INTO log VALUES ('ALTER TABLE users DROP COLUMN x;', $$CLUSTER$$, E'\\'; CLUSTER');\n/* VACUUM FULL; */\nSELECT 1;",
+		},
+		{
+			name:      "suppressed statement",
+			migration: "ALTER TABLE users DROP COLUMN name;\n-- old code stopped reading it\n-- migrate:safety-ignore=drop-column\nALTER TABLE users DROP COLUMN email;\n/* migrate:safety-ignore */\nCREATE INDEX users_age ON users (age);",
+			expected:  []string{"1 drop-column"},
+		},
+		{
+			name:      "suppressed migration",
+			migration: "-- migrate:safety-ignore=drop-column, set-not-null\n\nALTER TABLE users DROP COLUMN name;\nALTER TABLE users ALTER COLUMN email SET NOT NULL;\nDROP INDEX users_name;",
+			expected:  []string{"5 non-concurrent-index"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, err := Check(strings.NewReader(tc.migration))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var actual []string
+			for _, f := range findings {
+				actual = append(actual, fmt.Sprintf("%d %s", f.Line, f.Rule))
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Incorrect findings were: %v wanted %v\n%v", actual, tc.expected, findings)
+			}
+		})
+	}
+}
+
+func TestCheckInvalidDirective(t *testing.T) {
+	for _, migration := range []string{
+		"-- migrate:safety-ignore=drop-table\nDROP TABLE users;",
+		"-- migrate:safety-ignored\nDROP TABLE users;",
+	} {
+		if _, err := Check(strings.NewReader(migration)); err == nil {
+			t.Errorf("Expected error for %q", migration)
+		}
+	}
+}
+
+func TestFindingString(t *testing.T) {
+	f := Finding{Line: 3, Rule: DropColumn, Message: "dropping column a"}
+	if s := f.String(); s != "line 3: dropping column a (drop-column)" {
+		t.Errorf("Incorrect string was: %q", s)
+	}
+}
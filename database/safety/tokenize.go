@@ -0,0 +1,206 @@
+package safety
+
+import "strings"
+
+// token is a token of a statement.
+type token struct {
+	// word is the upper case keyword or unquoted identifier, empty for other
+	// tokens.
+	word string
+	// ident is the identifier: the lower case word or the quoted identifier.
+	ident string
+	// punct is the punctuation character, 0 for other tokens.
+	punct byte
+}
+
+type tokens []token
+
+func (t tokens) is(i int, word string) bool {
+	return i < len(t) && t[i].word == word
+}
+
+func (t tokens) punct(i int, c byte) bool {
+	return i < len(t) && t[i].punct == c
+}
+
+// find returns the index of word at or after i, or -1.
+func (t tokens) find(i int, word string) int {
+	for ; i < len(t); i++ {
+		if t[i].word == word {
+			return i
+		}
+	}
+	return -1
+}
+
+// seq reports whether t contains the words in a row.
+func (t tokens) seq(words ...string) bool {
+	for i := range t {
+		j := 0
+		for j < len(words) && t.is(i+j, words[j]) {
+			j++
+		}
+		if j == len(words) {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns the possibly schema qualified name at i and the index after it.
+func (t tokens) name(i int) (string, int) {
+	var parts []string
+	for i < len(t) && t[i].ident != "" {
+		parts = append(parts, t[i].ident)
+		i++
+		if !t.punct(i, '.') {
+			break
+		}
+		i++
+	}
+	return strings.Join(parts, "."), i
+}
+
+// split splits t at the separator sep outside of parentheses.
+func (t tokens) split(sep byte) []tokens {
+	var (
+		parts []tokens
+		depth int
+		start int
+	)
+	for i, tok := range t {
+		switch tok.punct {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, t[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, t[start:])
+}
+
+// tokenize returns the tokens of the statement text, skipping comments,
+// string constants and the terminating ;. It also returns the comments
+// preceding the first token.
+func tokenize(text string) (tokens, []string) {
+	var (
+		t        tokens
+		comments []string
+	)
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == ';':
+			i++
+		case strings.HasPrefix(text[i:], "--"):
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			if len(t) == 0 {
+				comments = append(comments, text[i+2:i+end])
+			}
+			i += end
+		case strings.HasPrefix(text[i:], "/*"):
+			start, depth := i, 0
+			for i < len(text) {
+				if strings.HasPrefix(text[i:], "/*") {
+					depth++
+					i += 2
+				} else if strings.HasPrefix(text[i:], "*/") {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+				} else {
+					i++
+				}
+			}
+			if len(t) == 0 {
+				comments = append(comments, strings.TrimSuffix(text[start+2:i], "*/"))
+			}
+		case c == '\'':
+			i = skipString(text, i, false)
+			t = append(t, token{})
+		case c == '"':
+			var ident strings.Builder
+			for i++; i < len(text); i++ {
+				if text[i] == '"' {
+					if i+1 < len(text) && text[i+1] == '"' {
+						i++
+					} else {
+						i++
+						break
+					}
+				}
+				ident.WriteByte(text[i])
+			}
+			t = append(t, token{ident: ident.String()})
+		case c == '$' && dollarTag(text[i:]) != "":
+			tag := dollarTag(text[i:])
+			end := strings.Index(text[i+len(tag):], tag)
+			if end < 0 {
+				i = len(text)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			t = append(t, token{})
+		case isIdent(c):
+			start := i
+			for i < len(text) && (isIdent(text[i]) || text[i] == '$') {
+				i++
+			}
+			if word := text[start:i]; (word == "E" || word == "e") && i < len(text) && text[i] == '\'' {
+				i = skipString(text, i, true)
+				t = append(t, token{})
+			} else {
+				t = append(t, token{word: strings.ToUpper(word), ident: strings.ToLower(word)})
+			}
+		default:
+			t = append(t, token{punct: c})
+			i++
+		}
+	}
+	return t, comments
+}
+
+// skipString returns the offset after the string constant starting at i.
+func skipString(text string, i int, backslash bool) int {
+	for i++; i < len(text); i++ {
+		switch {
+		case backslash && text[i] == '\\':
+			i++
+		case text[i] == '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				i++
+			} else {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// dollarTag returns the tag of the dollar quoted string constant starting
+// text, e.g. $$ or $body$, or "".
+func dollarTag(text string) string {
+	for i := 1; i < len(text); i++ {
+		switch {
+		case text[i] == '$':
+			return text[:i+1]
+		case !isIdent(text[i]) || (i == 1 && text[i] >= '0' && text[i] <= '9'):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
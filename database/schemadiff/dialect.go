@@ -0,0 +1,111 @@
+package schemadiff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Postgres is the dialect of PostgreSQL, which alters columns and
+	// constraints of existing tables.
+	Postgres Dialect = postgresDialect{}
+
+	// SQLite is the dialect of SQLite, which keeps the CREATE TABLE statement
+	// of a table. Tables whose statement changed are rebuilt by creating the
+	// new table, copying the rows and replacing the old table.
+	SQLite Dialect = sqliteDialect{}
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) CreateTable(t Table) string {
+	columns := make([]string, 0, len(t.Columns))
+	for _, col := range t.Columns {
+		columns = append(columns, "    "+columnDefinition(col))
+	}
+	return fmt.Sprintf("CREATE TABLE %s (\n%s\n);", Quote(t.Name), strings.Join(columns, ",\n"))
+}
+
+func (postgresDialect) AlterColumns(from Table, to Table) ([]string, bool) {
+	table := Quote(to.Name)
+	toColumns := make(map[string]Column, len(to.Columns))
+	for _, col := range to.Columns {
+		toColumns[col.Name] = col
+	}
+
+	var statements []string
+	fromColumns := make(map[string]Column, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = col
+		if _, ok := toColumns[col.Name]; !ok {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, Quote(col.Name)))
+		}
+	}
+	for _, col := range to.Columns {
+		fromCol, ok := fromColumns[col.Name]
+		if !ok {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, columnDefinition(col)))
+			continue
+		}
+		name := Quote(col.Name)
+		if fromCol.Type != col.Type {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;", table, name, col.Type, name, col.Type))
+		}
+		if fromCol.Default != col.Default {
+			if col.Default == "" {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT;", table, name))
+			} else {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s;", table, name, col.Default))
+			}
+		}
+		if fromCol.NotNull != col.NotNull {
+			if col.NotNull {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", table, name))
+			} else {
+				statements = append(statements, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL;", table, name))
+			}
+		}
+	}
+	return statements, true
+}
+
+func (postgresDialect) AlterConstraints() bool {
+	return true
+}
+
+func columnDefinition(col Column) string {
+	definition := Quote(col.Name) + " " + col.Type
+	if col.Default != "" {
+		definition += " DEFAULT " + col.Default
+	}
+	if col.NotNull {
+		definition += " NOT NULL"
+	}
+	return definition
+}
+
+type sqliteDialect struct{}
+
+// createTableRe matches the beginning of a CREATE TABLE statement up to the table name.
+var createTableRe = regexp.MustCompile("(?is)^\\s*CREATE\\s+TABLE\\s+(IF\\s+NOT\\s+EXISTS\\s+)?(\"(?:[^\"]|\"\")+\"|`[^`]+`|\\[[^\\]]+\\]|[^\\s(]+)")
+
+func (sqliteDialect) CreateTable(t Table) string {
+	// the definition may be of a table with another name, e.g. when rebuilding
+	return renameDefinition(t.Definition, t.Name) + ";"
+}
+
+// renameDefinition returns the CREATE TABLE statement definition creating
+// the table name instead.
+func renameDefinition(definition string, name string) string {
+	return createTableRe.ReplaceAllLiteralString(definition, "CREATE TABLE "+Quote(name))
+}
+
+func (sqliteDialect) AlterColumns(from Table, to Table) ([]string, bool) {
+	// the columns are part of the definition compared by Diff
+	return nil, true
+}
+
+func (sqliteDialect) AlterConstraints() bool {
+	return false
+}
@@ -0,0 +1,316 @@
+// Package schemadiff compares the schemas of two SQL databases and returns the
+// statements changing one into the other. The schemas are read by database
+// drivers implementing database.SchemaInspector.
+package schemadiff
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrDialectMismatch is returned by Diff if the schemas are read from
+// different kinds of databases.
+var ErrDialectMismatch = errors.New("schemadiff: can't compare schemas of different databases")
+
+// Schema holds the tables and views of a database schema. Tables and views
+// are compared by name, a renamed table is dropped and created again.
+type Schema struct {
+	Dialect Dialect
+	Tables  []Table
+	// Views are ordered such that views come after the views they select from.
+	Views []View
+}
+
+// Table is a table with its columns, constraints and indexes.
+type Table struct {
+	Name string
+	// Definition is the CREATE TABLE statement of the table if the database
+	// keeps it, like SQLite. If the definitions of two tables differ, the table
+	// is rebuilt.
+	Definition  string
+	Columns     []Column
+	Constraints []Constraint
+	Indexes     []Index
+}
+
+// Column is a column of a table.
+type Column struct {
+	Name    string
+	Type    string
+	NotNull bool
+	// Default is the default expression, empty if the column has no default.
+	Default string
+}
+
+// Constraint is a named table constraint, e.g. a primary key or a foreign key.
+type Constraint struct {
+	Name string
+	// Definition is the constraint without its name, e.g. "PRIMARY KEY (id)".
+	Definition string
+}
+
+// Index is an index that does not belong to a constraint.
+type Index struct {
+	Name string
+	// Definition is the CREATE INDEX statement.
+	Definition string
+}
+
+// View is a view or materialized view.
+type View struct {
+	Name string
+	// Definition is the CREATE VIEW statement.
+	Definition string
+	// Materialized is set for a materialized view.
+	Materialized bool
+}
+
+// Dialect renders the statements of Diff that depend on the database.
+type Dialect interface {
+	// CreateTable returns the statement creating table t with its columns.
+	// Constraints are added by Diff if the dialect can alter them.
+	CreateTable(t Table) string
+	// AlterColumns returns the statements adding, dropping and changing the
+	// columns of table from into those of table to, or false if the table
+	// has to be rebuilt instead.
+	AlterColumns(from Table, to Table) ([]string, bool)
+	// AlterConstraints reports whether constraints can be added to and dropped
+	// from existing tables.
+	AlterConstraints() bool
+}
+
+// Quote quotes identifier with double quotes.
+func Quote(identifier string) string {
+	return `"` + strings.Replace(identifier, `"`, `""`, -1) + `"`
+}
+
+// changes collects the statements of a diff in the order they have to run.
+type changes struct {
+	dropViews       []string
+	dropIndexes     []string
+	dropForeignKeys []string
+	dropConstraints []string
+	dropTables      []string
+	createTables    []string
+	alterTables     []string
+	addConstraints  []string
+	addForeignKeys  []string
+	createIndexes   []string
+	createViews     []string
+}
+
+func (c *changes) statements() []string {
+	var statements []string
+	for _, s := range [][]string{
+		c.dropViews, c.dropIndexes, c.dropForeignKeys, c.dropConstraints, c.dropTables,
+		c.createTables, c.alterTables, c.addConstraints, c.addForeignKeys, c.createIndexes, c.createViews,
+	} {
+		statements = append(statements, s...)
+	}
+	return statements
+}
+
+// Diff returns the statements changing schema from into schema to. Views are
+// dropped and created again if they, or the tables they select from, change.
+// Columns are compared by name, so a renamed column is dropped and added again.
+func Diff(from *Schema, to *Schema) ([]string, error) {
+	if from.Dialect != to.Dialect {
+		return nil, ErrDialectMismatch
+	}
+	d := from.Dialect
+	c := &changes{}
+
+	fromTables := make(map[string]Table, len(from.Tables))
+	for _, t := range from.Tables {
+		fromTables[t.Name] = t
+	}
+	toTables := make(map[string]Table, len(to.Tables))
+	for _, t := range to.Tables {
+		toTables[t.Name] = t
+	}
+
+	changedTables := make(map[string]bool)
+	for _, t := range from.Tables {
+		if _, ok := toTables[t.Name]; !ok {
+			c.dropTables = append(c.dropTables, fmt.Sprintf("DROP TABLE %s;", Quote(t.Name)))
+			changedTables[t.Name] = true
+		}
+	}
+	for _, t := range to.Tables {
+		ft, ok := fromTables[t.Name]
+		if !ok {
+			createTable(d, t, c)
+			changedTables[t.Name] = true
+			continue
+		}
+		if diffTable(d, ft, t, c) {
+			changedTables[t.Name] = true
+		}
+	}
+
+	diffViews(from.Views, to.Views, len(changedTables) > 0, c)
+	return c.statements(), nil
+}
+
+func createTable(d Dialect, t Table, c *changes) {
+	c.createTables = append(c.createTables, d.CreateTable(t))
+	if d.AlterConstraints() {
+		for _, con := range t.Constraints {
+			addConstraint(t.Name, con, c)
+		}
+	}
+	for _, i := range t.Indexes {
+		c.createIndexes = append(c.createIndexes, i.Definition+";")
+	}
+}
+
+// diffTable adds the statements changing table from into table to and reports
+// whether the table changed.
+func diffTable(d Dialect, from Table, to Table, c *changes) bool {
+	alter, ok := d.AlterColumns(from, to)
+	rebuild := !ok || !sameDefinition(from, to)
+	if rebuild {
+		alter = rebuildTable(d, from, to)
+	}
+	c.alterTables = append(c.alterTables, alter...)
+	changed := len(alter) > 0
+
+	if d.AlterConstraints() {
+		toConstraints := make(map[string]Constraint, len(to.Constraints))
+		for _, con := range to.Constraints {
+			toConstraints[con.Name] = con
+		}
+		fromConstraints := make(map[string]Constraint, len(from.Constraints))
+		for _, con := range from.Constraints {
+			fromConstraints[con.Name] = con
+			if toCon, ok := toConstraints[con.Name]; !ok || toCon.Definition != con.Definition {
+				dropConstraint(to.Name, con, c)
+				changed = true
+			}
+		}
+		for _, con := range to.Constraints {
+			if fromCon, ok := fromConstraints[con.Name]; !ok || fromCon.Definition != con.Definition {
+				addConstraint(to.Name, con, c)
+				changed = true
+			}
+		}
+	}
+
+	// rebuilding a table drops its indexes
+	toIndexes := make(map[string]Index, len(to.Indexes))
+	for _, i := range to.Indexes {
+		toIndexes[i.Name] = i
+	}
+	fromIndexes := make(map[string]Index, len(from.Indexes))
+	for _, i := range from.Indexes {
+		fromIndexes[i.Name] = i
+		if toIndex, ok := toIndexes[i.Name]; !ok || toIndex.Definition != i.Definition {
+			c.dropIndexes = append(c.dropIndexes, fmt.Sprintf("DROP INDEX %s;", Quote(i.Name)))
+			changed = true
+		}
+	}
+	for _, i := range to.Indexes {
+		if fromIndex, ok := fromIndexes[i.Name]; rebuild || !ok || fromIndex.Definition != i.Definition {
+			c.createIndexes = append(c.createIndexes, i.Definition+";")
+			changed = true
+		}
+	}
+	return changed
+}
+
+// rebuildTable returns the statements creating table to under a new name,
+// copying the columns it shares with table from and replacing table from.
+func rebuildTable(d Dialect, from Table, to Table) []string {
+	fromColumns := make(map[string]bool, len(from.Columns))
+	for _, col := range from.Columns {
+		fromColumns[col.Name] = true
+	}
+	var columns []string
+	for _, col := range to.Columns {
+		if fromColumns[col.Name] {
+			columns = append(columns, Quote(col.Name))
+		}
+	}
+
+	tmp := to
+	tmp.Name = to.Name + "_migrate_new"
+	statements := []string{d.CreateTable(tmp)}
+	if len(columns) > 0 {
+		list := strings.Join(columns, ", ")
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;", Quote(tmp.Name), list, list, Quote(from.Name)))
+	}
+	return append(statements,
+		fmt.Sprintf("DROP TABLE %s;", Quote(from.Name)),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", Quote(tmp.Name), Quote(to.Name)))
+}
+
+// sameDefinition reports whether tables from and to have the same definition,
+// ignoring whitespace and how the name of the table is quoted.
+func sameDefinition(from Table, to Table) bool {
+	if from.Definition == to.Definition {
+		return true
+	}
+	return whitespaceRe.ReplaceAllString(renameDefinition(from.Definition, from.Name), " ") ==
+		whitespaceRe.ReplaceAllString(renameDefinition(to.Definition, to.Name), " ")
+}
+
+func isForeignKey(con Constraint) bool {
+	return strings.HasPrefix(con.Definition, "FOREIGN KEY")
+}
+
+func addConstraint(table string, con Constraint, c *changes) {
+	statement := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", Quote(table), Quote(con.Name), con.Definition)
+	if isForeignKey(con) {
+		c.addForeignKeys = append(c.addForeignKeys, statement)
+	} else {
+		c.addConstraints = append(c.addConstraints, statement)
+	}
+}
+
+func dropConstraint(table string, con Constraint, c *changes) {
+	statement := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", Quote(table), Quote(con.Name))
+	if isForeignKey(con) {
+		c.dropForeignKeys = append(c.dropForeignKeys, statement)
+	} else {
+		c.dropConstraints = append(c.dropConstraints, statement)
+	}
+}
+
+// whitespaceRe matches runs of whitespace, which are ignored when comparing views.
+var whitespaceRe = regexp.MustCompile(`\s+`)
+
+// diffViews drops the views of from that are removed or changed and creates
+// the views of to that are new or changed. If tables changed, all views are
+// created again, as they may select from the changed tables.
+func diffViews(from []View, to []View, tablesChanged bool, c *changes) {
+	toViews := make(map[string]View, len(to))
+	for _, v := range to {
+		toViews[v.Name] = v
+	}
+	recreate := make(map[string]bool)
+	for i := len(from) - 1; i >= 0; i-- {
+		v := from[i]
+		toView, ok := toViews[v.Name]
+		if ok && !tablesChanged && whitespaceRe.ReplaceAllString(toView.Definition, " ") == whitespaceRe.ReplaceAllString(v.Definition, " ") {
+			continue
+		}
+		kind := "VIEW"
+		if v.Materialized {
+			kind = "MATERIALIZED VIEW"
+		}
+		c.dropViews = append(c.dropViews, fmt.Sprintf("DROP %s %s;", kind, Quote(v.Name)))
+		recreate[v.Name] = ok
+	}
+
+	fromViews := make(map[string]bool, len(from))
+	for _, v := range from {
+		fromViews[v.Name] = true
+	}
+	for _, v := range to {
+		if !fromViews[v.Name] || recreate[v.Name] {
+			c.createViews = append(c.createViews, v.Definition+";")
+		}
+	}
+}
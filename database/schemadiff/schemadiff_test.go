@@ -0,0 +1,129 @@
+package schemadiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffPostgres(t *testing.T) {
+	users := Table{
+		Name: "users",
+		Columns: []Column{
+			{Name: "id", Type: "serial", NotNull: true},
+			{Name: "name", Type: "text"},
+			{Name: "nickname", Type: "text"},
+		},
+		Constraints: []Constraint{{Name: "users_pkey", Definition: "PRIMARY KEY (id)"}},
+	}
+	from := &Schema{
+		Dialect: Postgres,
+		Tables:  []Table{users, {Name: "legacy", Columns: []Column{{Name: "id", Type: "integer"}}}},
+		Views:   []View{{Name: "names", Definition: "CREATE VIEW \"names\" AS\n SELECT name FROM users"}},
+	}
+
+	users.Columns = []Column{
+		{Name: "id", Type: "serial", NotNull: true},
+		{Name: "name", Type: "character varying(100)", NotNull: true, Default: "''::character varying"},
+		{Name: "email", Type: "text"},
+	}
+	users.Indexes = []Index{{Name: "users_email", Definition: "CREATE INDEX users_email ON users USING btree (email)"}}
+	orders := Table{
+		Name:    "orders",
+		Columns: []Column{{Name: "id", Type: "integer", NotNull: true}, {Name: "user_id", Type: "integer"}},
+		Constraints: []Constraint{
+			{Name: "orders_user_id_fkey", Definition: "FOREIGN KEY (user_id) REFERENCES users(id)"},
+			{Name: "orders_pkey", Definition: "PRIMARY KEY (id)"},
+		},
+	}
+	to := &Schema{
+		Dialect: Postgres,
+		Tables:  []Table{users, orders},
+		Views:   []View{{Name: "names", Definition: "CREATE VIEW \"names\" AS\n SELECT name FROM users"}},
+	}
+
+	up, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`DROP VIEW "names";`,
+		`DROP TABLE "legacy";`,
+		"CREATE TABLE \"orders\" (\n    \"id\" integer NOT NULL,\n    \"user_id\" integer\n);",
+		`ALTER TABLE "users" DROP COLUMN "nickname";`,
+		`ALTER TABLE "users" ALTER COLUMN "name" TYPE character varying(100) USING "name"::character varying(100);`,
+		`ALTER TABLE "users" ALTER COLUMN "name" SET DEFAULT ''::character varying;`,
+		`ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL;`,
+		`ALTER TABLE "users" ADD COLUMN "email" text;`,
+		`ALTER TABLE "orders" ADD CONSTRAINT "orders_pkey" PRIMARY KEY (id);`,
+		`ALTER TABLE "orders" ADD CONSTRAINT "orders_user_id_fkey" FOREIGN KEY (user_id) REFERENCES users(id);`,
+		`CREATE INDEX users_email ON users USING btree (email);`,
+		"CREATE VIEW \"names\" AS\n SELECT name FROM users;",
+	}
+	if !reflect.DeepEqual(up, expected) {
+		t.Errorf("Incorrect up was:\n%q\nwanted\n%q", up, expected)
+	}
+
+	down, err := Diff(to, from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []string{
+		`DROP VIEW "names";`,
+		`DROP INDEX "users_email";`,
+		`DROP TABLE "orders";`,
+		"CREATE TABLE \"legacy\" (\n    \"id\" integer\n);",
+		`ALTER TABLE "users" DROP COLUMN "email";`,
+		`ALTER TABLE "users" ALTER COLUMN "name" TYPE text USING "name"::text;`,
+		`ALTER TABLE "users" ALTER COLUMN "name" DROP DEFAULT;`,
+		`ALTER TABLE "users" ALTER COLUMN "name" DROP NOT NULL;`,
+		`ALTER TABLE "users" ADD COLUMN "nickname" text;`,
+		"CREATE VIEW \"names\" AS\n SELECT name FROM users;",
+	}
+	if !reflect.DeepEqual(down, expected) {
+		t.Errorf("Incorrect down was:\n%q\nwanted\n%q", down, expected)
+	}
+
+	if up, err := Diff(to, to); err != nil || len(up) != 0 {
+		t.Errorf("Incorrect diff of equal schemas was: %q (%v)", up, err)
+	}
+}
+
+func TestDiffSQLite(t *testing.T) {
+	from := &Schema{
+		Dialect: SQLite,
+		Tables: []Table{{
+			Name:       "users",
+			Definition: "CREATE TABLE users (id integer PRIMARY KEY, name text)",
+			Columns:    []Column{{Name: "id"}, {Name: "name"}},
+			Indexes:    []Index{{Name: "users_name", Definition: "CREATE INDEX users_name ON users (name)"}},
+		}},
+	}
+	to := &Schema{
+		Dialect: SQLite,
+		Tables: []Table{{
+			Name:       "users",
+			Definition: "CREATE TABLE users (id integer PRIMARY KEY, name text NOT NULL, email text UNIQUE)",
+			Columns:    []Column{{Name: "id"}, {Name: "name"}, {Name: "email"}},
+			Indexes:    []Index{{Name: "users_name", Definition: "CREATE INDEX users_name ON users (name)"}},
+		}},
+	}
+
+	up, err := Diff(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`CREATE TABLE "users_migrate_new" (id integer PRIMARY KEY, name text NOT NULL, email text UNIQUE);`,
+		`INSERT INTO "users_migrate_new" ("id", "name") SELECT "id", "name" FROM "users";`,
+		`DROP TABLE "users";`,
+		`ALTER TABLE "users_migrate_new" RENAME TO "users";`,
+		`CREATE INDEX users_name ON users (name);`,
+	}
+	if !reflect.DeepEqual(up, expected) {
+		t.Errorf("Incorrect up was:\n%q\nwanted\n%q", up, expected)
+	}
+
+	if _, err := Diff(from, &Schema{Dialect: Postgres}); err != ErrDialectMismatch {
+		t.Errorf("Incorrect error was: %v wanted %v", err, ErrDialectMismatch)
+	}
+}
@@ -0,0 +1,126 @@
+package sqlite3
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+)
+
+// migrateTables returns the names of the tables used by migrate itself.
+func (m *Sqlite) migrateTables() map[string]bool {
+	return map[string]bool{
+		m.config.MigrationsTable: true,
+		m.namespaceTable():       true,
+		m.repeatableTable():      true,
+		m.phaseTable():           true,
+		m.appliedTable():         true,
+		m.skippedTable():         true,
+	}
+}
+
+// DumpSchema implements database.SchemaDumper. It writes the statements
+// recorded in sqlite_master, tables first, in the order they were created.
+func (m *Sqlite) DumpSchema(w io.Writer) (err error) {
+	query := `SELECT tbl_name, sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, rowid`
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	skip := m.migrateTables()
+	for rows.Next() {
+		var table, statement string
+		if err := rows.Scan(&table, &statement); err != nil {
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		if skip[table] {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s;\n\n", statement); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// InspectSchema implements database.SchemaInspector. Tables are compared by
+// the CREATE TABLE statements recorded in sqlite_master, their columns are
+// read with PRAGMA table_info.
+func (m *Sqlite) InspectSchema() (s *schemadiff.Schema, err error) {
+	query := `SELECT type, name, tbl_name, sql FROM sqlite_master WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, rowid`
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	s = &schemadiff.Schema{Dialect: schemadiff.SQLite}
+	skip := m.migrateTables()
+	tables := make(map[string]int)
+	for rows.Next() {
+		var objectType, name, table, statement string
+		if err := rows.Scan(&objectType, &name, &table, &statement); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		switch {
+		case skip[table]:
+		case objectType == "table":
+			tables[name] = len(s.Tables)
+			s.Tables = append(s.Tables, schemadiff.Table{Name: name, Definition: statement})
+		case objectType == "index":
+			if i, ok := tables[table]; ok {
+				s.Tables[i].Indexes = append(s.Tables[i].Indexes, schemadiff.Index{Name: name, Definition: statement})
+			}
+		case objectType == "view":
+			s.Views = append(s.Views, schemadiff.View{Name: name, Definition: statement})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	for i := range s.Tables {
+		if s.Tables[i].Columns, err = m.columns(s.Tables[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (m *Sqlite) columns(table string) (columns []schemadiff.Column, err error) {
+	query := `PRAGMA table_info(` + schemadiff.Quote(table) + `)`
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var cid, pk int
+		var col schemadiff.Column
+		var def sql.NullString
+		if err := rows.Scan(&cid, &col.Name, &col.Type, &col.NotNull, &def, &pk); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		col.Default = def.String
+		columns = append(columns, col)
+	}
+	return columns, rows.Err()
+}
@@ -1,6 +1,7 @@
 package sqlite3
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
@@ -36,6 +37,9 @@ type Sqlite struct {
 	db       *sql.DB
 	isLocked bool
 
+	// namespace is set on drivers returned by Namespace
+	namespace string
+
 	config *Config
 }
 
@@ -129,6 +133,10 @@ func (m *Sqlite) Open(url string) (database.Driver, error) {
 }
 
 func (m *Sqlite) Close() error {
+	if m.namespace != "" {
+		// the connection is shared with the parent driver
+		return nil
+	}
 	return m.db.Close()
 }
 
@@ -161,7 +169,7 @@ func (m *Sqlite) Drop() (err error) {
 	if len(tableNames) > 0 {
 		for _, t := range tableNames {
 			query := "DROP TABLE " + t
-			err = m.executeQuery(query)
+			err = m.executeQuery(context.Background(), query)
 			if err != nil {
 				return &database.Error{OrigErr: err, Query: []byte(query)}
 			}
@@ -193,24 +201,38 @@ func (m *Sqlite) Unlock() error {
 }
 
 func (m *Sqlite) Run(migration io.Reader) error {
+	return m.RunWithOptions(migration, database.RunOptions{})
+}
+
+// RunWithOptions is part of database.OptionsRunner interface implementation.
+// SQLite runs all statements of a migration anyway, so MultiStatement has
+// no effect.
+func (m *Sqlite) RunWithOptions(migration io.Reader, options database.RunOptions) error {
 	migr, err := ioutil.ReadAll(migration)
 	if err != nil {
 		return err
 	}
 	query := string(migr[:])
 
-	if m.config.NoTxWrap {
-		return m.executeQueryNoTx(query)
+	ctx := context.Background()
+	if options.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	if m.config.NoTxWrap || options.NoTransaction {
+		return m.executeQueryNoTx(ctx, query)
 	}
-	return m.executeQuery(query)
+	return m.executeQuery(ctx, query)
 }
 
-func (m *Sqlite) executeQuery(query string) error {
-	tx, err := m.db.Begin()
+func (m *Sqlite) executeQuery(ctx context.Context, query string) error {
+	tx, err := m.db.BeginTx(ctx, nil)
 	if err != nil {
 		return &database.Error{OrigErr: err, Err: "transaction start failed"}
 	}
-	if _, err := tx.Exec(query); err != nil {
+	if _, err := tx.ExecContext(ctx, query); err != nil {
 		if errRollback := tx.Rollback(); errRollback != nil {
 			err = multierror.Append(err, errRollback)
 		}
@@ -222,14 +244,18 @@ func (m *Sqlite) executeQuery(query string) error {
 	return nil
 }
 
-func (m *Sqlite) executeQueryNoTx(query string) error {
-	if _, err := m.db.Exec(query); err != nil {
+func (m *Sqlite) executeQueryNoTx(ctx context.Context, query string) error {
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
 		return &database.Error{OrigErr: err, Query: []byte(query)}
 	}
 	return nil
 }
 
 func (m *Sqlite) SetVersion(version int, dirty bool) error {
+	if m.namespace != "" {
+		return m.setNamespaceVersion(version, dirty)
+	}
+
 	tx, err := m.db.Begin()
 	if err != nil {
 		return &database.Error{OrigErr: err, Err: "transaction start failed"}
@@ -261,6 +287,10 @@ func (m *Sqlite) SetVersion(version int, dirty bool) error {
 }
 
 func (m *Sqlite) Version() (version int, dirty bool, err error) {
+	if m.namespace != "" {
+		return m.namespaceVersion()
+	}
+
 	query := "SELECT version, dirty FROM " + m.config.MigrationsTable + " LIMIT 1"
 	err = m.db.QueryRow(query).Scan(&version, &dirty)
 	if err != nil {
@@ -268,3 +298,321 @@ func (m *Sqlite) Version() (version int, dirty bool, err error) {
 	}
 	return version, dirty, nil
 }
+
+// Namespace implements database.Namespacer. The versions of all namespaces
+// but the root one are kept in the "<migrations table>_namespaces" table,
+// one row per namespace.
+func (m *Sqlite) Namespace(name string) (database.Driver, error) {
+	if name == "" {
+		return m, nil
+	}
+
+	mx := &Sqlite{
+		db:        m.db,
+		config:    m.config,
+		namespace: name,
+	}
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (namespace text primary key, version uint64, dirty bool)`, mx.namespaceTable())
+	if _, err := m.db.Exec(query); err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return mx, nil
+}
+
+func (m *Sqlite) namespaceTable() string {
+	return m.config.MigrationsTable + "_namespaces"
+}
+
+func (m *Sqlite) setNamespaceVersion(version int, dirty bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction start failed"}
+	}
+
+	query := "DELETE FROM " + m.namespaceTable() + " WHERE namespace = ?"
+	if _, err := tx.Exec(query, m.namespace); err != nil {
+		if errRollback := tx.Rollback(); errRollback != nil {
+			err = multierror.Append(err, errRollback)
+		}
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+
+	if version >= 0 || (version == database.NilVersion && dirty) {
+		query := fmt.Sprintf(`INSERT INTO %s (namespace, version, dirty) VALUES (?, ?, ?)`, m.namespaceTable())
+		if _, err := tx.Exec(query, m.namespace, version, dirty); err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				err = multierror.Append(err, errRollback)
+			}
+			return &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return &database.Error{OrigErr: err, Err: "transaction commit failed"}
+	}
+
+	return nil
+}
+
+func (m *Sqlite) namespaceVersion() (version int, dirty bool, err error) {
+	query := "SELECT version, dirty FROM " + m.namespaceTable() + " WHERE namespace = ? LIMIT 1"
+	err = m.db.QueryRow(query, m.namespace).Scan(&version, &dirty)
+	if err != nil {
+		return database.NilVersion, false, nil
+	}
+	return version, dirty, nil
+}
+
+func (m *Sqlite) repeatableTable() string {
+	return m.config.MigrationsTable + "_repeatable"
+}
+
+// repeatableKey prefixes identifier with the namespace, so that repeatable
+// migrations of different namespaces don't collide.
+func (m *Sqlite) repeatableKey(identifier string) string {
+	if m.namespace != "" {
+		return m.namespace + "/" + identifier
+	}
+	return identifier
+}
+
+func (m *Sqlite) ensureRepeatableTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (identifier text primary key, checksum text, applied_at datetime default current_timestamp)`, m.repeatableTable())
+	if _, err := m.db.Exec(query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// RepeatableChecksum implements database.RepeatableTracker.
+func (m *Sqlite) RepeatableChecksum(identifier string) (checksum string, err error) {
+	if err := m.ensureRepeatableTable(); err != nil {
+		return "", err
+	}
+
+	query := "SELECT checksum FROM " + m.repeatableTable() + " WHERE identifier = ?"
+	err = m.db.QueryRow(query, m.repeatableKey(identifier)).Scan(&checksum)
+	switch {
+	case err == sql.ErrNoRows:
+		return "", nil
+	case err != nil:
+		return "", &database.Error{OrigErr: err, Query: []byte(query)}
+	default:
+		return checksum, nil
+	}
+}
+
+// SetRepeatableChecksum implements database.RepeatableTracker.
+func (m *Sqlite) SetRepeatableChecksum(identifier string, checksum string) error {
+	if err := m.ensureRepeatableTable(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (identifier, checksum) VALUES (?, ?)`, m.repeatableTable())
+	if _, err := m.db.Exec(query, m.repeatableKey(identifier), checksum); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (m *Sqlite) phaseTable() string {
+	return m.config.MigrationsTable + "_phases"
+}
+
+// phaseKey prefixes phase with the namespace, so that the phases of
+// different namespaces don't collide.
+func (m *Sqlite) phaseKey(phase string) string {
+	if m.namespace != "" {
+		return m.namespace + "/" + phase
+	}
+	return phase
+}
+
+func (m *Sqlite) ensurePhaseTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (phase text, version bigint, applied_at datetime default current_timestamp, primary key (phase, version))`, m.phaseTable())
+	if _, err := m.db.Exec(query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// PhaseVersions implements database.PhaseTracker. The phase table is only
+// created by SetPhaseApplied, a missing table has no versions.
+func (m *Sqlite) PhaseVersions(phase string) (versions []int, err error) {
+	if exists, err := m.tableExists(m.phaseTable()); err != nil || !exists {
+		return nil, err
+	}
+
+	query := "SELECT version FROM " + m.phaseTable() + " WHERE phase = ?"
+	rows, err := m.db.Query(query, m.phaseKey(phase))
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetPhaseApplied implements database.PhaseTracker.
+func (m *Sqlite) SetPhaseApplied(phase string, version int, applied bool) error {
+	if !applied {
+		if exists, err := m.tableExists(m.phaseTable()); err != nil || !exists {
+			return err
+		}
+	} else if err := m.ensurePhaseTable(); err != nil {
+		return err
+	}
+
+	query := "DELETE FROM " + m.phaseTable() + " WHERE phase = ? AND version = ?"
+	if applied {
+		query = "INSERT OR REPLACE INTO " + m.phaseTable() + " (phase, version) VALUES (?, ?)"
+	}
+	if _, err := m.db.Exec(query, m.phaseKey(phase), version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+func (m *Sqlite) appliedTable() string {
+	return m.config.MigrationsTable + "_applied"
+}
+
+func (m *Sqlite) ensureAppliedTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (version bigint primary key, applied_at datetime default current_timestamp)`, m.appliedTable())
+	if _, err := m.db.Exec(query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// AppliedVersions implements database.AppliedTracker.
+func (m *Sqlite) AppliedVersions() (versions []int, err error) {
+	if err := m.ensureAppliedTable(); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT version FROM " + m.appliedTable()
+	rows, err := m.db.Query(query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// SetApplied implements database.AppliedTracker.
+func (m *Sqlite) SetApplied(version int, applied bool) error {
+	if err := m.ensureAppliedTable(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE version = ?`, m.appliedTable())
+	if applied {
+		query = fmt.Sprintf(`INSERT OR IGNORE INTO %s (version) VALUES (?)`, m.appliedTable())
+	}
+	if _, err := m.db.Exec(query, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// tableExists reports whether the table name exists.
+func (m *Sqlite) tableExists(name string) (bool, error) {
+	query := `SELECT COUNT(1) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	var count int
+	if err := m.db.QueryRow(query, name).Scan(&count); err != nil {
+		return false, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return count > 0, nil
+}
+
+func (m *Sqlite) skippedTable() string {
+	return m.config.MigrationsTable + "_skipped"
+}
+
+func (m *Sqlite) ensureSkippedTable() error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (namespace text not null, version bigint not null, identifier text not null, skipped_at datetime default current_timestamp, primary key (namespace, version))`, m.skippedTable())
+	if _, err := m.db.Exec(query); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// Skip implements database.SkipRecorder.
+func (m *Sqlite) Skip(version int, identifier string) error {
+	if err := m.ensureSkippedTable(); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT OR REPLACE INTO %s (namespace, version, identifier) VALUES (?, ?, ?)`, m.skippedTable())
+	if _, err := m.db.Exec(query, m.namespace, version, identifier); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// SkippedVersions implements database.SkipRecorder. The ledger table is
+// only created by Skip, a missing table has no skipped versions.
+func (m *Sqlite) SkippedVersions() (versions []int, err error) {
+	if exists, err := m.tableExists(m.skippedTable()); err != nil || !exists {
+		return nil, err
+	}
+
+	query := "SELECT version FROM " + m.skippedTable() + " WHERE namespace = ?"
+	rows, err := m.db.Query(query, m.namespace)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// Unskip implements database.SkipRecorder.
+func (m *Sqlite) Unskip(version int) error {
+	if exists, err := m.tableExists(m.skippedTable()); err != nil || !exists {
+		return err
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE namespace = ? AND version = ?`, m.skippedTable())
+	if _, err := m.db.Exec(query, m.namespace, version); err != nil {
+		return &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	return nil
+}
+
+// BeginTx implements database.TxBeginner.
+func (m *Sqlite) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return m.db.BeginTx(ctx, opts)
+}
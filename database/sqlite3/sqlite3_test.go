@@ -6,11 +6,13 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
 	dt "github.com/golang-migrate/migrate/v4/database/testing"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/mattn/go-sqlite3"
@@ -69,6 +71,13 @@ func TestMigrate(t *testing.T) {
 		t.Fatal(err)
 	}
 	dt.TestMigrate(t, m)
+
+	// tables of opt-in features are not created by plain migrations
+	for _, table := range []string{"schema_migrations_phases", "schema_migrations_skipped"} {
+		exists, err := driver.(*Sqlite).tableExists(table)
+		assert.NoError(t, err)
+		assert.False(t, exists, table)
+	}
 }
 
 func TestMigrationTable(t *testing.T) {
@@ -181,3 +190,333 @@ func TestMigrateWithDirectoryNameContainsWhitespaces(t *testing.T) {
 	}
 	dt.Test(t, d, []byte("CREATE TABLE t (Qty int, Name string);"))
 }
+
+func TestNamespace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-namespace")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	nd, err := d.(*Sqlite).Namespace("http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt.TestSetVersion(t, nd)
+
+	if err := nd.SetVersion(3, false); err != nil {
+		t.Fatal(err)
+	}
+	v, _, err := d.Version()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, -1, v, "namespace versions must not touch the root version")
+	assert.NoError(t, nd.Close())
+}
+
+func TestRepeatableChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-repeatable")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tracker := d.(*Sqlite)
+	checksum, err := tracker.RepeatableChecksum("views")
+	assert.NoError(t, err)
+	assert.Equal(t, "", checksum)
+
+	assert.NoError(t, tracker.SetRepeatableChecksum("views", "abc"))
+	assert.NoError(t, tracker.SetRepeatableChecksum("views", "def"))
+	checksum, err = tracker.RepeatableChecksum("views")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", checksum)
+}
+
+func TestPhaseVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-phase")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tracker := d.(*Sqlite)
+	versions, err := tracker.PhaseVersions("expand")
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+	assert.NoError(t, tracker.SetPhaseApplied("expand", 1, false))
+	exists, err := tracker.tableExists(tracker.phaseTable())
+	assert.NoError(t, err)
+	assert.False(t, exists, "the phase table must only be created once a version is applied")
+
+	assert.NoError(t, tracker.SetPhaseApplied("expand", 1, true))
+	assert.NoError(t, tracker.SetPhaseApplied("expand", 3, true))
+	assert.NoError(t, tracker.SetPhaseApplied("expand", 3, true))
+	versions, err = tracker.PhaseVersions("expand")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 3}, versions)
+
+	assert.NoError(t, tracker.SetPhaseApplied("expand", 1, false))
+	versions, err = tracker.PhaseVersions("expand")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, versions)
+
+	versions, err = tracker.PhaseVersions("contract")
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+}
+
+func TestAppliedVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-applied")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	tracker := d.(*Sqlite)
+	versions, err := tracker.AppliedVersions()
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	assert.NoError(t, tracker.SetApplied(3, true))
+	assert.NoError(t, tracker.SetApplied(1, true))
+	assert.NoError(t, tracker.SetApplied(3, true))
+	versions, err = tracker.AppliedVersions()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 3}, versions)
+
+	assert.NoError(t, tracker.SetApplied(3, false))
+	versions, err = tracker.AppliedVersions()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, versions)
+}
+
+func TestSkippedVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-skipped")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	recorder := d.(*Sqlite)
+	versions, err := recorder.SkippedVersions()
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+	assert.NoError(t, recorder.Unskip(3))
+	exists, err := recorder.tableExists(recorder.skippedTable())
+	assert.NoError(t, err)
+	assert.False(t, exists, "the ledger table must only be created by Skip")
+
+	assert.NoError(t, recorder.Skip(3, "demo@dev"))
+	assert.NoError(t, recorder.Skip(1, "seed@dev"))
+	assert.NoError(t, recorder.Skip(3, "demo@dev"))
+	versions, err = recorder.SkippedVersions()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{1, 3}, versions)
+
+	ns, err := recorder.Namespace("http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err = ns.(*Sqlite).SkippedVersions()
+	assert.NoError(t, err)
+	assert.Empty(t, versions)
+
+	assert.NoError(t, recorder.Unskip(3))
+	versions, err = recorder.SkippedVersions()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1}, versions)
+}
+
+func TestDumpSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-dump")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	migration := `CREATE TABLE users (id integer primary key, email text not null);
+		CREATE VIEW emails AS SELECT email FROM users;
+		CREATE UNIQUE INDEX users_email ON users (email);`
+	assert.NoError(t, d.Run(strings.NewReader(migration)))
+	assert.NoError(t, d.SetVersion(1, false))
+	assert.NoError(t, d.(*Sqlite).SetPhaseApplied("expand", 1, true))
+
+	var schema strings.Builder
+	assert.NoError(t, d.(*Sqlite).DumpSchema(&schema))
+	assert.Equal(t, "CREATE TABLE users (id integer primary key, email text not null);\n\n"+
+		"CREATE UNIQUE INDEX users_email ON users (email);\n\n"+
+		"CREATE VIEW emails AS SELECT email FROM users;\n\n", schema.String())
+}
+
+func TestInspectSchema(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite3-driver-test-inspect")
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			t.Error(err)
+		}
+	}()
+	p := &Sqlite{}
+	addr := fmt.Sprintf("sqlite3://%s", filepath.Join(dir, "sqlite3.db"))
+	d, err := p.Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := d.Close(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	assert.NoError(t, d.Run(strings.NewReader(`CREATE TABLE users (id integer primary key, email text not null);
+		INSERT INTO users (email) VALUES ('a@example.com');
+		CREATE UNIQUE INDEX users_email ON users (email);`)))
+	from, err := d.(*Sqlite).InspectSchema()
+	assert.NoError(t, err)
+	assert.Equal(t, []schemadiff.Column{
+		{Name: "id", Type: "integer"},
+		{Name: "email", Type: "text", NotNull: true},
+	}, from.Tables[0].Columns)
+
+	assert.NoError(t, d.Run(strings.NewReader(`DROP TABLE users;
+		CREATE TABLE users (id integer primary key, email text not null, name text default 'anonymous');
+		CREATE UNIQUE INDEX users_email ON users (email);
+		CREATE VIEW names AS SELECT name FROM users;`)))
+	to, err := d.(*Sqlite).InspectSchema()
+	assert.NoError(t, err)
+	assert.Len(t, to.Views, 1)
+
+	// the diff rebuilds the table keeping its rows
+	assert.NoError(t, d.Run(strings.NewReader(`DROP VIEW names;
+		DROP TABLE users;
+		CREATE TABLE users (id integer primary key, email text not null);
+		INSERT INTO users (email) VALUES ('a@example.com');
+		CREATE UNIQUE INDEX users_email ON users (email);`)))
+	up, err := schemadiff.Diff(from, to)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Run(strings.NewReader(strings.Join(up, "\n"))))
+
+	recreated, err := d.(*Sqlite).InspectSchema()
+	assert.NoError(t, err)
+	diff, err := schemadiff.Diff(to, recreated)
+	assert.NoError(t, err)
+	assert.Empty(t, diff)
+
+	var name string
+	assert.NoError(t, d.(*Sqlite).db.QueryRow(`SELECT name FROM names`).Scan(&name))
+	assert.Equal(t, "anonymous", name)
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	migrations := filepath.Join(dir, "migrations")
+	assert.NoError(t, os.Mkdir(migrations, os.ModePerm))
+	for file, content := range map[string]string{
+		"1_users.up.sql":     "CREATE TABLE users (id integer PRIMARY KEY, name text);",
+		"1_users.down.sql":   "DROP TABLE users;",
+		"2_name.up.sql":      "CREATE INDEX users_name ON users (name);",
+		"2_name.down.sql":    "DROP INDEX users_name;",
+		"3_names.up.sql":     "CREATE VIEW names AS SELECT name FROM users;",
+		"3_names.down.sql":   "DROP VIEW names;",
+		"4_default.up.sql":   "INSERT INTO users (name) VALUES ('admin');",
+		"4_default.down.sql": "DELETE FROM users WHERE name = 'admin';",
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(migrations, file), []byte(content), 0644))
+	}
+
+	dt.TestMigrationsRoundTrip(t, "file://"+migrations, "sqlite3://"+filepath.Join(dir, "sqlite3.db"))
+}
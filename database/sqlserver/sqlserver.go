@@ -7,10 +7,13 @@ import (
 	"io"
 	"io/ioutil"
 	nurl "net/url"
+	"strings"
+	"time"
 
 	mssql "github.com/denisenkom/go-mssqldb" // mssql support
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/multistmt"
 	"github.com/hashicorp/go-multierror"
 )
 
@@ -21,6 +24,12 @@ func init() {
 // DefaultMigrationsTable is the name of the migrations table in the database
 var DefaultMigrationsTable = "schema_migrations"
 
+var (
+	// multiStmtMaxSize limits the size of a single batch of a migration
+	// with the multi-statement directive.
+	multiStmtMaxSize = 10 * 1 << 20 // 10 MB
+)
+
 var (
 	ErrNilConfig      = fmt.Errorf("no config")
 	ErrNoDatabaseName = fmt.Errorf("no database name")
@@ -202,22 +211,60 @@ func (ss *SQLServer) Unlock() error {
 
 // Run the migrations for the database
 func (ss *SQLServer) Run(migration io.Reader) error {
+	return ss.RunWithOptions(migration, database.RunOptions{})
+}
+
+// RunWithOptions is part of database.OptionsRunner interface implementation.
+// Migrations never run in a transaction, so NoTransaction has no effect.
+func (ss *SQLServer) RunWithOptions(migration io.Reader, options database.RunOptions) error {
+	if options.MultiStatement {
+		var err error
+		if e := multistmt.Split(migration, multistmt.TSQL, multiStmtMaxSize, func(s multistmt.Statement) bool {
+			if err = ss.runStatement(s.Text, s.Line, options.Timeout); err != nil {
+				return false
+			}
+			return true
+		}); e != nil {
+			return e
+		}
+		return err
+	}
+
 	migr, err := ioutil.ReadAll(migration)
 	if err != nil {
 		return err
 	}
+	return ss.runStatement(migr, 0, options.Timeout)
+}
+
+// runStatement runs a batch. line is the line of the migration the batch
+// starts at, or 0 if migr is the whole migration.
+func (ss *SQLServer) runStatement(migr []byte, line uint, timeout time.Duration) error {
+	ctx := context.Background()
+	if timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	// run migration
 	query := string(migr[:])
-	if _, err := ss.conn.ExecContext(context.Background(), query); err != nil {
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	if _, err := ss.conn.ExecContext(ctx, query); err != nil {
 		if msErr, ok := err.(mssql.Error); ok {
 			message := fmt.Sprintf("migration failed: %s", msErr.Message)
 			if msErr.ProcName != "" {
 				message = fmt.Sprintf("%s (proc name %s)", msErr.Message, msErr.ProcName)
 			}
-			return database.Error{OrigErr: err, Err: message, Query: migr, Line: uint(msErr.LineNo)}
+			errLine := uint(msErr.LineNo)
+			if line > 0 {
+				errLine += line - 1
+			}
+			return database.Error{OrigErr: err, Err: message, Query: migr, Line: errLine}
 		}
-		return database.Error{OrigErr: err, Err: "migration failed", Query: migr}
+		return database.Error{OrigErr: err, Err: "migration failed", Query: migr, Line: line}
 	}
 
 	return nil
@@ -344,3 +391,8 @@ func (ss *SQLServer) ensureVersionTable() (err error) {
 
 	return nil
 }
+
+// BeginTx implements database.TxBeginner.
+func (ss *SQLServer) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return ss.conn.BeginTx(ctx, opts)
+}
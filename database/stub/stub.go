@@ -1,6 +1,7 @@
 package stub
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"reflect"
@@ -20,6 +21,15 @@ type Stub struct {
 	LastRunMigration  []byte // todo: make []string
 	IsDirty           bool
 	IsLocked          bool
+	Namespaces        map[string]*Stub
+	Checksums         map[string]string
+	Baselines         []string
+	Skipped           map[int]string
+	Phases            map[string]map[int]bool
+	Applied           map[int]bool
+	Schema            string
+	LastRunOptions    database.RunOptions
+	Logger            database.Logger
 
 	Config *Config
 }
@@ -71,6 +81,15 @@ func (s *Stub) Run(migration io.Reader) error {
 	return nil
 }
 
+func (s *Stub) RunWithOptions(migration io.Reader, options database.RunOptions) error {
+	s.LastRunOptions = options
+	return s.Run(migration)
+}
+
+func (s *Stub) SetLogger(logger database.Logger) {
+	s.Logger = logger
+}
+
 func (s *Stub) SetVersion(version int, state bool) error {
 	s.CurrentVersion = version
 	s.IsDirty = state
@@ -81,6 +100,113 @@ func (s *Stub) Version() (version int, dirty bool, err error) {
 	return s.CurrentVersion, s.IsDirty, nil
 }
 
+// Namespace implements database.Namespacer. Each namespace is tracked by its
+// own Stub, which is kept in Namespaces.
+func (s *Stub) Namespace(name string) (database.Driver, error) {
+	if name == "" {
+		return s, nil
+	}
+	if s.Namespaces == nil {
+		s.Namespaces = make(map[string]*Stub)
+	}
+	if _, ok := s.Namespaces[name]; !ok {
+		s.Namespaces[name] = &Stub{
+			Url:               s.Url,
+			Instance:          s.Instance,
+			CurrentVersion:    database.NilVersion,
+			MigrationSequence: make([]string, 0),
+			Config:            s.Config,
+		}
+	}
+	return s.Namespaces[name], nil
+}
+
+func (s *Stub) RepeatableChecksum(identifier string) (string, error) {
+	return s.Checksums[identifier], nil
+}
+
+func (s *Stub) SetRepeatableChecksum(identifier string, checksum string) error {
+	if s.Checksums == nil {
+		s.Checksums = make(map[string]string)
+	}
+	s.Checksums[identifier] = checksum
+	return nil
+}
+
+func (s *Stub) Baseline(version int, description string) error {
+	s.Baselines = append(s.Baselines, fmt.Sprintf("%v %v", version, description))
+	return nil
+}
+
+func (s *Stub) Skip(version int, identifier string) error {
+	if s.Skipped == nil {
+		s.Skipped = make(map[int]string)
+	}
+	s.Skipped[version] = identifier
+	return nil
+}
+
+func (s *Stub) SkippedVersions() ([]int, error) {
+	versions := make([]int, 0, len(s.Skipped))
+	for v := range s.Skipped {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (s *Stub) Unskip(version int) error {
+	delete(s.Skipped, version)
+	return nil
+}
+
+func (s *Stub) PhaseVersions(phase string) ([]int, error) {
+	versions := make([]int, 0, len(s.Phases[phase]))
+	for v := range s.Phases[phase] {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (s *Stub) SetPhaseApplied(phase string, version int, applied bool) error {
+	if !applied {
+		delete(s.Phases[phase], version)
+		return nil
+	}
+	if s.Phases == nil {
+		s.Phases = make(map[string]map[int]bool)
+	}
+	if s.Phases[phase] == nil {
+		s.Phases[phase] = make(map[int]bool)
+	}
+	s.Phases[phase][version] = true
+	return nil
+}
+
+func (s *Stub) AppliedVersions() ([]int, error) {
+	versions := make([]int, 0, len(s.Applied))
+	for v := range s.Applied {
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (s *Stub) SetApplied(version int, applied bool) error {
+	if s.Applied == nil {
+		s.Applied = make(map[int]bool)
+	}
+	if applied {
+		s.Applied[version] = true
+	} else {
+		delete(s.Applied, version)
+	}
+	return nil
+}
+
+func (s *Stub) DumpSchema(w io.Writer) error {
+	_, err := io.WriteString(w, s.Schema)
+	return err
+}
+
 const DROP = "DROP"
 
 func (s *Stub) Drop() error {
@@ -4,6 +4,9 @@
 package testing
 
 import (
+	"errors"
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +35,69 @@ func TestMigrateUp(t *testing.T, m *migrate.Migrate) {
 		t.Fatal(err)
 	}
 }
+
+// TestMigrationsRoundTrip opens the migrations of sourceURL against the
+// database at databaseURL and runs TestMigrateRoundTrip. The database should
+// be empty, e.g. a sqlite3 or ql database in a temporary directory.
+func TestMigrationsRoundTrip(t *testing.T, sourceURL string, databaseURL string) {
+	m, err := migrate.New(sourceURL, databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		srcErr, dbErr := m.Close()
+		if srcErr != nil {
+			t.Error(srcErr)
+		}
+		if dbErr != nil {
+			t.Error(dbErr)
+		}
+	}()
+	TestMigrateRoundTrip(t, m)
+}
+
+// TestMigrateRoundTrip checks that the down migrations of m revert their up
+// migrations. Starting at the current version, it applies every pending up
+// migration, applies its down migration and asserts that the schema matches
+// the schema before the up migration, then applies the up migration again and
+// asserts that the schema matches the schema after the first up migration.
+// The database driver must implement database.SchemaDumper.
+func TestMigrateRoundTrip(t *testing.T, m *migrate.Migrate) {
+	before := dumpSchema(t, m)
+	for {
+		if err := m.Steps(1); errors.Is(err, os.ErrNotExist) {
+			return
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		version, _, err := m.Version()
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := dumpSchema(t, m)
+
+		t.Logf("ROUND TRIP %v", version)
+		if err := m.Steps(-1); err != nil {
+			t.Fatalf("version %v: down: %v", version, err)
+		}
+		if schema := dumpSchema(t, m); schema != before {
+			t.Fatalf("version %v: the down migration doesn't revert the up migration\n--- schema before up:\n%s\n--- schema after down:\n%s", version, before, schema)
+		}
+		if err := m.Steps(1); err != nil {
+			t.Fatalf("version %v: up again: %v", version, err)
+		}
+		if schema := dumpSchema(t, m); schema != after {
+			t.Fatalf("version %v: the up migration changes the schema differently when applied again\n--- schema after first up:\n%s\n--- schema after second up:\n%s", version, after, schema)
+		}
+		before = after
+	}
+}
+
+func dumpSchema(t *testing.T, m *migrate.Migrate) string {
+	t.Helper()
+	var schema strings.Builder
+	if err := m.DumpSchema(&schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema.String()
+}
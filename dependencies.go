@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"errors"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/graph"
+)
+
+// WithDependencyGraph orders the migrations of m by the versions they depend
+// on, declared with a "-- migrate:depends_on=V1,V2" directive, see source/graph.
+// It returns an error if migrations depend on each other in a cycle or on a
+// missing migration. Namespaces are not supported.
+//
+// If the database driver implements database.AppliedTracker, Up applies every
+// migration that is not applied yet once its dependencies are, even if a
+// migration following it was applied first, e.g. after merging a branch.
+// Otherwise Up applies the migrations following the current version in
+// dependency order.
+func (m *Migrate) WithDependencyGraph() error {
+	if len(m.Namespaces()) > 0 {
+		return ErrGraphNamespaces
+	}
+	src, err := graph.WithInstance(m.sourceDrv)
+	if err != nil {
+		return err
+	}
+	m.sourceDrv = src
+	return nil
+}
+
+// before reports whether version a comes before version b in the order of
+// the source, see source.Orderer. NilVersion comes before all versions.
+func (m *Migrate) before(a int, b int) bool {
+	if o, ok := m.sourceDrv.(source.Orderer); ok && a >= 0 && b >= 0 {
+		posA, okA := o.Position(uint(a))
+		posB, okB := o.Position(uint(b))
+		if okA && okB {
+			return posA < posB
+		}
+	}
+	return a < b
+}
+
+// appliedTracker returns the database driver as database.AppliedTracker if
+// the migrations are ordered by a dependency graph.
+func (m *Migrate) appliedTracker() (database.AppliedTracker, bool) {
+	if _, ok := m.sourceDrv.(source.Orderer); !ok {
+		return nil, false
+	}
+	tracker, ok := m.databaseDrv.(database.AppliedTracker)
+	return tracker, ok
+}
+
+// upGraph applies all migrations that are not recorded as applied, in the
+// order of the source. Afterwards the version is the last applied migration
+// in that order.
+func (m *Migrate) upGraph(tracker database.AppliedTracker, curVersion int) error {
+	applied, err := m.appliedVersions(tracker, curVersion)
+	if err != nil {
+		return err
+	}
+
+	var pending []uint
+	version, err := m.sourceDrv.First()
+	for ; err == nil; version, err = m.sourceDrv.Next(version) {
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(pending) == 0 {
+		return ErrNoChange
+	}
+
+	ret := make(chan interface{}, m.PrefetchMigrations)
+	go func() {
+		defer close(ret)
+		for _, v := range pending {
+			if m.stop() {
+				return
+			}
+			migr, err := m.newMigration(v, int(v))
+			if err != nil {
+				ret <- err
+				return
+			}
+			ret <- migr
+			go func() {
+				if err := migr.Buffer(); err != nil {
+					m.logErr(err)
+				}
+			}()
+		}
+	}()
+	if err := m.runMigrations(ret); err != nil {
+		return err
+	}
+
+	// a migration applied out of order may have moved the version back
+	if applied, err = m.appliedVersions(tracker, database.NilVersion); err != nil {
+		return err
+	}
+	last := database.NilVersion
+	for version, err = m.sourceDrv.First(); err == nil; version, err = m.sourceDrv.Next(version) {
+		if applied[version] {
+			last = int(version)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return m.setVersion(last, false)
+}
+
+// appliedVersions returns the versions recorded as applied by tracker. If none
+// are recorded yet, e.g. before the first Up with a dependency graph, the
+// migrations up to curVersion are recorded as applied.
+func (m *Migrate) appliedVersions(tracker database.AppliedTracker, curVersion int) (map[uint]bool, error) {
+	versions, err := tracker.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[uint]bool, len(versions))
+	for _, v := range versions {
+		applied[uint(v)] = true
+	}
+	if len(applied) > 0 || curVersion == database.NilVersion {
+		return applied, nil
+	}
+
+	if err := m.versionExists(uint(curVersion)); err != nil {
+		return nil, err
+	}
+	version, err := m.sourceDrv.First()
+	for ; err == nil; version, err = m.sourceDrv.Next(version) {
+		if err := tracker.SetApplied(int(version), true); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+		if int(version) == curVersion {
+			return applied, nil
+		}
+	}
+	return nil, err
+}
+
+// recordApplied records the up migration migr as applied, or the down
+// migration migr as no longer applied, if the migrations are ordered by a
+// dependency graph.
+func (m *Migrate) recordApplied(migr *Migration) error {
+	tracker, ok := m.appliedTracker()
+	if !ok {
+		return nil
+	}
+	return tracker.SetApplied(int(migr.Version), !migr.isDown())
+}
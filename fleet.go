@@ -0,0 +1,313 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nurl "net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// DefaultFleetConcurrency is the number of targets a Fleet migrates
+// at the same time, unless Fleet.Concurrency is set.
+var DefaultFleetConcurrency = 4
+
+// ErrFleetAborted is the error of the targets a Fleet did not migrate,
+// because an earlier target failed and Fleet.ContinueOnError is not set.
+var ErrFleetAborted = errors.New("aborted after an earlier target failed")
+
+// Placeholders in the database URL of TenantTargets.
+const (
+	TenantPlaceholder = "${tenant}"
+	IndexPlaceholder  = "${index}"
+)
+
+// FleetTarget is a database migrated by a Fleet.
+type FleetTarget struct {
+	// Name identifies the target in logs and results, e.g. the tenant.
+	Name string
+
+	// DatabaseURL is the database URL of the target.
+	DatabaseURL string
+
+	// Index is the Elasticsearch index of the target, e.g. the tenant.
+	// It is empty for targets that don't have an index of their own.
+	Index string
+}
+
+// FleetResult is the outcome of migrating a single target of a Fleet.
+type FleetResult struct {
+	Target FleetTarget
+
+	// Version and Dirty hold the version of the target after it was migrated.
+	Version uint
+	Dirty   bool
+
+	Duration time.Duration
+
+	// Err is nil if the target was migrated or had no change.
+	Err error
+}
+
+// ErrFleet is returned by Fleet if any of its targets failed.
+type ErrFleet struct {
+	Failed int
+	Total  int
+}
+
+// Error implements the error interface.
+func (e ErrFleet) Error() string {
+	return fmt.Sprintf("%v of %v targets failed", e.Failed, e.Total)
+}
+
+// Fleet runs the same migrations against many databases, e.g. one database
+// or schema per tenant.
+type Fleet struct {
+	// SourceURL is the source of the migrations for all targets.
+	SourceURL string
+
+	Targets []FleetTarget
+
+	// Concurrency limits the number of targets migrated at the same time.
+	// It defaults to DefaultFleetConcurrency.
+	Concurrency int
+
+	// ContinueOnError keeps migrating the remaining targets after a target
+	// failed. Otherwise targets that were not started yet are aborted.
+	ContinueOnError bool
+
+	// Log accepts a Logger interface. Log lines are prefixed with the
+	// name of the target.
+	Log Logger
+
+	// Configure is called with the Migrate instance of every target
+	// before it is migrated, e.g. to set WithTags or LockTimeout.
+	Configure func(target FleetTarget, m *Migrate)
+}
+
+// NewFleet returns a Fleet running the migrations of sourceURL against
+// databaseURLs. Targets are named by their URL without password.
+func NewFleet(sourceURL string, databaseURLs ...string) *Fleet {
+	return &Fleet{SourceURL: sourceURL, Targets: URLTargets(databaseURLs...)}
+}
+
+// URLTargets returns a target per database URL, named by the URL without password.
+func URLTargets(databaseURLs ...string) []FleetTarget {
+	targets := make([]FleetTarget, 0, len(databaseURLs))
+	for _, u := range databaseURLs {
+		targets = append(targets, FleetTarget{Name: redactURL(u), DatabaseURL: u})
+	}
+	return targets
+}
+
+// TenantTargets returns a target per tenant, named by the tenant. The tenant
+// is substituted for TenantPlaceholder and IndexPlaceholder in databaseURL,
+// e.g. postgres://localhost:5432/app?search_path=${tenant}, and is the index
+// of the target.
+func TenantTargets(databaseURL string, tenants []string) []FleetTarget {
+	targets := make([]FleetTarget, 0, len(tenants))
+	for _, tenant := range tenants {
+		u := strings.ReplaceAll(databaseURL, TenantPlaceholder, tenant)
+		u = strings.ReplaceAll(u, IndexPlaceholder, tenant)
+		targets = append(targets, FleetTarget{Name: tenant, DatabaseURL: u, Index: tenant})
+	}
+	return targets
+}
+
+// ListTenants runs query against databaseURL and returns the first column of
+// all rows, e.g. the names of the tenant schemas:
+//  SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'tenant_%'
+// The database driver must implement database.TxBeginner.
+func ListTenants(databaseURL string, query string) (tenants []string, err error) {
+	d, err := database.Open(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := d.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	b, ok := d.(database.TxBeginner)
+	if !ok {
+		return nil, fmt.Errorf("database driver %v can't list tenants", redactURL(databaseURL))
+	}
+	tx, err := b.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errRollback := tx.Rollback(); err == nil {
+			err = errRollback
+		}
+	}()
+
+	rows, err := tx.Query(query)
+	if err != nil {
+		return nil, &database.Error{OrigErr: err, Query: []byte(query)}
+	}
+	defer func() {
+		if errClose := rows.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	for rows.Next() {
+		var tenant string
+		if err := rows.Scan(&tenant); err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, rows.Err()
+}
+
+// Up migrates all targets all the way up, see Migrate.Up.
+func (f *Fleet) Up() ([]FleetResult, error) {
+	return f.Run(func(target FleetTarget, result *FleetResult) error {
+		m, err := New(f.SourceURL, target.DatabaseURL)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if _, errClose := m.Close(); errClose != nil {
+				f.logError(target.Name, errClose)
+			}
+		}()
+
+		if f.Log != nil {
+			m.Log = &fleetLogger{name: target.Name, Logger: f.Log}
+		}
+		if f.Configure != nil {
+			f.Configure(target, m)
+		}
+
+		err = m.Up()
+		if version, dirty, errVersion := m.Version(); errVersion == nil {
+			result.Version, result.Dirty = version, dirty
+		}
+		return err
+	})
+}
+
+// Run calls fn for every target with bounded concurrency, see Concurrency.
+// fn may set the Version and Dirty fields of result. The results are returned
+// in the order of Targets, ErrNoChange is not treated as an error.
+// If any target failed, ErrFleet is returned along with the results.
+func (f *Fleet) Run(fn func(target FleetTarget, result *FleetResult) error) ([]FleetResult, error) {
+	concurrency := f.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultFleetConcurrency
+	}
+
+	var (
+		results = make([]FleetResult, len(f.Targets))
+		sem     = make(chan struct{}, concurrency)
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  bool
+	)
+	for i, target := range f.Targets {
+		results[i].Target = target
+
+		sem <- struct{}{}
+		mu.Lock()
+		abort := failed && !f.ContinueOnError
+		mu.Unlock()
+		if abort {
+			<-sem
+			results[i].Err = ErrFleetAborted
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *FleetResult) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			startTime := time.Now()
+			err := fn(result.Target, result)
+			result.Duration = time.Since(startTime)
+
+			if err != nil && !errors.Is(err, ErrNoChange) {
+				result.Err = err
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+				f.logError(result.Target.Name, err)
+				return
+			}
+			f.logInfo(result.Target.Name, fmt.Sprintf("finished (%v)", result.Duration), "duration", result.Duration)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	failures := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failures++
+		}
+	}
+	if failures > 0 {
+		return results, ErrFleet{Failed: failures, Total: len(results)}
+	}
+	return results, nil
+}
+
+func (f *Fleet) logInfo(name string, msg string, keysAndValues ...interface{}) {
+	if f.Log != nil {
+		(&fleetLogger{name: name, Logger: f.Log}).Info(msg, keysAndValues...)
+	}
+}
+
+func (f *Fleet) logError(name string, err error) {
+	if f.Log != nil {
+		(&fleetLogger{name: name, Logger: f.Log}).Error(err.Error())
+	}
+}
+
+// fleetLogger prefixes log lines with the name of a fleet target.
+type fleetLogger struct {
+	name string
+	Logger
+}
+
+func (l *fleetLogger) Printf(format string, v ...interface{}) {
+	l.Logger.Printf("%v: "+format, append([]interface{}{l.name}, v...)...)
+}
+
+func (l *fleetLogger) Debug(msg string, keysAndValues ...interface{}) {
+	structured(l.Logger).Debug(l.name+": "+msg, l.fields(keysAndValues)...)
+}
+
+func (l *fleetLogger) Info(msg string, keysAndValues ...interface{}) {
+	structured(l.Logger).Info(l.name+": "+msg, l.fields(keysAndValues)...)
+}
+
+func (l *fleetLogger) Warn(msg string, keysAndValues ...interface{}) {
+	structured(l.Logger).Warn(l.name+": "+msg, l.fields(keysAndValues)...)
+}
+
+func (l *fleetLogger) Error(msg string, keysAndValues ...interface{}) {
+	structured(l.Logger).Error(l.name+": "+msg, l.fields(keysAndValues)...)
+}
+
+// fields adds the name of the target to the key-value pairs keysAndValues.
+func (l *fleetLogger) fields(keysAndValues []interface{}) []interface{} {
+	return append([]interface{}{"target", l.name}, keysAndValues...)
+}
+
+// redactURL returns u without password, or u unchanged if it can't be parsed.
+func redactURL(u string) string {
+	parsed, err := nurl.Parse(u)
+	if err != nil {
+		return u
+	}
+	return parsed.Redacted()
+}
@@ -44,7 +44,7 @@ require (
 	github.com/neo4j/neo4j-go-driver v1.8.1-0.20200803113522-b626aa943eba
 	github.com/pierrec/lz4/v4 v4.1.7 // indirect
 	github.com/pkg/browser v0.0.0-20210115035449-ce105d075bb4 // indirect
-	github.com/sirupsen/logrus v1.8.1 // indirect
+	github.com/sirupsen/logrus v1.8.1
 	github.com/snowflakedb/gosnowflake v1.4.3
 	github.com/spf13/cast v1.5.0
 	github.com/stretchr/testify v1.6.1
@@ -55,6 +55,7 @@ require (
 	golang.org/x/tools v0.1.12
 	google.golang.org/api v0.45.0
 	google.golang.org/genproto v0.0.0-20210427215850-f767ed18ee4d
+	gopkg.in/yaml.v3 v3.0.1
 	modernc.org/b v1.0.0 // indirect
 	modernc.org/db v1.0.0 // indirect
 	modernc.org/file v1.0.0 // indirect
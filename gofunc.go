@@ -0,0 +1,78 @@
+package migrate
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/gofunc"
+)
+
+var (
+	goMigrationsMu sync.Mutex
+	goMigrations   []*gofunc.Migration
+)
+
+// RegisterGoMigration globally registers a migration written in Go for
+// version. up or down may be nil. Every Migrate instance created afterwards
+// merges the registered Go migrations with the migrations of its source, see
+// source/gofunc. It is usually called in the init function of the package
+// holding the migration.
+//
+// Use gofunc.Tx to run a function taking a *sql.Tx.
+func RegisterGoMigration(version uint, up, down gofunc.Func) {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+	goMigrations = append(goMigrations, &gofunc.Migration{
+		Version:    version,
+		Identifier: funcIdentifier(up, down),
+		Up:         up,
+		Down:       down,
+	})
+}
+
+// funcIdentifier returns the name of up, or of down if up is nil,
+// without its package path.
+func funcIdentifier(up, down gofunc.Func) string {
+	fn := up
+	if fn == nil {
+		fn = down
+	}
+	if fn == nil {
+		return "go"
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// withGoMigrations merges the registered Go migrations with sourceDrv.
+// sourceDrv is returned unchanged if no Go migrations are registered.
+func withGoMigrations(sourceDrv source.Driver) (source.Driver, error) {
+	goMigrationsMu.Lock()
+	defer goMigrationsMu.Unlock()
+	if len(goMigrations) == 0 {
+		return sourceDrv, nil
+	}
+	return gofunc.WithInstance(sourceDrv, goMigrations...)
+}
+
+// readFunc returns the Go migration of version in direction, if the source
+// driver provides Go migrations, see gofunc.FuncReader.
+func (m *Migrate) readFunc(version uint, direction source.Direction) (gofunc.Func, string, error) {
+	fr, ok := m.sourceDrv.(gofunc.FuncReader)
+	if !ok {
+		return nil, "", nil
+	}
+	return fr.ReadFunc(version, direction)
+}
+
+// runFunc runs a migration written in Go against the database.
+func (m *Migrate) runFunc(fn gofunc.Func) error {
+	return fn(context.Background(), m.databaseDrv)
+}
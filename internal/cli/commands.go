@@ -4,21 +4,24 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/elasticsearch"
 	_ "github.com/golang-migrate/migrate/v4/database/elasticsearch"
 	httpModels "github.com/golang-migrate/migrate/v4/database/http"
 	_ "github.com/golang-migrate/migrate/v4/database/stub" // TODO remove again
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
@@ -26,6 +29,10 @@ var (
 	errInvalidSequenceWidth     = errors.New("Digits must be positive")
 	errIncompatibleSeqAndFormat = errors.New("The seq and format options are mutually exclusive")
 	errInvalidTimeFormat        = errors.New("Time format may not be empty")
+	errNoFleetTargets           = errors.New("fleet requires -tenants, -tenants-query or -targets-file")
+	errNoTenantsDatabase        = errors.New("-tenants-query requires -tenants-database")
+	errFleetCommand             = errors.New("fleet only supports the up and elastic-up commands")
+	errFleetMigrater            = errors.New("fleet, apply, diff and lint don't use a single migrate instance")
 )
 
 func nextSeqVersion(matches []string, seqDigits int) (string, error) {
@@ -80,7 +87,13 @@ func timeVersion(startTime time.Time, format string) (version string, err error)
 }
 
 // createCmd (meant to be called via a CLI command) creates a new migration
-func createCmd(dir string, startTime time.Time, format string, name string, ext string, seq bool, seqDigits int, print bool) error {
+func createCmd(dir string, startTime time.Time, format string, name string, ext string, seq bool, seqDigits int, singleFile bool, print bool) error {
+	return createMigration(dir, startTime, format, name, ext, seq, seqDigits, singleFile, print, nil, nil)
+}
+
+// createMigration creates a new migration like createCmd, with the statements up
+// and down. The files are empty, or hold the single file template, if both are nil.
+func createMigration(dir string, startTime time.Time, format string, name string, ext string, seq bool, seqDigits int, singleFile bool, print bool, up []byte, down []byte) error {
 	if seq && format != defaultTimeFormat {
 		return errIncompatibleSeqAndFormat
 	}
@@ -126,11 +139,32 @@ func createCmd(dir string, startTime time.Time, format string, name string, ext
 		return err
 	}
 
+	if singleFile {
+		filename := filepath.Join(dir, fmt.Sprintf("%s_%s%s", version, name, ext))
+
+		content := singleFileTemplate(ext)
+		if up != nil || down != nil {
+			content = []byte(fmt.Sprintf("-- +migrate Up\n%s\n-- +migrate Down\n%s", up, down))
+		}
+
+		if err = createFile(filename, content); err != nil {
+			return err
+		}
+
+		if print {
+			absPath, _ := filepath.Abs(filename)
+			log.Println(absPath)
+		}
+
+		return nil
+	}
+
+	contents := map[string][]byte{"up": up, "down": down}
 	for _, direction := range []string{"up", "down"} {
 		basename := fmt.Sprintf("%s_%s.%s%s", version, name, direction, ext)
 		filename := filepath.Join(dir, basename)
 
-		if err = createFile(filename); err != nil {
+		if err = createFile(filename, contents[direction]); err != nil {
 			return err
 		}
 
@@ -143,7 +177,7 @@ func createCmd(dir string, startTime time.Time, format string, name string, ext
 	return nil
 }
 
-func createFile(filename string) error {
+func createFile(filename string, content []byte) error {
 	// create exclusive (fails if file already exists)
 	// os.Create() specifies 0666 as the FileMode, so we're doing the same
 	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
@@ -152,15 +186,29 @@ func createFile(filename string) error {
 		return err
 	}
 
+	if _, err = f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+
 	return f.Close()
 }
 
+// singleFileTemplate returns the initial content of a single file migration
+// with extension ext, see source.SplitSections.
+func singleFileTemplate(ext string) []byte {
+	if ext == ".json" {
+		return []byte("{\n  \"up\": [],\n  \"down\": []\n}\n")
+	}
+	return []byte("-- +migrate Up\n\n-- +migrate Down\n")
+}
+
 func gotoCmd(m *migrate.Migrate, v uint) error {
 	if err := m.Migrate(v); err != nil {
 		if err != migrate.ErrNoChange {
 			return err
 		}
-		log.Println(err)
+		log.Info(err.Error())
 	}
 	return nil
 }
@@ -171,33 +219,43 @@ func upCmd(m *migrate.Migrate, limit int) error {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	} else {
 		if err := m.Up(); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	}
 	return nil
 }
 
+func upPhaseCmd(m *migrate.Migrate, phase string) error {
+	if err := m.UpPhase(phase); err != nil {
+		if err != migrate.ErrNoChange {
+			return err
+		}
+		log.Info(err.Error())
+	}
+	return nil
+}
+
 func seedUpCmd(m *migrate.Migrate, limit int) error {
 	if limit >= 0 {
 		if err := m.Steps(limit); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	} else {
 		if err := m.SeedUp(); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	}
 	return nil
@@ -209,14 +267,14 @@ func seedDownCmd(m *migrate.Migrate, limit int) error {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	} else {
 		if err := m.SeedDown(); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	}
 	return nil
@@ -243,10 +301,12 @@ func seedUpInfluxCmd(database string, path string, token string) error {
 			for index, data := range splitStr {
 				data = strings.TrimSuffix(data, ",")
 				curl := fmt.Sprintf(`curl -i -XPOST "%s" --header 'Authorization: Token %s' --data-raw "%s"`, database, path, data)
-				resp, err := resty.New().SetDebug(false).R().
+				req := newRestyClient().SetDebug(false).R().
 					SetHeader("Authorization", fmt.Sprintf("Token %s", token)).
-					SetBody(data).
-					Post(database)
+					SetBody(data)
+				req.Method = http.MethodPost
+				req.URL = database
+				resp, err := sendRequest("influx", req)
 				if err != nil {
 					return fmt.Errorf(`
 						Error of file: %s
@@ -259,7 +319,7 @@ func seedUpInfluxCmd(database string, path string, token string) error {
 					)
 				}
 				if resp.StatusCode() == http.StatusNoContent || resp.StatusCode() == http.StatusOK {
-					fmt.Println("sending data success: " + data)
+					log.Println("sending data success: " + data)
 					continue
 				}
 				log.Println(fmt.Sprintf("Error Line : %d", index))
@@ -273,41 +333,53 @@ func seedUpInfluxCmd(database string, path string, token string) error {
 	return nil
 }
 
-func seedUpElasticCmd(database string, path string, excludeHeader string, index string, skippError bool, debug bool) error {
+func seedUpElasticCmd(database string, path string, excludeHeader string, index string, skippError bool, debug bool, tags []string, excludeTags []string) error {
+	_, err := seedUpElastic(database, path, excludeHeader, index, skippError, debug, tags, excludeTags)
+	return err
+}
+
+// seedUpElastic runs the up files in path like elastic-up and returns the
+// names of the files it ran.
+func seedUpElastic(database string, path string, excludeHeader string, index string, skippError bool, debug bool, tags []string, excludeTags []string) (applied []string, err error) {
 	filesInfo, err := ioutil.ReadDir(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(filesInfo) == 0 {
-		return migrate.ErrNoChange
+		return nil, migrate.ErrNoChange
 	}
 
 	for _, fInfo := range filesInfo {
 		if !fInfo.IsDir() && strings.Contains(fInfo.Name(), ".up.json") {
+			if !fileMatchesTags(fInfo.Name(), tags, excludeTags) {
+				fmt.Println("skip file: " + fInfo.Name())
+				continue
+			}
+
 			filepath := path + "/" + fInfo.Name()
 
 			bu, err := ioutil.ReadFile(filepath)
 			if err != nil {
-				return err
+				return applied, err
 			}
 
 			restyConfig := make([]*elasticsearch.RestConfig, 0)
 			elastics := make([]*elasticsearch.Elasticsearch, 0)
 			if err := json.Unmarshal(bu, &restyConfig); err != nil {
-				return err
+				return applied, err
 			}
 
 			for configIndex, _ := range restyConfig {
 				if restyConfig[configIndex].IsZero() {
-					return errors.New("method and path must be required")
+					return applied, errors.New("method and path must be required")
 				}
 
 				restyConfig[configIndex].MigrationPath = path
 				if err := restyConfig[configIndex].ReplaceStringWithIndex(index); err != nil {
-					return err
+					return applied, err
 				}
 				if err := restyConfig[configIndex].ExcludeHeader(excludeHeader); err != nil {
-					return err
+					return applied, err
 				}
 				elastics = append(elastics, &elasticsearch.Elasticsearch{
 					Index:      index,
@@ -322,56 +394,68 @@ func seedUpElasticCmd(database string, path string, excludeHeader string, index
 				} else {
 					url = fmt.Sprintf("%s/%s", strings.Trim(database, "/"), strings.Trim(elastic.RestConfig.Path, "/"))
 				}
-				req := resty.New().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
+				req := newRestyClient().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
 				req.URL = url
 				req.Method = elastic.RestConfig.Method
 				req.Header = elastic.RestConfig.ToHTTPHeader()
 				req.Body = elastic.RestConfig.Body
 
-				resp, err := req.Send()
+				resp, err := sendRequest("elastic", req)
 				if err != nil {
-					return err
+					return applied, err
 				}
 
 				if resp.StatusCode() >= 400 {
-					msg := errors.New(string(resp.Body()))
 					if skippError {
-						fmt.Println(msg.Error())
+						log.Error(string(resp.Body()))
 					} else {
-						return msg
+						return applied, errors.New(string(resp.Body()))
 					}
 				}
 			}
 
 			fmt.Println("migrate file: " + fInfo.Name() + " success")
+			applied = append(applied, fInfo.Name())
 		}
 	}
 
-	return nil
+	return applied, nil
+}
+
+func seedUpHttpCmd(database string, path string, excludeHeader string, skippError bool, debug bool, tags []string, excludeTags []string) error {
+	_, err := seedUpHttp(database, path, excludeHeader, skippError, debug, tags, excludeTags)
+	return err
 }
 
-func seedUpHttpCmd(database string, path string, excludeHeader string, skippError bool, debug bool) error {
+// seedUpHttp runs the up files in path like http-up and returns the names
+// of the files it ran.
+func seedUpHttp(database string, path string, excludeHeader string, skippError bool, debug bool, tags []string, excludeTags []string) (applied []string, err error) {
 	filesInfo, err := ioutil.ReadDir(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(filesInfo) == 0 {
-		return migrate.ErrNoChange
+		return nil, migrate.ErrNoChange
 	}
 
 	for _, fInfo := range filesInfo {
 		if !fInfo.IsDir() && strings.Contains(fInfo.Name(), ".up.json") {
+			if !fileMatchesTags(fInfo.Name(), tags, excludeTags) {
+				fmt.Println("skip file: " + fInfo.Name())
+				continue
+			}
+
 			filepath := path + "/" + fInfo.Name()
 
 			bu, err := ioutil.ReadFile(filepath)
 			if err != nil {
-				return err
+				return applied, err
 			}
 
 			restyConfig := make([]*httpModels.RestConfig, 0)
 			params := make([]map[string]interface{}, 0)
 			if err := json.Unmarshal(bu, &params); err != nil {
-				return err
+				return applied, err
 			}
 			for _, param := range params {
 				r := httpModels.NewRestConfig(param)
@@ -380,7 +464,7 @@ func seedUpHttpCmd(database string, path string, excludeHeader string, skippErro
 
 			for _, rest := range restyConfig {
 				if err := rest.ExcludeHeader(excludeHeader); err != nil {
-					return err
+					return applied, err
 				}
 				var url string
 				if rest.URL != "" {
@@ -388,7 +472,7 @@ func seedUpHttpCmd(database string, path string, excludeHeader string, skippErro
 				} else {
 					url = fmt.Sprintf("%s/%s", strings.Trim(database, "/"), strings.Trim(rest.Path, "/"))
 				}
-				req := resty.New().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
+				req := newRestyClient().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
 				req.URL = url
 				req.QueryParam = rest.QueryParams
 				req.Method = rest.Method
@@ -399,35 +483,41 @@ func seedUpHttpCmd(database string, path string, excludeHeader string, skippErro
 					ymlPath := path + "/" + rest.FilePath
 					bu, err := ioutil.ReadFile(ymlPath)
 					if err != nil {
-						return err
+						return applied, err
 					}
 
 					req.SetBody(bytes.NewReader(bu))
 				}
 
-				resp, err := req.Send()
+				resp, err := sendRequest("http", req)
 				if err != nil {
-					return err
+					return applied, err
 				}
 
 				if resp.StatusCode() >= 400 {
-					msg := errors.New(string(resp.Body()))
 					if skippError {
-						fmt.Println(msg.Error())
+						log.Error(string(resp.Body()))
 					} else {
-						return msg
+						return applied, errors.New(string(resp.Body()))
 					}
 				}
 			}
 
 			fmt.Println("migrate file: " + fInfo.Name() + " success")
+			applied = append(applied, fInfo.Name())
 		}
 	}
 
-	return nil
+	return applied, nil
 }
 
 func seedDownHttpCmd(database string, path string, excludeHeader string, skippError bool, debug bool) error {
+	return seedDownHttp(database, path, excludeHeader, skippError, debug, nil)
+}
+
+// seedDownHttp runs the down files in path like http-down. If only is not
+// nil, only the down files of the up files named in only are run.
+func seedDownHttp(database string, path string, excludeHeader string, skippError bool, debug bool, only []string) error {
 	filesInfo, err := ioutil.ReadDir(path)
 	if err != nil {
 		return err
@@ -439,6 +529,9 @@ func seedDownHttpCmd(database string, path string, excludeHeader string, skippEr
 	for i := len(filesInfo) - 1; i >= 0; i-- {
 		fInfo := filesInfo[i]
 		if !fInfo.IsDir() && strings.Contains(fInfo.Name(), ".down.json") {
+			if only != nil && !containsUpFile(only, fInfo.Name()) {
+				continue
+			}
 			filepath := path + "/" + fInfo.Name()
 
 			bu, err := ioutil.ReadFile(filepath)
@@ -466,24 +559,23 @@ func seedDownHttpCmd(database string, path string, excludeHeader string, skippEr
 				} else {
 					url = fmt.Sprintf("%s/%s", strings.Trim(database, "/"), strings.Trim(rest.Path, "/"))
 				}
-				req := resty.New().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
+				req := newRestyClient().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
 				req.URL = url
 				req.QueryParam = rest.QueryParams
 				req.Method = rest.Method
 				req.Header = http.Header(rest.Header)
 				req.Body = rest.Body
 
-				resp, err := req.Send()
+				resp, err := sendRequest("http", req)
 				if err != nil {
 					return err
 				}
 
 				if resp.StatusCode() >= 400 {
-					msg := errors.New(string(resp.Body()))
 					if skippError {
-						fmt.Println(msg.Error())
+						log.Error(string(resp.Body()))
 					} else {
-						return msg
+						return errors.New(string(resp.Body()))
 					}
 				}
 			}
@@ -496,6 +588,12 @@ func seedDownHttpCmd(database string, path string, excludeHeader string, skippEr
 }
 
 func seedDownElasticCmd(database string, path string, excludeHeader string, index string, skippError bool, debug bool) error {
+	return seedDownElastic(database, path, excludeHeader, index, skippError, debug, nil)
+}
+
+// seedDownElastic runs the down files in path like elastic-down. If only is
+// not nil, only the down files of the up files named in only are run.
+func seedDownElastic(database string, path string, excludeHeader string, index string, skippError bool, debug bool, only []string) error {
 	filesInfo, err := ioutil.ReadDir(path)
 	if err != nil {
 		return err
@@ -507,6 +605,9 @@ func seedDownElasticCmd(database string, path string, excludeHeader string, inde
 	for i := len(filesInfo) - 1; i >= 0; i-- {
 		fInfo := filesInfo[i]
 		if !fInfo.IsDir() && strings.Contains(fInfo.Name(), ".down.json") {
+			if only != nil && !containsUpFile(only, fInfo.Name()) {
+				continue
+			}
 			filepath := path + "/" + fInfo.Name()
 
 			bu, err := ioutil.ReadFile(filepath)
@@ -545,23 +646,22 @@ func seedDownElasticCmd(database string, path string, excludeHeader string, inde
 				} else {
 					url = fmt.Sprintf("%s/%s", strings.Trim(database, "/"), strings.Trim(elastic.RestConfig.Path, "/"))
 				}
-				req := resty.New().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
+				req := newRestyClient().SetContentLength(true).SetDebug(debug).SetAllowGetMethodPayload(true).R()
 				req.URL = url
 				req.Method = elastic.RestConfig.Method
 				req.Header = elastic.RestConfig.ToHTTPHeader()
 				req.Body = elastic.RestConfig.Body
 
-				resp, err := req.Send()
+				resp, err := sendRequest("elastic", req)
 				if err != nil {
 					return err
 				}
 
 				if resp.StatusCode() >= 400 {
-					msg := errors.New(string(resp.Body()))
 					if skippError {
-						fmt.Println(msg.Error())
+						log.Error(string(resp.Body()))
 					} else {
-						return msg
+						return errors.New(string(resp.Body()))
 					}
 				}
 			}
@@ -573,25 +673,175 @@ func seedDownElasticCmd(database string, path string, excludeHeader string, inde
 	return nil
 }
 
+// containsUpFile reports whether the up file of the down file downName is
+// named in upNames.
+func containsUpFile(upNames []string, downName string) bool {
+	upName := strings.Replace(downName, ".down.json", ".up.json", 1)
+	for _, name := range upNames {
+		if name == upName {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList splits a comma separated list, e.g. of tags.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fileMatchesTags reports whether the migration file name is selected by tags
+// and excludeTags. Files are tagged by a qualifier in their name, see source.Tags.
+func fileMatchesTags(name string, tags []string, excludeTags []string) bool {
+	identifier := strings.TrimSuffix(name, ".up.json")
+	if m, err := source.Parse(name); err == nil {
+		identifier = m.Identifier
+	}
+	return migrate.MatchTags(source.Tags(identifier), tags, excludeTags)
+}
+
+// fleetTargets returns the targets of the fleet command. Targets are read from
+// targetsFile, one database URL per line, or are the tenants substituted into
+// databaseURL. Tenants are listed by tenants or by running tenantsQuery
+// against tenantsDatabase.
+func fleetTargets(databaseURL string, tenants string, tenantsQuery string, tenantsDatabase string, targetsFile string) ([]migrate.FleetTarget, error) {
+	if targetsFile != "" {
+		content, err := ioutil.ReadFile(targetsFile)
+		if err != nil {
+			return nil, err
+		}
+		var urls []string
+		for _, line := range strings.Split(string(content), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				urls = append(urls, line)
+			}
+		}
+		return migrate.URLTargets(urls...), nil
+	}
+
+	names := splitList(tenants)
+	if tenantsQuery != "" {
+		if tenantsDatabase == "" {
+			return nil, errNoTenantsDatabase
+		}
+		listed, err := migrate.ListTenants(tenantsDatabase, tenantsQuery)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, listed...)
+	}
+	if len(names) == 0 {
+		return nil, errNoFleetTargets
+	}
+	return migrate.TenantTargets(databaseURL, names), nil
+}
+
+func fleetCmd(f *migrate.Fleet, command string, elasticUp func(target migrate.FleetTarget) error, w io.Writer) error {
+	var (
+		results []migrate.FleetResult
+		err     error
+	)
+	switch command {
+	case "up":
+		results, err = f.Up()
+	case "elastic-up":
+		results, err = f.Run(func(target migrate.FleetTarget, _ *migrate.FleetResult) error {
+			return elasticUp(target)
+		})
+	default:
+		return errFleetCommand
+	}
+	printFleetResults(w, results)
+	return err
+}
+
+// fleetConfigure returns the Configure function of a Fleet, which sets up the
+// instance of each target like the single instance of the other commands.
+// observer, e.g. the metrics collector, may be nil.
+func fleetConfigure(observer migrate.Observer, prefetch uint, lockTimeout time.Duration, tags, excludeTags []string) func(migrate.FleetTarget, *migrate.Migrate) {
+	return func(_ migrate.FleetTarget, m *migrate.Migrate) {
+		if observer != nil {
+			m.Observer = observer
+		}
+		m.PrefetchMigrations = prefetch
+		m.LockTimeout = lockTimeout
+		m.WithTags(tags, excludeTags)
+	}
+}
+
+// printFleetResults prints a summary table with a row per target.
+func printFleetResults(w io.Writer, results []migrate.FleetResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tSTATUS\tVERSION\tDURATION\tERROR")
+	for _, r := range results {
+		status, errString := "ok", ""
+		switch {
+		case errors.Is(r.Err, migrate.ErrFleetAborted):
+			status = "aborted"
+		case r.Err != nil:
+			status, errString = "failed", r.Err.Error()
+		}
+		version := strconv.FormatUint(uint64(r.Version), 10)
+		if r.Dirty {
+			version += " (dirty)"
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", r.Target.Name, status, version, r.Duration.Round(time.Millisecond), errString)
+	}
+	tw.Flush()
+}
+
 func downCmd(m *migrate.Migrate, limit int) error {
 	if limit >= 0 {
 		if err := m.Steps(-limit); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	} else {
 		if err := m.Down(); err != nil {
 			if err != migrate.ErrNoChange {
 				return err
 			}
-			log.Println(err)
+			log.Info(err.Error())
 		}
 	}
 	return nil
 }
 
+// shadowCmd validates the migrations applied by run on the shadow database at
+// databaseURL, see migrate.ValidateOnShadow. It does nothing if databaseURL is empty.
+func shadowCmd(m *migrate.Migrate, databaseURL string, run func(shadow *migrate.Migrate) error) error {
+	if databaseURL == "" {
+		return nil
+	}
+	log.Info("Applying migrations to shadow database")
+	if err := m.ValidateOnShadow(databaseURL, run); err != nil {
+		return err
+	}
+	log.Info("Migrations applied cleanly to shadow database")
+	return nil
+}
+
+// dumpSchemaCmd writes the schema of the database of m to the file path, or to
+// stdout if path is empty. The file is only written once the whole schema is dumped.
+func dumpSchemaCmd(m *migrate.Migrate, path string) error {
+	if path == "" {
+		return m.DumpSchema(os.Stdout)
+	}
+	var schema bytes.Buffer
+	if err := m.DumpSchema(&schema); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, schema.Bytes(), 0644)
+}
+
 func dropCmd(m *migrate.Migrate) error {
 	if err := m.Drop(); err != nil {
 		return err
@@ -606,19 +856,54 @@ func forceCmd(m *migrate.Migrate, v int) error {
 	return nil
 }
 
+func baselineCmd(m *migrate.Migrate, v uint, description string) error {
+	if err := m.BaselineWithDescription(v, description); err != nil {
+		return err
+	}
+	return nil
+}
+
 func versionCmd(m *migrate.Migrate) error {
 	v, dirty, err := m.Version()
 	if err != nil {
 		return err
 	}
 	if dirty {
-		log.Printf("%v (dirty)\n", v)
+		log.Info(fmt.Sprintf("%v (dirty)", v), "version", v, "dirty", dirty)
 	} else {
-		log.Println(v)
+		log.Info(fmt.Sprint(v), "version", v, "dirty", dirty)
 	}
 	return nil
 }
 
+// baselineVersionFromArgs parses args with flagSet and returns the version
+// argument V. Flags may precede or follow V, as in "baseline V -description D".
+func baselineVersionFromArgs(flagSet *flag.FlagSet, args []string) (uint, error) {
+	if err := flagSet.Parse(args); err != nil {
+		return 0, err
+	}
+	var positional []string
+	for flagSet.NArg() > 0 {
+		positional = append(positional, flagSet.Arg(0))
+		if err := flagSet.Parse(flagSet.Args()[1:]); err != nil {
+			return 0, err
+		}
+	}
+
+	switch len(positional) {
+	case 0:
+		return 0, errors.New("please specify version argument V")
+	case 1:
+		v, err := strconv.ParseUint(positional[0], 10, 64)
+		if err != nil {
+			return 0, errors.New("can't read version argument V")
+		}
+		return uint(v), nil
+	default:
+		return 0, errors.New("too many arguments")
+	}
+}
+
 // numDownMigrationsFromArgs returns an int for number of migrations to apply
 // and a bool indicating if we need a confirm before applying
 func numDownMigrationsFromArgs(applyAll bool, args []string) (int, bool, error) {
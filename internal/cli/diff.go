@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+)
+
+var errDiffNotSupported = errors.New("diff requires databases that can inspect their schema, like postgres and sqlite3")
+
+// diffCmd (meant to be called via a CLI command) compares the schemas of the
+// databases fromURL and toURL and creates a migration changing the schema of
+// the first into the schema of the second, named like migrations of createCmd.
+func diffCmd(fromURL string, toURL string, dir string, startTime time.Time, format string, name string, seq bool, seqDigits int, singleFile bool) error {
+	from, err := inspectSchema(fromURL)
+	if err != nil {
+		return err
+	}
+	to, err := inspectSchema(toURL)
+	if err != nil {
+		return err
+	}
+
+	up, down, err := diffMigration(from, to)
+	if err != nil {
+		return err
+	}
+	if up == nil {
+		log.Println("no schema changes")
+		return nil
+	}
+	return createMigration(dir, startTime, format, name, ".sql", seq, seqDigits, singleFile, true, up, down)
+}
+
+// diffMigration returns the up and down migration changing schema from into
+// schema to, or nil if the schemas are equal.
+func diffMigration(from *schemadiff.Schema, to *schemadiff.Schema) (up []byte, down []byte, err error) {
+	upStatements, err := schemadiff.Diff(from, to)
+	if err != nil || len(upStatements) == 0 {
+		return nil, nil, err
+	}
+	downStatements, err := schemadiff.Diff(to, from)
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(strings.Join(upStatements, "\n\n") + "\n"), []byte(strings.Join(downStatements, "\n\n") + "\n"), nil
+}
+
+func inspectSchema(url string) (s *schemadiff.Schema, err error) {
+	d, err := database.Open(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if errClose := d.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	inspector, ok := d.(database.SchemaInspector)
+	if !ok {
+		return nil, errDiffNotSupported
+	}
+	return inspector.InspectSchema()
+}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+)
+
+func TestDiffMigration(t *testing.T) {
+	from := &schemadiff.Schema{Dialect: schemadiff.Postgres}
+	to := &schemadiff.Schema{
+		Dialect: schemadiff.Postgres,
+		Tables:  []schemadiff.Table{{Name: "users", Columns: []schemadiff.Column{{Name: "id", Type: "integer", NotNull: true}}}},
+	}
+
+	up, down, err := diffMigration(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := createMigration(dir, time.Unix(0, 0), defaultTimeFormat, "users", ".sql", true, 3, false, false, up, down); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, expected := range map[string]string{
+		"001_users.up.sql":   "CREATE TABLE \"users\" (\n    \"id\" integer NOT NULL\n);\n",
+		"001_users.down.sql": "DROP TABLE \"users\";\n",
+	} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expected {
+			t.Errorf("Incorrect %v was: %q wanted %q", file, content, expected)
+		}
+	}
+
+	if up, down, err := diffMigration(to, to); err != nil || up != nil || down != nil {
+		t.Errorf("Incorrect diff of equal schemas was: %q %q (%v)", up, down, err)
+	}
+}
+
+func TestInspectSchemaNotSupported(t *testing.T) {
+	if _, err := inspectSchema("stub://"); err != errDiffNotSupported {
+		t.Errorf("Incorrect error was: %v wanted %v", err, errDiffNotSupported)
+	}
+}
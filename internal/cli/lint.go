@@ -0,0 +1,567 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BlackMocca/mongo-go-driver/bson"
+	"gopkg.in/yaml.v3"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/safety"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// lintSeverity is the severity of the findings of a lint rule.
+type lintSeverity int
+
+const (
+	lintOff lintSeverity = iota
+	lintWarning
+	lintError
+)
+
+func (s lintSeverity) String() string {
+	switch s {
+	case lintWarning:
+		return "warning"
+	case lintError:
+		return "error"
+	}
+	return "off"
+}
+
+func parseLintSeverity(s string) (lintSeverity, error) {
+	switch s {
+	case "off":
+		return lintOff, nil
+	case "warning":
+		return lintWarning, nil
+	case "error":
+		return lintError, nil
+	}
+	return lintOff, fmt.Errorf("unknown lint severity %q, use off, warning or error", s)
+}
+
+// lintRules maps the lint rules to their default severity.
+var lintRules = map[string]lintSeverity{
+	"naming":            lintError,   // file names the parser can't read
+	"duplicate":         lintError,   // several files of the same version and direction
+	"gap":               lintWarning, // missing versions between sequential versions
+	"missing-down":      lintWarning, // up migrations without down migration
+	"directive":         lintError,   // invalid -- migrate: directives
+	"unknown-directive": lintWarning, // -- migrate: comments that aren't known directives
+	"json":              lintError,   // invalid JSON
+	"rest-config":       lintError,   // http and elastic requests without method or path
+	"mongodb":           lintError,   // invalid MongoDB extended JSON
+	"line-protocol":     lintError,   // invalid InfluxDB line protocol
+	"placeholder":       lintError,   // unresolved ${name} placeholders, and {{name}} with template_placeholders
+	"missing-file":      lintError,   // file_path and body_path_file referencing missing files
+
+	// dangerous statements of PostgreSQL migrations, checked with -safety-check
+	safety.NonConcurrentIndex:   lintError,
+	safety.AlterColumnType:      lintError,
+	safety.SetNotNull:           lintError,
+	safety.DropColumn:           lintError,
+	safety.TableRewrite:         lintError,
+	safety.ConstraintValidation: lintError,
+}
+
+// maxSequentialVersion is the highest version of sequential migrations, higher
+// versions are timestamps and are not checked for gaps.
+const maxSequentialVersion = 100000000
+
+var (
+	placeholderRe         = regexp.MustCompile(`\$\{([^}]*)\}`)
+	templatePlaceholderRe = regexp.MustCompile(`\$\{([^}]*)\}|\{\{\s*([^}]*?)\s*\}\}`)
+	errLintConfig         = errors.New("invalid lint configuration")
+)
+
+// lintConfig configures the lint command, it can be read from a YAML file.
+type lintConfig struct {
+	// Rules maps rules to their severity: off, warning or error.
+	Rules map[string]string `yaml:"rules"`
+	// Placeholders are the names of the placeholders replaced when running
+	// migrations, e.g. index for elastic-up.
+	Placeholders []string `yaml:"placeholders"`
+	// TemplatePlaceholders checks {{name}} placeholders, too. They are not
+	// replaced by migrate, but by templates rendering the migrations.
+	TemplatePlaceholders bool `yaml:"template_placeholders"`
+	// Parser is the name of the parser of migration file names, see source.Parsers.
+	Parser string `yaml:"parser"`
+	// SafetyCheck checks .sql migrations for dangerous PostgreSQL statements,
+	// see database/safety.
+	SafetyCheck bool `yaml:"safety_check"`
+}
+
+// lintFinding is a problem found in a migration file.
+type lintFinding struct {
+	File     string
+	Line     int
+	Rule     string
+	Severity lintSeverity
+	Message  string
+}
+
+func (f lintFinding) String() string {
+	location := f.File
+	if f.Line > 0 {
+		location += ":" + strconv.Itoa(f.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s (%s)", location, f.Severity, f.Message, f.Rule)
+}
+
+// linter checks the migration files of a directory.
+type linter struct {
+	severities   map[string]lintSeverity
+	placeholders map[string]bool
+	placeholder  *regexp.Regexp
+	parse        source.Parser
+	safetyCheck  bool
+	findings     []lintFinding
+}
+
+// newLinter returns a linter configured by config, with the severities in
+// rules, e.g. "gap=error,missing-down=off", taking precedence.
+func newLinter(config lintConfig, rules string) (*linter, error) {
+	l := &linter{
+		severities:   make(map[string]lintSeverity, len(lintRules)),
+		placeholders: map[string]bool{"index": true},
+		placeholder:  placeholderRe,
+	}
+	for rule, severity := range lintRules {
+		l.severities[rule] = severity
+	}
+
+	overrides := make(map[string]string)
+	for rule, severity := range config.Rules {
+		overrides[rule] = severity
+	}
+	for _, rule := range splitList(rules) {
+		kv := strings.SplitN(rule, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("%w: rule %q is not rule=severity", errLintConfig, rule)
+		}
+		overrides[kv[0]] = kv[1]
+	}
+	for rule, s := range overrides {
+		if _, ok := lintRules[rule]; !ok {
+			return nil, fmt.Errorf("%w: unknown rule %q", errLintConfig, rule)
+		}
+		severity, err := parseLintSeverity(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errLintConfig, err)
+		}
+		l.severities[rule] = severity
+	}
+
+	if config.Placeholders != nil {
+		l.placeholders = make(map[string]bool, len(config.Placeholders))
+		for _, p := range config.Placeholders {
+			l.placeholders[p] = true
+		}
+	}
+
+	if config.TemplatePlaceholders {
+		l.placeholder = templatePlaceholderRe
+	}
+
+	parse, err := source.ParserByName(config.Parser)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errLintConfig, err)
+	}
+	l.parse = parse
+	l.safetyCheck = config.SafetyCheck
+	return l, nil
+}
+
+func (l *linter) report(file string, line int, rule string, format string, args ...interface{}) {
+	if l.severities[rule] == lintOff {
+		return
+	}
+	l.findings = append(l.findings, lintFinding{
+		File:     file,
+		Line:     line,
+		Rule:     rule,
+		Severity: l.severities[rule],
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintDir checks the migration files of dir, and of its subdirectories holding
+// migrations if recursive is set.
+func (l *linter) lintDir(dir string, recursive bool) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var (
+		files      []string
+		migrations = make(map[string]*source.Migration)
+		referenced = make(map[string]bool)
+		subdirs    []string
+	)
+	for _, e := range entries {
+		if e.IsDir() {
+			subdirs = append(subdirs, filepath.Join(dir, e.Name()))
+			continue
+		}
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		files = append(files, e.Name())
+		if m, err := l.parse(e.Name()); err == nil {
+			migrations[e.Name()] = m
+		}
+	}
+
+	if len(migrations) > 0 {
+		for _, f := range files {
+			if _, ok := migrations[f]; ok {
+				for _, ref := range l.lintFile(dir, f) {
+					referenced[filepath.Clean(ref)] = true
+				}
+			}
+		}
+		for _, f := range files {
+			if _, ok := migrations[f]; !ok && !referenced[f] {
+				l.report(filepath.Join(dir, f), 0, "naming", "file name is not a migration")
+			}
+		}
+		l.lintVersions(dir, migrations)
+	}
+
+	if recursive {
+		for _, subdir := range subdirs {
+			if err := l.lintDir(subdir, recursive); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lintVersions checks the versions of migrations for duplicates, gaps and
+// missing down migrations.
+func (l *linter) lintVersions(dir string, migrations map[string]*source.Migration) {
+	files := make([]string, 0, len(migrations))
+	for f := range migrations {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	byVersion := make(map[uint]map[source.Direction][]string)
+	for _, f := range files {
+		m := migrations[f]
+		if m.Repeatable {
+			continue
+		}
+		if byVersion[m.Version] == nil {
+			byVersion[m.Version] = make(map[source.Direction][]string)
+		}
+		byVersion[m.Version][m.Direction] = append(byVersion[m.Version][m.Direction], f)
+		if m.SingleFile && hasDownSection(filepath.Join(dir, f)) {
+			byVersion[m.Version][source.Down] = append(byVersion[m.Version][source.Down], f)
+		}
+	}
+
+	versions := make([]uint, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	for i, v := range versions {
+		for _, direction := range []source.Direction{source.Up, source.Down} {
+			if files := byVersion[v][direction]; len(files) > 1 {
+				l.report(filepath.Join(dir, files[1]), 0, "duplicate", "version %v has several %s migrations: %s", v, direction, strings.Join(files, ", "))
+			}
+		}
+		if up := byVersion[v][source.Up]; len(up) > 0 && len(byVersion[v][source.Down]) == 0 {
+			l.report(filepath.Join(dir, up[0]), 0, "missing-down", "version %v has no down migration", v)
+		}
+		if i == 0 || v >= maxSequentialVersion || v <= versions[i-1]+1 {
+			continue
+		}
+		if file := filepath.Join(dir, firstFile(byVersion[v])); v == versions[i-1]+2 {
+			l.report(file, 0, "gap", "version %v is missing", v-1)
+		} else {
+			l.report(file, 0, "gap", "versions %v to %v are missing", versions[i-1]+1, v-1)
+		}
+	}
+}
+
+// hasDownSection reports whether the single file migration path has a
+// non-empty down section, see source.SplitSections.
+func hasDownSection(path string) bool {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	_, down, err := source.SplitSections(content)
+	return err == nil && len(bytes.TrimSpace(down)) > 0
+}
+
+func firstFile(files map[source.Direction][]string) string {
+	if len(files[source.Up]) > 0 {
+		return files[source.Up][0]
+	}
+	return files[source.Down][0]
+}
+
+// lintFile checks the content of the migration file f in dir and returns the
+// files it references.
+func (l *linter) lintFile(dir string, f string) []string {
+	path := filepath.Join(dir, f)
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		l.report(path, 0, "naming", "can't read file: %v", err)
+		return nil
+	}
+
+	if _, _, err := database.ParseDirectives(bytes.NewReader(content)); err != nil {
+		l.report(path, 0, "directive", "%v", err)
+	}
+	if unknown, err := database.UnknownDirectives(bytes.NewReader(content)); err == nil {
+		for _, d := range unknown {
+			l.report(path, 0, "unknown-directive", "unknown directive %q, ignored", d)
+		}
+	}
+	l.lintPlaceholders(path, content)
+
+	switch filepath.Ext(f) {
+	case ".json":
+		return l.lintJSON(dir, path, content)
+	case ".txt":
+		l.lintLineProtocol(path, content)
+	case ".sql":
+		if l.safetyCheck {
+			l.lintSafety(path, content)
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintSafety(path string, content []byte) {
+	findings, err := safety.Check(bytes.NewReader(content))
+	if err != nil {
+		l.report(path, 0, "directive", "%v", err)
+		return
+	}
+	for _, f := range findings {
+		l.report(path, int(f.Line), f.Rule, "%s", f.Message)
+	}
+}
+
+func (l *linter) lintPlaceholders(path string, content []byte) {
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, match := range l.placeholder.FindAllStringSubmatch(line, -1) {
+			if name := strings.Join(match[1:], ""); !l.placeholders[name] {
+				l.report(path, i+1, "placeholder", "unresolved placeholder %s", match[0])
+			}
+		}
+	}
+}
+
+// lintJSON checks a JSON migration: an array of http or elastic requests, or
+// of MongoDB commands. It returns the files referenced by the requests.
+func (l *linter) lintJSON(dir string, path string, content []byte) []string {
+	var items []map[string]interface{}
+	if err := json.Unmarshal(content, &items); err != nil {
+		line := 0
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line = bytes.Count(content[:syntaxErr.Offset], []byte("\n")) + 1
+		}
+		l.report(path, line, "json", "invalid JSON: %v", err)
+		return nil
+	}
+
+	isRequest := false
+	for _, item := range items {
+		if _, ok := item["method"]; ok {
+			isRequest = true
+		}
+		if _, ok := item["path"]; ok {
+			isRequest = true
+		}
+	}
+	if !isRequest {
+		var cmds []bson.D
+		if err := bson.UnmarshalExtJSON(content, true, &cmds); err != nil {
+			l.report(path, 0, "mongodb", "invalid extended JSON: %v", err)
+		}
+		return nil
+	}
+
+	var referenced []string
+	for i, item := range items {
+		method, _ := item["method"].(string)
+		requestPath, _ := item["path"].(string)
+		if method == "" || requestPath == "" {
+			l.report(path, 0, "rest-config", "request %d: method and path are required", i+1)
+		}
+		for _, key := range []string{"file_path", "body_path_file"} {
+			ref, _ := item[key].(string)
+			if ref == "" {
+				continue
+			}
+			ref = strings.Trim(ref, "/")
+			referenced = append(referenced, ref)
+			if _, err := os.Stat(filepath.Join(dir, ref)); err != nil {
+				l.report(path, 0, "missing-file", "request %d: %s %q does not exist", i+1, key, ref)
+			}
+		}
+	}
+	return referenced
+}
+
+// lintLineProtocol checks the lines of an InfluxDB migration, see
+// https://docs.influxdata.com/influxdb/v2.0/reference/syntax/line-protocol/.
+func (l *linter) lintLineProtocol(path string, content []byte) {
+	for i, line := range strings.Split(string(content), "\n") {
+		// seed-influx-up trims a trailing comma of every line
+		line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := checkLineProtocol(line); err != nil {
+			l.report(path, i+1, "line-protocol", "%v", err)
+		}
+	}
+}
+
+func checkLineProtocol(line string) error {
+	parts := splitUnescaped(line, ' ')
+	if len(parts) < 2 || len(parts) > 3 {
+		return errors.New("expected measurement, fields and an optional timestamp separated by spaces")
+	}
+
+	series := splitUnescaped(parts[0], ',')
+	if series[0] == "" {
+		return errors.New("missing measurement")
+	}
+	for _, tag := range series[1:] {
+		if kv := splitUnescaped(tag, '='); len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	for _, field := range splitUnescaped(parts[1], ',') {
+		kv := splitUnescaped(field, '=')
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("invalid field %q", field)
+		}
+		if !validFieldValue(kv[1]) {
+			return fmt.Errorf("invalid value of field %q", kv[0])
+		}
+	}
+
+	if len(parts) == 3 {
+		if _, err := strconv.ParseInt(parts[2], 10, 64); err != nil {
+			return fmt.Errorf("invalid timestamp %q", parts[2])
+		}
+	}
+	return nil
+}
+
+func validFieldValue(v string) bool {
+	switch {
+	case len(v) >= 2 && strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`):
+		return true
+	case strings.HasSuffix(v, "i"):
+		_, err := strconv.ParseInt(strings.TrimSuffix(v, "i"), 10, 64)
+		return err == nil
+	case strings.HasSuffix(v, "u"):
+		_, err := strconv.ParseUint(strings.TrimSuffix(v, "u"), 10, 64)
+		return err == nil
+	}
+	switch v {
+	case "t", "T", "true", "True", "TRUE", "f", "F", "false", "False", "FALSE":
+		return true
+	}
+	_, err := strconv.ParseFloat(v, 64)
+	return err == nil
+}
+
+// splitUnescaped splits s at sep, except where sep is escaped with a backslash
+// or inside a double quoted string.
+func splitUnescaped(s string, sep byte) []string {
+	var (
+		parts  []string
+		start  int
+		quoted bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// lintCmd (meant to be called via a CLI command) checks the migration files of
+// dir and writes the findings to w, with safetyCheck also the dangerous
+// statements of .sql migrations. It returns the exit code: 1 if an error was
+// found, or a warning with strict set, 0 otherwise.
+func lintCmd(dir string, configFile string, rules string, recursive bool, strict bool, safetyCheck bool, w io.Writer) (int, error) {
+	var config lintConfig
+	if configFile != "" {
+		b, err := ioutil.ReadFile(configFile)
+		if err != nil {
+			return 0, err
+		}
+		if err := yaml.Unmarshal(b, &config); err != nil {
+			return 0, fmt.Errorf("%w: %v", errLintConfig, err)
+		}
+	}
+
+	config.SafetyCheck = config.SafetyCheck || safetyCheck
+
+	l, err := newLinter(config, rules)
+	if err != nil {
+		return 0, err
+	}
+	if err := l.lintDir(dir, recursive); err != nil {
+		return 0, err
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].File != l.findings[j].File {
+			return l.findings[i].File < l.findings[j].File
+		}
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	var errs, warnings int
+	for _, f := range l.findings {
+		fmt.Fprintln(w, f)
+		if f.Severity == lintError {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	log.Printf("%d errors, %d warnings\n", errs, warnings)
+
+	if errs > 0 || (strict && warnings > 0) {
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLintFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLintCmd(t *testing.T) {
+	dir := writeLintFiles(t, map[string]string{
+		"1_init.up.sql":        "CREATE TABLE t (id int);",
+		"1_init.down.sql":      "DROP TABLE t;",
+		"1_other.up.sql":       "SELECT 1;",
+		"3_seed.up.sql":        "INSERT INTO t VALUES (${id});",
+		"4_bad.up.json":        "[{\"method\": \"PUT\",\n \"path\": }]",
+		"5_req.up.json":        `[{"method": "PUT", "path": "/{{index}}", "body_path_file": "missing.json"}, {"body": {}}]`,
+		"6_mongo.up.json":      `[{"insert": "t", "documents": [{"n": {"$numberInt": "x"}}]}]`,
+		"7_points.up.txt":      "cpu,host=a value=1 1\ncpu value=\n",
+		"8_directive.up.sql":   "-- migrate:no-such-directive\nSELECT 1;",
+		"notes.md":             "not a migration",
+		"9_ok.up.sql":          "SELECT 1;",
+		"9_ok.down.sql":        "SELECT 1;",
+		".hidden_file.up.json": "not checked",
+	})
+
+	tests := []struct {
+		name     string
+		rules    string
+		strict   bool
+		code     int
+		expected []string
+	}{
+		{
+			name: "defaults",
+			code: 1,
+			expected: []string{
+				"1_other.up.sql: error: version 1 has several up migrations: 1_init.up.sql, 1_other.up.sql (duplicate)",
+				"3_seed.up.sql: warning: version 2 is missing (gap)",
+				"3_seed.up.sql:1: error: unresolved placeholder ${id} (placeholder)",
+				"3_seed.up.sql: warning: version 3 has no down migration (missing-down)",
+				"4_bad.up.json:2: error: invalid JSON",
+				"5_req.up.json: error: request 1: body_path_file \"missing.json\" does not exist (missing-file)",
+				"5_req.up.json: error: request 2: method and path are required (rest-config)",
+				"6_mongo.up.json: error: invalid extended JSON",
+				"7_points.up.txt:2: error: invalid value of field \"value\" (line-protocol)",
+				"8_directive.up.sql: warning: unknown directive \"migrate:no-such-directive\", ignored (unknown-directive)",
+				"notes.md: error: file name is not a migration (naming)",
+			},
+		},
+		{
+			name:  "rules",
+			rules: "duplicate=off,naming=off,directive=off,unknown-directive=off,json=off,rest-config=off,mongodb=off,line-protocol=off,placeholder=off,missing-file=off",
+			code:  0,
+		},
+		{
+			name:   "strict",
+			rules:  "duplicate=off,naming=off,directive=off,unknown-directive=off,json=off,rest-config=off,mongodb=off,line-protocol=off,placeholder=off,missing-file=off",
+			strict: true,
+			code:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			code, err := lintCmd(dir, "", tc.rules, false, tc.strict, false, &out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if code != tc.code {
+				t.Errorf("Incorrect exit code was: %v wanted %v\n%s", code, tc.code, out.String())
+			}
+			for _, e := range tc.expected {
+				if !strings.Contains(out.String(), e) {
+					t.Errorf("Missing finding %q in:\n%s", e, out.String())
+				}
+			}
+			if strings.Contains(out.String(), ".hidden_file") {
+				t.Errorf("Hidden files should be skipped:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestLintCmdConfig(t *testing.T) {
+	dir := writeLintFiles(t, map[string]string{
+		"1_seed.up.sql":   "INSERT INTO t VALUES (${id});",
+		"1_seed.down.sql": "DELETE FROM t;",
+		"3_seed.up.sql":   "SELECT 1;",
+		"3_seed.down.sql": "SELECT 1;",
+	})
+	config := filepath.Join(t.TempDir(), "lint.yaml")
+	if err := ioutil.WriteFile(config, []byte("rules:\n  gap: error\nplaceholders: [id]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	code, err := lintCmd(dir, config, "", false, false, false, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 1 || !strings.Contains(out.String(), "error: version 2 is missing") || strings.Contains(out.String(), "placeholder") {
+		t.Errorf("Incorrect findings with exit code %v:\n%s", code, out.String())
+	}
+
+	for _, rules := range []string{"gap", "unknown=error", "gap=fatal"} {
+		if _, err := lintCmd(dir, "", rules, false, false, false, &out); !errors.Is(err, errLintConfig) {
+			t.Errorf("Incorrect error for rules %q was: %v wanted %v", rules, err, errLintConfig)
+		}
+	}
+}
+
+func TestLintCmdTemplatePlaceholders(t *testing.T) {
+	dir := writeLintFiles(t, map[string]string{
+		"1_seed.up.sql":   "INSERT INTO t VALUES ('{{ user }}');",
+		"1_seed.down.sql": "DELETE FROM t;",
+	})
+
+	var out bytes.Buffer
+	if code, err := lintCmd(dir, "", "", false, true, false, &out); err != nil || code != 0 {
+		t.Errorf("Incorrect findings with exit code %v (%v):\n%s", code, err, out.String())
+	}
+
+	config := filepath.Join(t.TempDir(), "lint.yaml")
+	if err := ioutil.WriteFile(config, []byte("template_placeholders: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	code, err := lintCmd(dir, config, "", false, false, false, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 1 || !strings.Contains(out.String(), "1_seed.up.sql:1: error: unresolved placeholder {{ user }} (placeholder)") {
+		t.Errorf("Incorrect findings with exit code %v:\n%s", code, out.String())
+	}
+}
+
+func TestLintCmdSingleFile(t *testing.T) {
+	dir := writeLintFiles(t, map[string]string{
+		"1_users.sql":  "-- +migrate Up\nCREATE TABLE users (id int);\n\n-- +migrate Down\nDROP TABLE users;\n",
+		"2_orders.sql": "-- +migrate Up\nCREATE TABLE orders (id int);\n",
+		"3_items.sql":  "-- +migrate Up\nCREATE TABLE items (id int);\n-- +migrate Down\n",
+	})
+	config := filepath.Join(t.TempDir(), "lint.yaml")
+	if err := ioutil.WriteFile(config, []byte("parser: single-file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if _, err := lintCmd(dir, config, "", false, false, false, &out); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"2_orders.sql: warning: version 2 has no down migration (missing-down)",
+		"3_items.sql: warning: version 3 has no down migration (missing-down)",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Missing finding %q in:\n%s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "version 1 has no down") {
+		t.Errorf("Incorrect finding for version 1 in:\n%s", out.String())
+	}
+}
+
+func TestCheckLineProtocol(t *testing.T) {
+	for line, valid := range map[string]bool{
+		"cpu,host=a value=1 1600000000":  true,
+		`cpu,host=a\ b value="x y",n=1i`: true,
+		"cpu value=t":                    true,
+		"cpu":                            false,
+		"cpu value=":                     false,
+		"cpu value=1 notatime":           false,
+		`cpu value="unterminated`:        false,
+		"cpu,host value=1":               false,
+	} {
+		if err := checkLineProtocol(line); (err == nil) != valid {
+			t.Errorf("Incorrect result for %q was: %v", line, err)
+		}
+	}
+}
+
+func TestLintCmdSafetyCheck(t *testing.T) {
+	dir := writeLintFiles(t, map[string]string{
+		"1_index.up.sql":   "SELECT 1;\nCREATE INDEX users_email ON users (email);",
+		"1_index.down.sql": "-- migrate:safety-ignore\nDROP INDEX users_email;",
+	})
+
+	for safetyCheck, expectedCode := range map[bool]int{false: 0, true: 1} {
+		var out bytes.Buffer
+		code, err := lintCmd(dir, "", "", false, false, safetyCheck, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := strings.Contains(out.String(), "1_index.up.sql:2: error: CREATE INDEX without CONCURRENTLY")
+		if found != safetyCheck || code != expectedCode || strings.Contains(out.String(), "down") {
+			t.Errorf("Incorrect findings with -safety-check=%v and exit code %v:\n%s", safetyCheck, code, out.String())
+		}
+	}
+}
@@ -1,31 +1,52 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	logpkg "log"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/internal/redact"
 )
 
 // Log represents the logger
 type Log struct {
 	verbose bool
+
+	// json writes log entries as JSON objects, see -log-format.
+	json bool
+
+	// redactor masks secrets in all log entries, if not nil.
+	redactor *redact.Redactor
+
+	// exitHooks are called by exit, e.g. to write metrics.
+	exitHooks []func(err error)
 }
 
 // Printf prints out formatted string into a log
 func (l *Log) Printf(format string, v ...interface{}) {
-	if l.verbose {
-		logpkg.Printf(format, v...)
+	msg := l.redact(fmt.Sprintf(format, v...))
+	if l.json {
+		l.entry("info", strings.TrimSuffix(msg, "\n"), nil)
+	} else if l.verbose {
+		logpkg.Print(msg)
 	} else {
-		fmt.Fprintf(os.Stderr, format, v...)
+		fmt.Fprint(os.Stderr, msg)
 	}
 }
 
 // Println prints out args into a log
 func (l *Log) Println(args ...interface{}) {
-	if l.verbose {
-		logpkg.Println(args...)
+	msg := l.redact(fmt.Sprintln(args...))
+	if l.json {
+		l.entry("info", strings.TrimSuffix(msg, "\n"), nil)
+	} else if l.verbose {
+		logpkg.Print(msg)
 	} else {
-		fmt.Fprintln(os.Stderr, args...)
+		fmt.Fprint(os.Stderr, msg)
 	}
 }
 
@@ -34,11 +55,114 @@ func (l *Log) Verbose() bool {
 	return l.verbose
 }
 
+// Debug logs msg and the key-value pairs keysAndValues if verbose print
+// enabled. It implements migrate.StructuredLogger.
+func (l *Log) Debug(msg string, keysAndValues ...interface{}) {
+	if l.verbose {
+		l.entry("debug", msg, keysAndValues)
+	}
+}
+
+// Info logs msg and the key-value pairs keysAndValues.
+func (l *Log) Info(msg string, keysAndValues ...interface{}) {
+	l.entry("info", msg, keysAndValues)
+}
+
+// Warn logs msg and the key-value pairs keysAndValues as warning.
+func (l *Log) Warn(msg string, keysAndValues ...interface{}) {
+	l.entry("warn", msg, keysAndValues)
+}
+
+// Error logs msg and the key-value pairs keysAndValues as error.
+func (l *Log) Error(msg string, keysAndValues ...interface{}) {
+	l.entry("error", msg, keysAndValues)
+}
+
+// entry writes a log entry. Text entries are the message only, prefixed
+// for warnings and errors, JSON entries hold the key-value pairs too.
+func (l *Log) entry(level string, msg string, keysAndValues []interface{}) {
+	if !l.json {
+		switch level {
+		case "warn":
+			msg = "warning: " + msg
+		case "error":
+			msg = "error: " + msg
+		}
+		l.Println(msg)
+		return
+	}
+
+	entry := map[string]interface{}{
+		"time":  time.Now().Format(time.RFC3339Nano),
+		"level": level,
+		"msg":   l.redact(msg),
+	}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		value := jsonValue(keysAndValues[i+1])
+		if s, ok := value.(string); ok {
+			value = l.redact(s)
+		}
+		entry[fmt.Sprint(keysAndValues[i])] = value
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		b, _ = json.Marshal(map[string]interface{}{"level": level, "msg": l.redact(msg)})
+	}
+	fmt.Fprintln(os.Stderr, string(b))
+}
+
+// redact masks the secrets in s.
+func (l *Log) redact(s string) string {
+	if l.redactor == nil {
+		return s
+	}
+	return l.redactor.String(s)
+}
+
+// jsonValue returns the JSON representation of errors and fmt.Stringers,
+// e.g. time.Duration, as string and v otherwise.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+// finished logs the time the command took since startTime.
+func (l *Log) finished(startTime time.Time) {
+	duration := time.Since(startTime)
+	l.Info(fmt.Sprintf("Finished after %v", duration), "duration", duration)
+}
+
 func (l *Log) fatal(args ...interface{}) {
-	l.Println(args...)
+	msg := l.redact(strings.TrimSuffix(fmt.Sprintln(args...), "\n"))
+	if l.json {
+		l.Error(strings.TrimPrefix(msg, "error: "))
+	} else {
+		l.Println(args...)
+	}
+	l.exit(errors.New(strings.TrimPrefix(msg, "error: ")))
 	os.Exit(1)
 }
 
 func (l *Log) fatalErr(err error) {
 	l.fatal("error:", err)
 }
+
+// atExit registers hook to be called by exit.
+func (l *Log) atExit(hook func(err error)) {
+	l.exitHooks = append(l.exitHooks, hook)
+}
+
+// exit calls the exit hooks in reverse order with the error the CLI exits
+// with, if any. It is called on return of Main and on fatal errors.
+func (l *Log) exit(err error) {
+	hooks := l.exitHooks
+	l.exitHooks = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i](err)
+	}
+}
@@ -12,33 +12,78 @@ import (
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/internal/redact"
+	iurl "github.com/golang-migrate/migrate/v4/internal/url"
 	"github.com/golang-migrate/migrate/v4/source"
 )
 
 const (
 	defaultTimeFormat = "20060102150405"
 	defaultTimezone   = "UTC"
-	createUsage       = `create [-ext E] [-dir D] [-seq] [-digits N] [-format] [-tz] NAME
+	createUsage       = `create [-ext E] [-dir D] [-seq] [-digits N] [-format] [-tz] [-single-file] NAME
 	   Create a set of timestamped up/down migrations titled NAME, in directory D with extension E.
 	   Use -seq option to generate sequential up/down migrations with N digits.
+	   Use -single-file option to keep up and down in one file, separated by section markers (read with x-parser=single-file).
 	   Use -format option to specify a Go time format string. Note: migrations with the same time cause "duplicate migration version" error.
            Use -tz option to specify the timezone that will be used when generating non-sequential migrations (defaults: UTC).
 `
-	gotoUsage        = `goto V       	  Migrate to version V`
-	upUsage          = `up [N]       	  Apply all or N up migrations`
-	upElasticUsage   = `elastic-up	 	  Apply all rest api on elasticsearch with json file`
-	seedUsage        = `seed-up [N]	  	  Apply all migration File without version`
+	gotoUsage = `goto V       	  Migrate to version V`
+	upUsage   = `up [N] [-tags T] [-exclude-tags T] [-phase P] [-safety-check]
+	   Apply all or N up migrations
+	   Use -tags and -exclude-tags to run only the migrations tagged (not) with one of the comma separated tags T.
+	   Skipped migrations are not run, but the version moves past them.
+	   Use -phase option to apply only the pending migrations of phase P (expand or contract).
+	   Use -safety-check option to refuse pending postgres migrations with statements locking tables for long,
+	   like CREATE INDEX without CONCURRENTLY, unless they are suppressed by "-- migrate:safety-ignore=RULES".`
+	upElasticUsage   = `elastic-up [-tags T] [-exclude-tags T]	 	  Apply all rest api on elasticsearch with json file`
+	seedUsage        = `seed-up [N] [-tags T] [-exclude-tags T]	  	  Apply all migration File without version`
 	seedDownUsage    = `seed-down [N]	  Apply all down migrations only first file`
 	seedInfluxUsage  = `seed-influx-up 	  Read All file with write data by line protocol`
 	seedElasticUsage = `seed-elastic-up   Read All file with write data by json pattern`
-	httpUp           = `http-up			  Read All file with send rest api (start by version ascending)`
+	httpUp           = `http-up [-tags T] [-exclude-tags T]			  Read All file with send rest api (start by version ascending)`
 	httpDown         = `http-down		  Apply All file with send rest api (start by version descending)`
 	downUsage        = `down [N] [-all]   Apply all or N down migrations
 	Use -all to apply all down migrations`
 	downElasticUsage = `elastic-down	  Down All File migration`
 	dropUsage        = `drop [-f]    Drop everything inside database
 	Use -f to bypass confirmation`
-	forceUsage = `force V      Set version V but don't run migration (ignores dirty state)`
+	forceUsage    = `force V      Set version V but don't run migration (ignores dirty state)`
+	baselineUsage = `baseline V [-description D]
+	   Mark an existing database as being at version V without running migrations.
+	   Refuses if the database already has migrations applied. up will start after V.`
+	fleetUsage = `fleet [-tenants T] [-tenants-query Q -tenants-database D] [-targets-file F] [-concurrency N] [-continue-on-error] [-tags T] [-exclude-tags T] [up|elastic-up]
+	   Run up (default) or elastic-up against many databases and print a summary per target.
+	   Use -tenants or -tenants-query to substitute each tenant for ${tenant} and ${index} in -database,
+	   -tenants-query is run against -tenants-database and returns one tenant per row.
+	   Use -targets-file to read one database URL per line instead.
+	   elastic-up uses the tenant as index, or -index for the targets of -targets-file.
+	   Use -continue-on-error to keep migrating the remaining targets after a target failed.`
+	applyUsage = `apply -plan F
+	   Apply the steps of the YAML plan F in order, each with its own source and database.
+	   Steps run like up, elastic-up or http-up. With rollback_on_failure, completed steps are run down if a step fails.`
+	squashUsage = `squash -to V [-archive DIR]
+	   Replace the migrations up to V by a single migration holding the schema at V, dumped from the database,
+	   and a down migration dropping it. The files are named for the parser of the source (-x-parser).
+	   The database must be empty, it is migrated to V first, or already be at V. Supported for postgres and sqlite3.
+	   The original migration files are moved to DIR (default: <path>_archive).
+	   Databases at V or later are unaffected, older databases must be migrated to V with the archived migrations
+	   first, databases holding the schema that were never migrated must be marked with "baseline V".`
+	diffUsage = `diff -from URL -to URL [-dir D] [-seq] [-digits N] [-format] [-tz] [-single-file] NAME
+	   Compare the schemas of the databases -from and -to and create a set of up/down migrations titled NAME,
+	   changing the schema of -from into the schema of -to. Supported for postgres and sqlite3.
+	   The other options work like for create.`
+	lintUsage = `lint [-config F] [-rules R] [-recursive] [-strict] [-safety-check] [DIR]
+	   Check the migration files in DIR (default: -path) for invalid names, duplicate and missing versions,
+	   missing down migrations, invalid JSON, MongoDB extended JSON and InfluxDB line protocol,
+	   incomplete http and elastic requests, unresolved placeholders and missing referenced files.
+	   Use -safety-check option to check .sql migrations for dangerous postgres statements, like up -safety-check.
+	   Use -rules to set the severity of rules, e.g. "gap=error,missing-down=off", or a YAML file -config.
+	   Exits with 1 if errors, or with -strict warnings, were found and with 2 if the files can't be checked.`
+	serveUsage = `serve [-listen A] [-token T]
+	   Serve an HTTP API on address A (default :8080) to run and observe migrations, see cmd/migrate/README.md.
+	   Requests must send the bearer token T, which defaults to the MIGRATE_SERVE_TOKEN environment variable.`
+	dumpSchemaUsage = `dump-schema [FILE]
+	   Write the database schema to FILE, the -dump-schema file or stdout.`
 )
 
 const (
@@ -87,8 +132,14 @@ func newFlagSetWithHelp(name string) (*flag.FlagSet, *bool) {
 	return flagSet, helpPtr
 }
 
+func tagFlags(flagSet *flag.FlagSet) (tags *string, excludeTags *string) {
+	tags = flagSet.String("tags", "", "Only run migrations tagged with one of these comma separated tags (untagged migrations always run)")
+	excludeTags = flagSet.String("exclude-tags", "", "Skip migrations tagged with one of these comma separated tags")
+	return tags, excludeTags
+}
+
 // set main log
-var log = &Log{}
+var log = &Log{redactor: redact.New()}
 
 func printUsageAndExit() {
 	flag.Usage()
@@ -103,8 +154,16 @@ func Main(version string) {
 	helpPtr := flag.Bool("help", false, "")
 	versionPtr := flag.Bool("version", false, "")
 	verbosePtr := flag.Bool("verbose", false, "")
+	logFormatPtr := flag.String("log-format", "text", "")
 	prefetchPtr := flag.Uint("prefetch", 10, "")
 	lockTimeoutPtr := flag.Uint("lock-timeout", 15, "")
+	graphPtr := flag.Bool("graph", false, "")
+	dumpSchemaPtr := flag.String("dump-schema", "", "")
+	shadowDatabasePtr := flag.String("shadow-database", "", "")
+	metricsListenPtr := flag.String("metrics-listen", "", "")
+	metricsFilePtr := flag.String("metrics-file", "", "")
+	traceOTLPPtr := flag.String("trace-otlp", os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), "")
+	traceFilePtr := flag.String("trace-file", "", "")
 	pathPtr := flag.String("path", "", "")
 	databasePtr := flag.String("database", "", "")
 	sourcePtr := flag.String("source", "", "")
@@ -113,6 +172,7 @@ func Main(version string) {
 	excludeHeader := flag.String("exclude_header", "", "Exclude Header")
 	skippErrorPtr := flag.Bool("skip-error", true, "skip error when some migrate error but will show error message")
 	debugPtr := flag.Bool("debug", false, "open debug mode")
+	redactHeadersPtr := flag.String("redact-headers", "", "")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr,
@@ -125,9 +185,24 @@ Options:
   -database        Run migrations against this database (driver://url)
   -prefetch N      Number of migrations to load in advance before executing (default 10)
   -lock-timeout N  Allow N seconds to acquire database lock (default 15)
+  -graph           Order migrations by their depends_on directives instead of by version
+  -dump-schema F   Write the database schema to file F after up, down and goto
+  -shadow-database URL
+                   Apply the migrations of up and goto to this empty database first, after replaying the
+                   migrations up to the current version, and stop if they fail. The shadow database is dropped afterwards.
+  -metrics-listen ADDR
+                   Serve Prometheus metrics of the run on ADDR/metrics, e.g. :9090
+  -metrics-file F  Write Prometheus metrics of the run to file F on exit, e.g. for the node exporter textfile collector
+  -trace-otlp URL  Export OpenTelemetry spans of the run to the OTLP/HTTP traces endpoint URL,
+                   e.g. http://localhost:4318/v1/traces (default $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT)
+  -trace-file F    Append OpenTelemetry spans of the run as OTLP JSON to file F
   -verbose         Print verbose logging
+  -log-format F    Print log entries as text or as json objects with fields such as version and duration (default text)
   -version         Print version
   -token		   Token Policy usage for seed influx
+  -redact-headers H
+                   Comma separated HTTP headers whose values are masked in logs and -debug dumps,
+                   in addition to Authorization, Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key
   -help            Print usage
 
 Commands:
@@ -145,16 +220,35 @@ Commands:
   %s
   %s
   %s
+  %s
+  %s
+  %s
+  %s
+  %s
+  %s
+  %s
+  %s
   version      Print current migration version
 
 Source drivers: `+strings.Join(source.List(), ", ")+`
-Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoUsage, upUsage, upElasticUsage, httpUp, httpDown, seedUsage, seedDownUsage, seedInfluxUsage, seedElasticDetail, downUsage, dropUsage, forceUsage, seedHTTPDetail)
+Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoUsage, upUsage, upElasticUsage, httpUp, httpDown, seedUsage, seedDownUsage, seedInfluxUsage, seedElasticDetail, downUsage, dropUsage, forceUsage, baselineUsage, fleetUsage, applyUsage, squashUsage, dumpSchemaUsage, diffUsage, lintUsage, serveUsage, seedHTTPDetail)
 	}
 
 	flag.Parse()
 
 	// initialize logger
 	log.verbose = *verbosePtr
+	log.redactor.AddSecret(*tokenPtr)
+	for _, header := range splitList(*redactHeadersPtr) {
+		log.redactor.AddHeader(header)
+	}
+	switch *logFormatPtr {
+	case "text":
+	case "json":
+		log.json = true
+	default:
+		log.fatal("error: -log-format must be text or json")
+	}
 
 	// show cli version
 	if *versionPtr {
@@ -176,7 +270,17 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 	// initialize migrate
 	// don't catch migraterErr here and let each command decide
 	// how it wants to handle the error
-	migrater, migraterErr := migrate.New(*sourcePtr, *databasePtr)
+	// fleet and apply create an instance per target, -database is a template
+	// or not used there, diff only opens databases and lint only reads files
+	var (
+		migrater    *migrate.Migrate
+		migraterErr = errFleetMigrater
+	)
+	switch flag.Arg(0) {
+	case "fleet", "apply", "diff", "lint":
+	default:
+		migrater, migraterErr = migrate.New(*sourcePtr, *databasePtr)
+	}
 	defer func() {
 		if migraterErr == nil {
 			if _, err := migrater.Close(); err != nil {
@@ -184,10 +288,41 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			}
 		}
 	}()
+	// write metrics and traces on exit, also on fatal errors
+	defer log.exit(nil)
+	var observers []migrate.Observer
+	if *traceOTLPPtr != "" || *traceFilePtr != "" {
+		if err := startTracing(*traceOTLPPtr, *traceFilePtr, "migrate "+flag.Arg(0)); err != nil {
+			log.fatalErr(err)
+		}
+		log.atExit(endTracing)
+		observers = append(observers, tracer)
+	}
+	if *metricsListenPtr != "" || *metricsFilePtr != "" {
+		startMetrics(*metricsListenPtr)
+		databaseName, _ := iurl.SchemeFromURL(*databasePtr)
+		log.atExit(func(error) {
+			writeMetrics(migrater, databaseName, *metricsFilePtr)
+		})
+		observers = append(observers, collector)
+	}
+	// the observer is also set on the instances of fleet and apply
+	var observer migrate.Observer
+	if len(observers) > 0 {
+		observer = migrate.MultiObserver(observers...)
+	}
 	if migraterErr == nil {
 		migrater.Log = log
+		if observer != nil {
+			migrater.Observer = observer
+		}
 		migrater.PrefetchMigrations = *prefetchPtr
 		migrater.LockTimeout = time.Duration(int64(*lockTimeoutPtr)) * time.Second
+		if *graphPtr {
+			if err := migrater.WithDependencyGraph(); err != nil {
+				log.fatalErr(err)
+			}
+		}
 
 		// handle Ctrl+c
 		signals := make(chan os.Signal, 1)
@@ -213,6 +348,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 
 		seq := false
 		seqDigits := 6
+		singleFile := false
 
 		createFlagSet, help := newFlagSetWithHelp("create")
 		extPtr := createFlagSet.String("ext", "", "File extension")
@@ -221,6 +357,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 		timezoneName := createFlagSet.String("tz", defaultTimezone, `The timezone that will be used for generating timestamps (default: utc)`)
 		createFlagSet.BoolVar(&seq, "seq", seq, "Use sequential numbers instead of timestamps (default: false)")
 		createFlagSet.IntVar(&seqDigits, "digits", seqDigits, "The number of digits to use in sequences (default: 6)")
+		createFlagSet.BoolVar(&singleFile, "single-file", singleFile, "Create a single file holding the up and down migration (default: false)")
 
 		if err := createFlagSet.Parse(args); err != nil {
 			log.fatalErr(err)
@@ -242,7 +379,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatal(err)
 		}
 
-		if err := createCmd(*dirPtr, startTime.In(timezone), *formatPtr, name, *extPtr, seq, seqDigits, true); err != nil {
+		if err := createCmd(*dirPtr, startTime.In(timezone), *formatPtr, name, *extPtr, seq, seqDigits, singleFile, true); err != nil {
 			log.fatalErr(err)
 		}
 
@@ -269,16 +406,31 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatal("error: can't read version argument V")
 		}
 
+		if err := shadowCmd(migrater, *shadowDatabasePtr, func(shadow *migrate.Migrate) error {
+			return shadow.Migrate(uint(v))
+		}); err != nil {
+			log.fatalErr(err)
+		}
+
 		if err := gotoCmd(migrater, uint(v)); err != nil {
 			log.fatalErr(err)
 		}
 
+		if *dumpSchemaPtr != "" {
+			if err := dumpSchemaCmd(migrater, *dumpSchemaPtr); err != nil {
+				log.fatalErr(err)
+			}
+		}
+
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 
 	case "up":
 		upSet, helpPtr := newFlagSetWithHelp("up")
+		tagsPtr, excludeTagsPtr := tagFlags(upSet)
+		phasePtr := upSet.String("phase", "", "Apply only the pending migrations of this phase (expand or contract)")
+		safetyCheckPtr := upSet.Bool("safety-check", false, "Refuse pending postgres migrations with dangerous statements")
 
 		if err := upSet.Parse(args); err != nil {
 			log.fatalErr(err)
@@ -299,15 +451,50 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			limit = int(n)
 		}
 
-		if err := upCmd(migrater, limit); err != nil {
+		if *safetyCheckPtr {
+			if err := safetyCheckCmd(migrater, *sourcePtr, *databasePtr, limit); err != nil {
+				log.fatalErr(err)
+			}
+		}
+
+		migrater.WithTags(splitList(*tagsPtr), splitList(*excludeTagsPtr))
+		if *phasePtr != "" && limit >= 0 {
+			log.fatal("error: -phase cannot be used with limit argument N")
+		}
+
+		if err := shadowCmd(migrater, *shadowDatabasePtr, func(shadow *migrate.Migrate) error {
+			switch {
+			case *phasePtr != "":
+				return shadow.UpPhase(*phasePtr)
+			case limit >= 0:
+				return shadow.Steps(limit)
+			default:
+				return shadow.Up()
+			}
+		}); err != nil {
 			log.fatalErr(err)
 		}
 
+		if *phasePtr != "" {
+			if err := upPhaseCmd(migrater, *phasePtr); err != nil {
+				log.fatalErr(err)
+			}
+		} else if err := upCmd(migrater, limit); err != nil {
+			log.fatalErr(err)
+		}
+
+		if *dumpSchemaPtr != "" {
+			if err := dumpSchemaCmd(migrater, *dumpSchemaPtr); err != nil {
+				log.fatalErr(err)
+			}
+		}
+
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 	case "elastic-up":
 		elasticUpSet, helpPtr := newFlagSetWithHelp("elastic-up")
+		tagsPtr, excludeTagsPtr := tagFlags(elasticUpSet)
 
 		if err := elasticUpSet.Parse(args); err != nil {
 			log.fatal(fmt.Errorf("errors: " + err.Error()).Error())
@@ -321,11 +508,11 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 
 		handleSubCmdHelp(*helpPtr, upElasticUsage, elasticUpSet)
 
-		if err := seedUpElasticCmd(*databasePtr, *pathPtr, *excludeHeader, *indexPtr, *skippErrorPtr, *debugPtr); err != nil {
+		if err := seedUpElasticCmd(*databasePtr, *pathPtr, *excludeHeader, *indexPtr, *skippErrorPtr, *debugPtr, splitList(*tagsPtr), splitList(*excludeTagsPtr)); err != nil {
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "elastic-down":
 		elasticDownSet, helpPtr := newFlagSetWithHelp("elastic-down")
 
@@ -345,9 +532,10 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "http-up":
 		httpUpSet, helpPtr := newFlagSetWithHelp("http-up")
+		tagsPtr, excludeTagsPtr := tagFlags(httpUpSet)
 
 		if err := httpUpSet.Parse(args); err != nil {
 			log.fatal(fmt.Errorf("errors: " + err.Error()).Error())
@@ -360,11 +548,11 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 
 		handleSubCmdHelp(*helpPtr, httpUp, httpUpSet)
 
-		if err := seedUpHttpCmd(*databasePtr, *pathPtr, *excludeHeader, *skippErrorPtr, *debugPtr); err != nil {
+		if err := seedUpHttpCmd(*databasePtr, *pathPtr, *excludeHeader, *skippErrorPtr, *debugPtr, splitList(*tagsPtr), splitList(*excludeTagsPtr)); err != nil {
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "http-down":
 		httpDownSet, helpPtr := newFlagSetWithHelp("http-down")
 
@@ -384,9 +572,10 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "seed-up":
 		upSet, helpPtr := newFlagSetWithHelp("seed-up")
+		tagsPtr, excludeTagsPtr := tagFlags(upSet)
 
 		if err := upSet.Parse(args); err != nil {
 			log.fatalErr(err)
@@ -407,12 +596,13 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			limit = int(n)
 		}
 
+		migrater.WithTags(splitList(*tagsPtr), splitList(*excludeTagsPtr))
 		if err := seedUpCmd(migrater, limit); err != nil {
 			log.fatalErr(err)
 		}
 
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 
 	case "seed-down":
@@ -440,7 +630,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 		}
 
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 	case "seed-influx-up":
 		seedInfluxFlagSet, helpPtr := newFlagSetWithHelp("seed-influx-up")
@@ -459,7 +649,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "seed-elastic-up":
 		seedElasticSet, helpPtr := newFlagSetWithHelp("seed-elastic-up")
 
@@ -475,11 +665,11 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 
 		handleSubCmdHelp(*helpPtr, seedElasticDetail, seedElasticSet)
 
-		if err := seedUpElasticCmd(*databasePtr, *pathPtr, *excludeHeader, *indexPtr, *skippErrorPtr, *debugPtr); err != nil {
+		if err := seedUpElasticCmd(*databasePtr, *pathPtr, *excludeHeader, *indexPtr, *skippErrorPtr, *debugPtr, nil, nil); err != nil {
 			log.fatalErr(err)
 		}
 
-		log.Println("Finished after", time.Since(startTime))
+		log.finished(startTime)
 	case "down":
 		downFlagSet, helpPtr := newFlagSetWithHelp("down")
 		applyAll := downFlagSet.Bool("all", false, "Apply all down migrations")
@@ -516,8 +706,14 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 			log.fatalErr(err)
 		}
 
+		if *dumpSchemaPtr != "" {
+			if err := dumpSchemaCmd(migrater, *dumpSchemaPtr); err != nil {
+				log.fatalErr(err)
+			}
+		}
+
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 
 	case "drop":
@@ -552,7 +748,7 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 		}
 
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
 		}
 
 	case "force":
@@ -586,7 +782,253 @@ Database drivers: `+strings.Join(database.List(), ", ")+"\n", createUsage, gotoU
 		}
 
 		if log.verbose {
-			log.Println("Finished after", time.Since(startTime))
+			log.finished(startTime)
+		}
+
+	case "serve":
+		serveSet, helpPtr := newFlagSetWithHelp("serve")
+		listenPtr := serveSet.String("listen", ":8080", "Address to listen on")
+		serveTokenPtr := serveSet.String("token", os.Getenv("MIGRATE_SERVE_TOKEN"), "Bearer token required by all requests")
+
+		if err := serveSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, serveUsage, serveSet)
+
+		if migraterErr != nil {
+			log.fatalErr(migraterErr)
+		}
+
+		if err := serveCmd(migrater, *listenPtr, *serveTokenPtr); err != nil {
+			log.fatalErr(err)
+		}
+
+	case "lint":
+		lintSet, helpPtr := newFlagSetWithHelp("lint")
+		configPtr := lintSet.String("config", "", "YAML file configuring the rules")
+		rulesPtr := lintSet.String("rules", "", "Comma separated rule=severity pairs, severity is off, warning or error")
+		recursivePtr := lintSet.Bool("recursive", false, "Check the subdirectories holding migrations, too")
+		strictPtr := lintSet.Bool("strict", false, "Fail on warnings")
+		safetyCheckPtr := lintSet.Bool("safety-check", false, "Check .sql migrations for dangerous postgres statements")
+
+		if err := lintSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, lintUsage, lintSet)
+
+		dir := *pathPtr
+		if lintSet.NArg() > 0 {
+			dir = lintSet.Arg(0)
+		}
+		if dir == "" {
+			log.fatal("error: please specify the migrations directory DIR or -path")
+		}
+
+		code, err := lintCmd(dir, *configPtr, *rulesPtr, *recursivePtr, *strictPtr, *safetyCheckPtr, os.Stdout)
+		if err != nil {
+			log.Error(err.Error())
+			os.Exit(2)
+		}
+		os.Exit(code)
+
+	case "diff":
+		seq := false
+		seqDigits := 6
+		singleFile := false
+
+		diffFlagSet, help := newFlagSetWithHelp("diff")
+		fromPtr := diffFlagSet.String("from", "", "Database URL of the current schema")
+		toPtr := diffFlagSet.String("to", "", "Database URL of the new schema")
+		dirPtr := diffFlagSet.String("dir", "", "Directory to place file in (default: current working directory)")
+		formatPtr := diffFlagSet.String("format", defaultTimeFormat, `The Go time format string to use. If the string "unix" or "unixNano" is specified, then the seconds or nanoseconds since January 1, 1970 UTC respectively will be used. Caution, due to the behavior of time.Time.Format(), invalid format strings will not error`)
+		timezoneName := diffFlagSet.String("tz", defaultTimezone, `The timezone that will be used for generating timestamps (default: utc)`)
+		diffFlagSet.BoolVar(&seq, "seq", seq, "Use sequential numbers instead of timestamps (default: false)")
+		diffFlagSet.IntVar(&seqDigits, "digits", seqDigits, "The number of digits to use in sequences (default: 6)")
+		diffFlagSet.BoolVar(&singleFile, "single-file", singleFile, "Create a single file holding the up and down migration (default: false)")
+
+		if err := diffFlagSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*help, diffUsage, diffFlagSet)
+
+		if *fromPtr == "" || *toPtr == "" {
+			log.fatal("error: please specify -from and -to")
+		}
+
+		if diffFlagSet.NArg() == 0 {
+			log.fatal("error: please specify name")
+		}
+		name := diffFlagSet.Arg(0)
+
+		timezone, err := time.LoadLocation(*timezoneName)
+		if err != nil {
+			log.fatal(err)
+		}
+
+		if err := diffCmd(*fromPtr, *toPtr, *dirPtr, startTime.In(timezone), *formatPtr, name, seq, seqDigits, singleFile); err != nil {
+			log.fatalErr(err)
+		}
+
+	case "dump-schema":
+		dumpSchemaSet, helpPtr := newFlagSetWithHelp("dump-schema")
+
+		if err := dumpSchemaSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, dumpSchemaUsage, dumpSchemaSet)
+
+		if migraterErr != nil {
+			log.fatalErr(migraterErr)
+		}
+
+		path := *dumpSchemaPtr
+		if dumpSchemaSet.NArg() > 0 {
+			path = dumpSchemaSet.Arg(0)
+		}
+
+		if err := dumpSchemaCmd(migrater, path); err != nil {
+			log.fatalErr(err)
+		}
+
+	case "squash":
+		squashSet, helpPtr := newFlagSetWithHelp("squash")
+		toPtr := squashSet.Uint("to", 0, "Version of the last migration to squash")
+		archivePtr := squashSet.String("archive", "", "Directory the squashed migration files are moved to")
+
+		if err := squashSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, squashUsage, squashSet)
+
+		if migraterErr != nil {
+			log.fatalErr(migraterErr)
+		}
+
+		if *toPtr == 0 {
+			log.fatal("error: please specify the version to squash to with -to")
+		}
+
+		dir, parser, err := squashDir(*pathPtr, *sourcePtr)
+		if err != nil {
+			log.fatalErr(err)
+		}
+
+		if err := squashCmd(migrater, dir, parser, *archivePtr, *toPtr, startTime); err != nil {
+			log.fatalErr(err)
+		}
+
+		if log.verbose {
+			log.finished(startTime)
+		}
+
+	case "baseline":
+		baselineSet, helpPtr := newFlagSetWithHelp("baseline")
+		descriptionPtr := baselineSet.String("description", "", "Description recorded with the baseline")
+
+		v, argErr := baselineVersionFromArgs(baselineSet, args)
+
+		handleSubCmdHelp(*helpPtr, baselineUsage, baselineSet)
+
+		if migraterErr != nil {
+			log.fatalErr(migraterErr)
+		}
+
+		if argErr != nil {
+			log.fatalErr(argErr)
+		}
+
+		if err := baselineCmd(migrater, v, *descriptionPtr); err != nil {
+			log.fatalErr(err)
+		}
+
+		if log.verbose {
+			log.finished(startTime)
+		}
+
+	case "fleet":
+		fleetSet, helpPtr := newFlagSetWithHelp("fleet")
+		tenantsPtr := fleetSet.String("tenants", "", "Comma separated tenants substituted for ${tenant} and ${index} in -database")
+		tenantsQueryPtr := fleetSet.String("tenants-query", "", "Query listing the tenants, one per row")
+		tenantsDatabasePtr := fleetSet.String("tenants-database", "", "Database to run -tenants-query against")
+		targetsFilePtr := fleetSet.String("targets-file", "", "File with one database URL per line")
+		concurrencyPtr := fleetSet.Int("concurrency", migrate.DefaultFleetConcurrency, "Number of targets migrated at the same time")
+		continueOnErrorPtr := fleetSet.Bool("continue-on-error", false, "Keep migrating the remaining targets after a target failed")
+		tagsPtr, excludeTagsPtr := tagFlags(fleetSet)
+
+		if err := fleetSet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, fleetUsage, fleetSet)
+
+		command := "up"
+		if fleetSet.NArg() > 0 {
+			command = fleetSet.Arg(0)
+		}
+
+		targets, err := fleetTargets(*databasePtr, *tenantsPtr, *tenantsQueryPtr, *tenantsDatabasePtr, *targetsFilePtr)
+		if err != nil {
+			log.fatalErr(err)
+		}
+
+		tags, excludeTags := splitList(*tagsPtr), splitList(*excludeTagsPtr)
+		fleet := &migrate.Fleet{
+			SourceURL:       *sourcePtr,
+			Targets:         targets,
+			Concurrency:     *concurrencyPtr,
+			ContinueOnError: *continueOnErrorPtr,
+			Log:             log,
+			Configure:       fleetConfigure(observer, *prefetchPtr, time.Duration(int64(*lockTimeoutPtr))*time.Second, tags, excludeTags),
+		}
+		elasticUp := func(target migrate.FleetTarget) error {
+			index := target.Index
+			if index == "" {
+				index = *indexPtr
+			}
+			return seedUpElasticCmd(target.DatabaseURL, *pathPtr, *excludeHeader, index, *skippErrorPtr, *debugPtr, tags, excludeTags)
+		}
+
+		if err := fleetCmd(fleet, command, elasticUp, os.Stdout); err != nil {
+			log.fatalErr(err)
+		}
+
+		if log.verbose {
+			log.finished(startTime)
+		}
+
+	case "apply":
+		applySet, helpPtr := newFlagSetWithHelp("apply")
+		planPtr := applySet.String("plan", "", "YAML file with the steps to apply")
+
+		if err := applySet.Parse(args); err != nil {
+			log.fatalErr(err)
+		}
+
+		handleSubCmdHelp(*helpPtr, applyUsage, applySet)
+
+		if *planPtr == "" {
+			log.fatal("error: please specify -plan")
+		}
+
+		configure := func(m *migrate.Migrate) {
+			m.Log = log
+			if observer != nil {
+				m.Observer = observer
+			}
+			m.PrefetchMigrations = *prefetchPtr
+			m.LockTimeout = time.Duration(int64(*lockTimeoutPtr)) * time.Second
+		}
+		if err := applyCmd(*planPtr, configure); err != nil {
+			log.fatalErr(err)
+		}
+
+		if log.verbose {
+			log.finished(startTime)
 		}
 
 	case "version":
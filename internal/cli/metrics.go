@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"net/http"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/metrics"
+)
+
+// collector collects the metrics of the run if -metrics-listen or
+// -metrics-file is set, otherwise it is nil.
+var collector *metrics.Collector
+
+// startMetrics creates the collector and serves it on /metrics at listen, if
+// not empty.
+func startMetrics(listen string) {
+	collector = metrics.New()
+	if listen == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", collector)
+	go func() {
+		if err := http.ListenAndServe(listen, mux); err != nil {
+			log.Error("metrics: "+err.Error())
+		}
+	}()
+}
+
+// writeMetrics reports the version of m, if not nil, and writes the metrics
+// to the file name, if not empty.
+func writeMetrics(m *migrate.Migrate, databaseName string, name string) {
+	if collector == nil {
+		return
+	}
+	if m != nil {
+		if version, dirty, err := m.Version(); err == nil {
+			collector.VersionChanged(databaseName, int(version), dirty)
+		} else if err == migrate.ErrNilVersion {
+			collector.VersionChanged(databaseName, -1, false)
+		}
+	}
+	if name == "" {
+		return
+	}
+	if err := collector.WriteFile(name); err != nil {
+		log.Error("metrics: "+err.Error())
+	}
+}
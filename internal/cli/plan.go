@@ -0,0 +1,302 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4"
+	"gopkg.in/yaml.v3"
+)
+
+// Step types of a plan.
+const (
+	stepMigrate = "migrate"
+	stepElastic = "elastic"
+	stepHTTP    = "http"
+)
+
+var (
+	errPlanNoSteps = errors.New("plan has no steps")
+	errPlanCycle   = errors.New("plan steps depend on each other in a cycle")
+)
+
+// Plan is an ordered list of steps applied by the apply command, e.g. SQL
+// migrations followed by an Elasticsearch mapping change and a REST call.
+// Plans are read from YAML:
+//
+//  rollback_on_failure: true
+//  steps:
+//    - name: schema
+//      source: file://migrations/sql
+//      database: postgres://localhost:5432/app
+//    - name: mappings
+//      type: elastic
+//      path: migrations/elastic
+//      database: http://localhost:9200
+//      index: products
+//      depends_on: [schema]
+type Plan struct {
+	// RollbackOnFailure runs down for the completed steps, in reverse order,
+	// if a step fails.
+	RollbackOnFailure bool `yaml:"rollback_on_failure"`
+
+	Steps []PlanStep `yaml:"steps"`
+}
+
+// PlanStep is a single step of a Plan.
+type PlanStep struct {
+	Name string `yaml:"name"`
+
+	// Type is migrate (default), elastic or http, like the up, elastic-up
+	// and http-up commands.
+	Type string `yaml:"type"`
+
+	// Source is the source URL of a migrate step. Path is a shorthand for
+	// a file:// source, and the directory of elastic and http steps.
+	Source string `yaml:"source"`
+	Path   string `yaml:"path"`
+
+	Database      string   `yaml:"database"`
+	Index         string   `yaml:"index"`
+	ExcludeHeader string   `yaml:"exclude_header"`
+	SkipError     bool     `yaml:"skip_error"`
+	Debug         bool     `yaml:"debug"`
+	Tags          []string `yaml:"tags"`
+	ExcludeTags   []string `yaml:"exclude_tags"`
+
+	// DependsOn names the steps that must be applied before this step.
+	DependsOn []string `yaml:"depends_on"`
+}
+
+// envRe matches the references to environment variables expanded in plans.
+var envRe = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// readPlan reads a plan from a YAML file. Environment variables like
+// ${DATABASE_URL} are expanded, so credentials can be kept out of the file.
+// Other $ signs, e.g. in passwords, are kept.
+func readPlan(filename string) (*Plan, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parsePlan([]byte(expandEnv(string(content))))
+}
+
+// expandEnv replaces ${VAR} in s by the value of the environment variable VAR.
+func expandEnv(s string) string {
+	return envRe.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(envRe.FindStringSubmatch(ref)[1])
+	})
+}
+
+func parsePlan(content []byte) (*Plan, error) {
+	var p Plan
+	if err := yaml.Unmarshal(content, &p); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		if step.Type == "" {
+			step.Type = stepMigrate
+		}
+		if step.Type == stepMigrate && step.Source == "" && step.Path != "" {
+			step.Source = "file://" + step.Path
+		}
+	}
+	return &p, p.validate()
+}
+
+func (p *Plan) validate() error {
+	if len(p.Steps) == 0 {
+		return errPlanNoSteps
+	}
+	names := make(map[string]bool, len(p.Steps))
+	for _, step := range p.Steps {
+		if step.Name == "" {
+			return errors.New("plan step without name")
+		}
+		if names[step.Name] {
+			return fmt.Errorf("duplicate plan step %q", step.Name)
+		}
+		names[step.Name] = true
+
+		switch step.Type {
+		case stepMigrate:
+			if step.Source == "" {
+				return fmt.Errorf("plan step %q requires source or path", step.Name)
+			}
+		case stepElastic, stepHTTP:
+			if step.Path == "" {
+				return fmt.Errorf("plan step %q requires path", step.Name)
+			}
+		default:
+			return fmt.Errorf("plan step %q has unknown type %q, must be %v, %v or %v", step.Name, step.Type, stepMigrate, stepElastic, stepHTTP)
+		}
+		if step.Database == "" {
+			return fmt.Errorf("plan step %q requires database", step.Name)
+		}
+	}
+	for _, step := range p.Steps {
+		for _, dep := range step.DependsOn {
+			if !names[dep] {
+				return fmt.Errorf("plan step %q depends on unknown step %q", step.Name, dep)
+			}
+		}
+	}
+	return nil
+}
+
+// ordered returns the steps in the order they are applied: the declared order,
+// except that a step is moved after the steps it depends on.
+func (p *Plan) ordered() ([]PlanStep, error) {
+	done := make(map[string]bool, len(p.Steps))
+	ordered := make([]PlanStep, 0, len(p.Steps))
+	for len(ordered) < len(p.Steps) {
+		progress := false
+		for _, step := range p.Steps {
+			if done[step.Name] || !dependenciesDone(step, done) {
+				continue
+			}
+			ordered = append(ordered, step)
+			done[step.Name] = true
+			progress = true
+			break
+		}
+		if !progress {
+			return nil, errPlanCycle
+		}
+	}
+	return ordered, nil
+}
+
+func dependenciesDone(step PlanStep, done map[string]bool) bool {
+	for _, dep := range step.DependsOn {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// applyPlan applies the steps of p in order with run. run returns a func that
+// reverts the step, or nil if there is nothing to revert. If a step fails and
+// p.RollbackOnFailure is set, the completed steps are reverted in reverse order.
+func applyPlan(p *Plan, run func(step PlanStep) (rollback func() error, err error)) error {
+	steps, err := p.ordered()
+	if err != nil {
+		return err
+	}
+
+	type completed struct {
+		name     string
+		rollback func() error
+	}
+	var done []completed
+	for _, step := range steps {
+		log.Printf("Applying step %v (%v)\n", step.Name, step.Type)
+		rollback, err := run(step)
+		if err == nil {
+			done = append(done, completed{name: step.Name, rollback: rollback})
+			continue
+		}
+
+		err = fmt.Errorf("step %v: %w", step.Name, err)
+		if !p.RollbackOnFailure {
+			return err
+		}
+		var rollbackErrs []string
+		for i := len(done) - 1; i >= 0; i-- {
+			if done[i].rollback == nil {
+				continue
+			}
+			log.Printf("Rolling back step %v\n", done[i].name)
+			if errRollback := done[i].rollback(); errRollback != nil {
+				rollbackErrs = append(rollbackErrs, fmt.Sprintf("step %v: %v", done[i].name, errRollback))
+			}
+		}
+		if len(rollbackErrs) > 0 {
+			return fmt.Errorf("%w (rollback failed: %v)", err, strings.Join(rollbackErrs, "; "))
+		}
+		return err
+	}
+	return nil
+}
+
+// runPlanStep applies step like the up, elastic-up or http-up command.
+func runPlanStep(step PlanStep, configure func(m *migrate.Migrate)) (rollback func() error, err error) {
+	switch step.Type {
+	case stepElastic:
+		applied, err := seedUpElastic(step.Database, step.Path, step.ExcludeHeader, step.Index, step.SkipError, step.Debug, step.Tags, step.ExcludeTags)
+		if err != nil || len(applied) == 0 {
+			return nil, err
+		}
+		return func() error {
+			return seedDownElastic(step.Database, step.Path, step.ExcludeHeader, step.Index, step.SkipError, step.Debug, applied)
+		}, nil
+	case stepHTTP:
+		applied, err := seedUpHttp(step.Database, step.Path, step.ExcludeHeader, step.SkipError, step.Debug, step.Tags, step.ExcludeTags)
+		if err != nil || len(applied) == 0 {
+			return nil, err
+		}
+		return func() error {
+			return seedDownHttp(step.Database, step.Path, step.ExcludeHeader, step.SkipError, step.Debug, applied)
+		}, nil
+	}
+
+	m, err := migrate.New(step.Source, step.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, errClose := m.Close(); errClose != nil {
+			log.Println(errClose)
+		}
+	}()
+	configure(m)
+	m.WithTags(step.Tags, step.ExcludeTags)
+
+	prevVersion, _, err := m.Version()
+	hasVersion := err == nil
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, err
+	}
+
+	if err := m.Up(); err != nil {
+		if errors.Is(err, migrate.ErrNoChange) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return func() error {
+		m, err := migrate.New(step.Source, step.Database)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if _, errClose := m.Close(); errClose != nil {
+				log.Println(errClose)
+			}
+		}()
+		configure(m)
+		m.WithTags(step.Tags, step.ExcludeTags)
+		if !hasVersion {
+			return m.Down()
+		}
+		return m.Migrate(prevVersion)
+	}, nil
+}
+
+func applyCmd(planFile string, configure func(m *migrate.Migrate)) error {
+	p, err := readPlan(planFile)
+	if err != nil {
+		return err
+	}
+	return applyPlan(p, func(step PlanStep) (func() error, error) {
+		return runPlanStep(step, configure)
+	})
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-migrate/migrate/v4/tracing"
+)
+
+// newRestyClient returns a resty client logging to log, so that secrets are
+// masked in its -debug dumps.
+func newRestyClient() *resty.Client {
+	return resty.New().SetLogger(restyLogger{})
+}
+
+// restyLogger implements resty.Logger.
+type restyLogger struct{}
+
+func (restyLogger) Errorf(format string, v ...interface{}) {
+	log.Error(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (restyLogger) Warnf(format string, v ...interface{}) {
+	log.Warn(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (restyLogger) Debugf(format string, v ...interface{}) {
+	log.Printf(format, v...)
+}
+
+// sendRequest sends req of a seeder, e.g. elastic or http, counts it in the
+// metrics and traces it, passing the trace context on in the traceparent
+// header.
+func sendRequest(seeder string, req *resty.Request) (*resty.Response, error) {
+	var span *tracing.Span
+	if tracer != nil {
+		span = tracer.Start("HTTP " + req.Method)
+		span.Kind = tracing.KindClient
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.url", log.redact(req.URL))
+		span.SetAttribute("migrate.seeder", seeder)
+		if req.Header == nil {
+			req.Header = http.Header{}
+		}
+		tracer.Inject(span, req.Header)
+	}
+
+	resp, err := req.Send()
+
+	statusCode := 0
+	if resp != nil {
+		statusCode = resp.StatusCode()
+	}
+	if collector != nil {
+		collector.RequestSent(seeder, statusCode, err)
+	}
+	if span != nil {
+		spanErr := err
+		if statusCode > 0 {
+			span.SetAttribute("http.status_code", statusCode)
+			if statusCode >= 400 && spanErr == nil {
+				spanErr = fmt.Errorf("HTTP status %d", statusCode)
+			}
+		}
+		tracer.End(span, spanErr)
+	}
+	return resp, err
+}
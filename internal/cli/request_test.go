@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-migrate/migrate/v4/internal/redact"
+	"github.com/golang-migrate/migrate/v4/metrics"
+	"github.com/golang-migrate/migrate/v4/tracing"
+)
+
+func TestSendRequest(t *testing.T) {
+	var traceparent string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	var spans bytes.Buffer
+	collector = metrics.New()
+	tracer = tracing.New("migrate", &tracing.FileExporter{W: &spans})
+	defer func() {
+		collector = nil
+		tracer = nil
+	}()
+
+	req := resty.New().R()
+	req.Method = http.MethodGet
+	req.URL = ts.URL
+	if _, err := sendRequest("http", req); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if _, err := collector.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `migrate_seed_requests_total{seeder="http",status="404"} 1`; !strings.Contains(b.String(), expected) {
+		t.Errorf("Metrics are missing %q:\n%v", expected, b.String())
+	}
+
+	if err := tracer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"name":"HTTP GET","kind":3`,
+		`{"key":"http.status_code","value":{"intValue":"404"}}`,
+		`"status":{"code":2,"message":"HTTP status 404"}`,
+	} {
+		if !strings.Contains(spans.String(), expected) {
+			t.Errorf("Spans are missing %q:\n%v", expected, spans.String())
+		}
+	}
+	if len(traceparent) != 55 || !strings.Contains(spans.String(), traceparent[36:52]) {
+		t.Errorf("Incorrect traceparent header was: %q", traceparent)
+	}
+}
+
+func TestRestyDebugRedact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "session=abc123")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	redactor := log.redactor
+	log.redactor = redact.New()
+	log.redactor.AddSecret("body-s3cr3t")
+	defer func() { log.redactor = redactor }()
+
+	out := captureStderr(t, func() {
+		req := newRestyClient().SetDebug(true).R().
+			SetHeader("Authorization", "Token t0ken").
+			SetBody(`{"password":"body-s3cr3t"}`)
+		req.Method = http.MethodPost
+		req.URL = ts.URL
+		if _, err := req.Send(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "Authorization: xxxxx") {
+		t.Errorf("Debug dump is missing the masked header:\n%v", out)
+	}
+	for _, secret := range []string{"t0ken", "body-s3cr3t", "abc123"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Debug dump contains %q:\n%v", secret, out)
+		}
+	}
+}
+
+func TestSeedUpElasticRedact(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid password body-s3cr3t"}`))
+	}))
+	defer ts.Close()
+
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "1_users.up.json"), []byte(`[{"method":"PUT","path":"users"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	redactor := log.redactor
+	log.redactor = redact.New()
+	log.redactor.AddSecret("body-s3cr3t")
+	defer func() { log.redactor = redactor }()
+
+	out := captureStderr(t, func() {
+		if _, err := seedUpElastic(ts.URL, dir, "", "users", true, false, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "invalid password xxxxx") || strings.Contains(out, "body-s3cr3t") {
+		t.Errorf("Incorrect output was:\n%v", out)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/safety"
+	iurl "github.com/golang-migrate/migrate/v4/internal/url"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+var (
+	errSafetyCheck             = errors.New("safety check failed, suppress intended findings with a -- migrate:safety-ignore directive")
+	errSafetyCheckNotSupported = errors.New("-safety-check supports postgres and pgx databases only")
+)
+
+// safetyCheckCmd (meant to be called via a CLI command) checks the next limit,
+// or all if limit is negative, pending up migrations for dangerous statements
+// before they are run, see database/safety.
+func safetyCheckCmd(m *migrate.Migrate, sourceURL string, databaseURL string, limit int) error {
+	scheme, err := iurl.SchemeFromURL(databaseURL)
+	if err != nil {
+		return err
+	}
+	switch scheme {
+	case "postgres", "postgresql", "pgx":
+	default:
+		return errSafetyCheckNotSupported
+	}
+
+	n, err := checkPendingSafety(m, sourceURL, limit)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return errSafetyCheck
+	}
+	return nil
+}
+
+// checkPendingSafety logs the findings of the pending up migrations of m and
+// returns their number.
+func checkPendingSafety(m *migrate.Migrate, sourceURL string, limit int) (n int, err error) {
+	curVersion, _, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return 0, err
+	}
+	pending := err == migrate.ErrNilVersion
+
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if errClose := src.Close(); err == nil {
+			err = errClose
+		}
+	}()
+
+	var version uint
+	if pending {
+		version, err = src.First()
+	} else {
+		version, err = src.Next(curVersion)
+	}
+	for checked := 0; err == nil && (limit < 0 || checked < limit); checked++ {
+		r, identifier, errRead := src.ReadUp(version)
+		if errRead == nil {
+			findings, errCheck := safety.Check(r)
+			if errClose := r.Close(); errCheck == nil {
+				errCheck = errClose
+			}
+			if errCheck != nil {
+				return n, fmt.Errorf("migration %v/u %s: %w", version, identifier, errCheck)
+			}
+			for _, f := range findings {
+				log.Warn(fmt.Sprintf("%v/u %s: %s", version, identifier, f), "version", version, "identifier", identifier, "line", f.Line, "rule", f.Rule)
+			}
+			n += len(findings)
+		} else if !errors.Is(errRead, os.ErrNotExist) {
+			return n, errRead
+		}
+		version, err = src.Next(version)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return n, err
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+)
+
+func TestCheckPendingSafety(t *testing.T) {
+	dir := t.TempDir()
+	for f, content := range map[string]string{
+		"1_users.up.sql":   "CREATE TABLE users (id int);\nCREATE INDEX users_id ON users (id);",
+		"1_users.down.sql": "DROP TABLE users;",
+		"2_email.up.sql":   "ALTER TABLE users ADD COLUMN email text;\nCREATE INDEX users_email ON users (email);",
+		"3_name.down.sql":  "DROP INDEX users_name;",
+		"4_drop.up.sql":    "-- migrate:safety-ignore=drop-column\nALTER TABLE users DROP COLUMN email;\nALTER TABLE users ALTER COLUMN id SET NOT NULL;",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := dStub.WithInstance(nil, &dStub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "stub", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		version  int
+		limit    int
+		expected int
+	}{
+		{version: -1, limit: -1, expected: 2},
+		{version: -1, limit: 1, expected: 0},
+		{version: 1, limit: 1, expected: 1},
+		{version: 2, limit: -1, expected: 1},
+		{version: 4, limit: -1, expected: 0},
+	} {
+		if err := m.Force(tc.version); err != nil {
+			t.Fatal(err)
+		}
+		n, err := checkPendingSafety(m, "file://"+dir, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != tc.expected {
+			t.Errorf("Incorrect findings at version %v with limit %v were: %v wanted %v", tc.version, tc.limit, n, tc.expected)
+		}
+	}
+}
+
+func TestSafetyCheckCmdNotSupported(t *testing.T) {
+	if err := safetyCheckCmd(nil, "file://migrations", "stub://", -1); err != errSafetyCheckNotSupported {
+		t.Errorf("Incorrect error was: %v wanted %v", err, errSafetyCheckNotSupported)
+	}
+}
@@ -0,0 +1,516 @@
+package cli
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+var (
+	errServeNoToken = errors.New("serve requires a bearer token, use -token or MIGRATE_SERVE_TOKEN")
+	errJobRunning   = errors.New("a migration job is already running")
+)
+
+// Job states.
+const (
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// maxJobs is the number of jobs kept in the history, older jobs are dropped.
+const maxJobs = 100
+
+// job is a migration started through the HTTP API. Its log lines are
+// streamed to clients while it runs.
+type job struct {
+	ID       string     `json:"id"`
+	Command  string     `json:"command"`
+	Status   string     `json:"status"`
+	Error    string     `json:"error,omitempty"`
+	Started  time.Time  `json:"started"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Log      []string   `json:"log"`
+
+	mu sync.Mutex
+	// changed is closed and replaced whenever a line is logged or the job
+	// finishes.
+	changed chan struct{}
+}
+
+// Printf implements migrate.Logger.
+func (j *job) Printf(format string, v ...interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.Log = append(j.Log, log.redact(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")))
+	j.notify()
+}
+
+// Verbose implements migrate.Logger.
+func (j *job) Verbose() bool {
+	return false
+}
+
+func (j *job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.Finished = &now
+	switch {
+	case err == nil || err == migrate.ErrNoChange:
+		j.Status = jobSucceeded
+		if err != nil {
+			j.Log = append(j.Log, err.Error())
+		}
+	default:
+		j.Status = jobFailed
+		j.Error = log.redact(err.Error())
+	}
+	j.notify()
+}
+
+// notify wakes up the clients waiting for changes, j.mu must be held.
+func (j *job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns a copy of j, safe to encode while the job runs.
+func (j *job) snapshot() *job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &job{
+		ID:       j.ID,
+		Command:  j.Command,
+		Status:   j.Status,
+		Error:    j.Error,
+		Started:  j.Started,
+		Finished: j.Finished,
+		Log:      append([]string{}, j.Log...),
+	}
+}
+
+// versionState is the result of migrate.Migrate.Version.
+type versionState struct {
+	version uint
+	dirty   bool
+	err     error
+}
+
+// server serves the HTTP API of the serve command. It runs one job at a time,
+// as a migrate.Migrate instance can't run migrations concurrently.
+type server struct {
+	m     *migrate.Migrate
+	token string
+	mux   *http.ServeMux
+
+	mu      sync.Mutex
+	jobs    []*job
+	running *job
+	// last is the version read before the running job started.
+	last versionState
+}
+
+func newServer(m *migrate.Migrate, token string) *server {
+	s := &server{m: m, token: token, mux: http.NewServeMux()}
+	s.mux.HandleFunc("/status", s.handleStatus)
+	s.mux.HandleFunc("/plan", s.handlePlan)
+	s.mux.HandleFunc("/lock", s.handleLock)
+	s.mux.HandleFunc("/history", s.handleHistory)
+	s.mux.HandleFunc("/jobs/", s.handleJob)
+	for _, command := range []string{"up", "down", "goto"} {
+		s.mux.HandleFunc("/"+command, s.handleRun)
+	}
+	return s
+}
+
+// ServeHTTP implements http.Handler, it rejects requests without the bearer token.
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(s.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, errors.New("invalid bearer token"))
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// allowMethod reports whether r uses method and writes an error otherwise.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return false
+	}
+	return true
+}
+
+type status struct {
+	// Version is nil if no migration has been applied.
+	Version *uint `json:"version"`
+	Dirty   bool  `json:"dirty"`
+}
+
+// version returns the version of the database. The database connection is
+// busy while a job runs, so the version read before the job started is
+// returned then.
+func (s *server) version() (uint, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running == nil {
+		s.last.version, s.last.dirty, s.last.err = s.m.Version()
+	}
+	return s.last.version, s.last.dirty, s.last.err
+}
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	var st status
+	version, dirty, err := s.version()
+	switch {
+	case err == migrate.ErrNilVersion:
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	default:
+		st.Version, st.Dirty = &version, dirty
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *server) handleLock(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock := struct {
+		Locked bool   `json:"locked"`
+		Job    string `json:"job,omitempty"`
+	}{}
+	if s.running != nil {
+		lock.Locked, lock.Job = true, s.running.ID
+	}
+	writeJSON(w, http.StatusOK, lock)
+}
+
+func (s *server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.mu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		jobs = append(jobs, s.jobs[i].snapshot())
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// runRequest is an up, down or goto request read from the path and the
+// query parameters n and version.
+type runRequest struct {
+	command string
+	// limit is the number of migrations of up and down, -1 for all.
+	limit   int
+	version uint
+}
+
+func parseRunRequest(r *http.Request) (runRequest, error) {
+	req := runRequest{command: strings.TrimPrefix(r.URL.Path, "/"), limit: -1}
+	if r.URL.Path == "/plan" {
+		req.command = r.URL.Query().Get("command")
+		if req.command == "" {
+			req.command = "up"
+		}
+	}
+
+	q := r.URL.Query()
+	switch req.command {
+	case "up", "down":
+		if n := q.Get("n"); n != "" {
+			limit, err := strconv.ParseUint(n, 10, 64)
+			if err != nil {
+				return req, fmt.Errorf("can't read n: %w", err)
+			}
+			req.limit = int(limit)
+		} else if req.command == "down" && q.Get("all") != "true" {
+			return req, errors.New("down requires n or all=true")
+		}
+	case "goto":
+		version, err := strconv.ParseUint(q.Get("version"), 10, 64)
+		if err != nil {
+			return req, fmt.Errorf("can't read version: %w", err)
+		}
+		req.version = uint(version)
+	default:
+		return req, fmt.Errorf("unknown command %q", req.command)
+	}
+	return req, nil
+}
+
+func (req runRequest) String() string {
+	switch {
+	case req.command == "goto":
+		return fmt.Sprintf("goto %v", req.version)
+	case req.limit >= 0:
+		return fmt.Sprintf("%s %v", req.command, req.limit)
+	default:
+		return req.command
+	}
+}
+
+func (req runRequest) run(m *migrate.Migrate) error {
+	switch {
+	case req.command == "goto":
+		return m.Migrate(req.version)
+	case req.command == "up" && req.limit >= 0:
+		return m.Steps(req.limit)
+	case req.command == "up":
+		return m.Up()
+	case req.limit >= 0:
+		return m.Steps(-req.limit)
+	default:
+		return m.Down()
+	}
+}
+
+// plan returns the migrations run would run against the database of m.
+func (req runRequest) plan(m *migrate.Migrate) ([]migrate.PlannedMigration, error) {
+	switch req.command {
+	case "goto":
+		return m.PlanMigrate(req.version)
+	case "up":
+		return m.PlanUp(req.limit)
+	default:
+		return m.PlanDown(req.limit)
+	}
+}
+
+func (s *server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodPost) {
+		return
+	}
+	req, err := parseRunRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := s.start(req)
+	if err == errJobRunning {
+		writeError(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/jobs/"+j.ID)
+	writeJSON(w, http.StatusAccepted, j.snapshot())
+}
+
+// start runs req in the background, unless another job is running.
+func (s *server) start(req runRequest) (*job, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running != nil {
+		return nil, errJobRunning
+	}
+	s.last.version, s.last.dirty, s.last.err = s.m.Version()
+	j := &job{
+		ID:      hex.EncodeToString(id),
+		Command: req.String(),
+		Status:  jobRunning,
+		Started: time.Now(),
+		Log:     []string{},
+		changed: make(chan struct{}),
+	}
+	s.jobs = append(s.jobs, j)
+	if len(s.jobs) > maxJobs {
+		s.jobs = append(s.jobs[:0], s.jobs[len(s.jobs)-maxJobs:]...)
+	}
+	s.running = j
+
+	// the logger of m is only swapped while s.mu is held
+	logger := s.m.Log
+	s.m.Log = j
+	go func() {
+		err := req.run(s.m)
+
+		s.mu.Lock()
+		s.m.Log = logger
+		s.running = nil
+		s.mu.Unlock()
+		j.finish(err)
+	}()
+	return j, nil
+}
+
+func (s *server) findJob(id string) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// handleJob serves /jobs/ID and the server-sent events of /jobs/ID/events.
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/jobs/")
+	events := strings.HasSuffix(id, "/events")
+	j := s.findJob(strings.TrimSuffix(id, "/events"))
+	if j == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %q not found", id))
+		return
+	}
+	if events {
+		streamJob(w, r, j)
+		return
+	}
+	writeJSON(w, http.StatusOK, j.snapshot())
+}
+
+// streamJob sends the log lines of j as "log" events and the finished job as
+// "done" event.
+func streamJob(w http.ResponseWriter, r *http.Request, j *job) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	sent := 0
+	for {
+		j.mu.Lock()
+		lines := append([]string{}, j.Log[sent:]...)
+		finished := j.Finished != nil
+		changed := j.changed
+		j.mu.Unlock()
+
+		for _, line := range lines {
+			writeEvent(w, "log", line)
+		}
+		sent += len(lines)
+		if finished {
+			done, err := json.Marshal(j.snapshot())
+			if err != nil {
+				return
+			}
+			writeEvent(w, "done", string(done))
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes a server-sent event, with one data field per line of data.
+func writeEvent(w io.Writer, event string, data string) {
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+	fmt.Fprintf(w, "event: %s\n", event)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// planStep is a migration that a command would run.
+type planStep struct {
+	Namespace  string `json:"namespace,omitempty"`
+	Version    uint   `json:"version"`
+	Direction  string `json:"direction"`
+	Identifier string `json:"identifier"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	Repeatable bool   `json:"repeatable,omitempty"`
+}
+
+func (s *server) handlePlan(w http.ResponseWriter, r *http.Request) {
+	if !allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	req, err := parseRunRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the plan reads the database, which is busy while a job runs
+	s.mu.Lock()
+	if s.running != nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, errJobRunning)
+		return
+	}
+	planned, err := req.plan(s.m)
+	s.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	steps := make([]planStep, 0, len(planned))
+	for _, p := range planned {
+		steps = append(steps, planStep{
+			Namespace:  p.Namespace,
+			Version:    p.Version,
+			Direction:  string(p.Direction),
+			Identifier: p.Identifier,
+			Skipped:    p.Skipped,
+			Repeatable: p.Repeatable,
+		})
+	}
+	writeJSON(w, http.StatusOK, steps)
+}
+
+// serveCmd (meant to be called via a CLI command) serves the HTTP API of m on
+// the address listen, see newServer.
+func serveCmd(m *migrate.Migrate, listen string, token string) error {
+	if token == "" {
+		return errServeNoToken
+	}
+	log.Info("Listening on "+listen, "listen", listen)
+	return http.ListenAndServe(listen, newServer(m, token))
+}
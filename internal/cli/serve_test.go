@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+)
+
+func newTestServer(t *testing.T) (*server, *httptest.Server) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range []string{
+		"1_users.up.sql", "1_users.down.sql",
+		"2_orders.up.sql", "2_orders.down.sql",
+		"3_items.up.sql", "3_items.down.sql",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d, err := dStub.WithInstance(nil, &dStub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "stub", d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newServer(m, "secret")
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+// request sends a request with the bearer token and returns the status code
+// and the body.
+func request(t *testing.T, ts *httptest.Server, method string, path string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+// runJob starts a job and waits for it by reading its events.
+func runJob(t *testing.T, ts *httptest.Server, path string) (events string, done *job) {
+	t.Helper()
+	code, body := request(t, ts, http.MethodPost, path)
+	if code != http.StatusAccepted {
+		t.Fatalf("Incorrect status of %v was: %v (%s)", path, code, body)
+	}
+	var started job
+	if err := json.Unmarshal([]byte(body), &started); err != nil {
+		t.Fatal(err)
+	}
+	_, events = request(t, ts, http.MethodGet, "/jobs/"+started.ID+"/events")
+	i := strings.Index(events, "event: done\ndata: ")
+	if i < 0 {
+		t.Fatalf("Missing done event in %q", events)
+	}
+	if err := json.Unmarshal([]byte(events[i+len("event: done\ndata: "):]), &done); err != nil {
+		t.Fatal(err)
+	}
+	return events, done
+}
+
+func TestServeAuth(t *testing.T) {
+	_, ts := newTestServer(t)
+	for _, auth := range []string{"", "Bearer wrong", "secret"} {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/status", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Incorrect status with authorization %q was: %v", auth, resp.StatusCode)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	s, ts := newTestServer(t)
+
+	for _, tc := range []struct {
+		method   string
+		path     string
+		code     int
+		expected string
+	}{
+		{method: http.MethodGet, path: "/status", code: http.StatusOK, expected: `{"version":null,"dirty":false}`},
+		{method: http.MethodGet, path: "/plan", code: http.StatusOK, expected: `[{"version":1,"direction":"up","identifier":"users"},{"version":2,"direction":"up","identifier":"orders"},{"version":3,"direction":"up","identifier":"items"}]`},
+		{method: http.MethodGet, path: "/plan?command=goto&version=1", code: http.StatusOK, expected: `[{"version":1,"direction":"up","identifier":"users"}]`},
+		{method: http.MethodGet, path: "/plan?command=down&all=true", code: http.StatusOK, expected: `[]`},
+		{method: http.MethodGet, path: "/up", code: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/down", code: http.StatusBadRequest, expected: `{"error":"down requires n or all=true"}`},
+		{method: http.MethodPost, path: "/goto?version=x", code: http.StatusBadRequest},
+		{method: http.MethodGet, path: "/jobs/unknown", code: http.StatusNotFound},
+		{method: http.MethodGet, path: "/lock", code: http.StatusOK, expected: `{"locked":false}`},
+	} {
+		code, body := request(t, ts, tc.method, tc.path)
+		if code != tc.code || (tc.expected != "" && strings.TrimSpace(body) != tc.expected) {
+			t.Errorf("Incorrect response of %v %v was: %v %s wanted %v %s", tc.method, tc.path, code, body, tc.code, tc.expected)
+		}
+	}
+
+	events, done := runJob(t, ts, "/up?n=2")
+	if done.Status != jobSucceeded || done.Command != "up 2" || len(done.Log) != 2 || !strings.Contains(events, "event: log\ndata: 1/u users") {
+		t.Errorf("Incorrect job %+v with events:\n%s", done.snapshot(), events)
+	}
+	if _, body := request(t, ts, http.MethodGet, "/status"); strings.TrimSpace(body) != `{"version":2,"dirty":false}` {
+		t.Errorf("Incorrect status after up was: %s", body)
+	}
+	if _, body := request(t, ts, http.MethodGet, "/plan?command=down&n=1"); strings.TrimSpace(body) != `[{"version":2,"direction":"down","identifier":"orders"}]` {
+		t.Errorf("Incorrect down plan was: %s", body)
+	}
+
+	if _, done := runJob(t, ts, "/goto?version=9"); done.Status != jobFailed || done.Error == "" {
+		t.Errorf("Incorrect failed job: %+v", done)
+	}
+	if _, done := runJob(t, ts, "/down?all=true"); done.Status != jobSucceeded {
+		t.Errorf("Incorrect down job: %+v", done)
+	}
+
+	var history []*job
+	_, body := request(t, ts, http.MethodGet, "/history")
+	if err := json.Unmarshal([]byte(body), &history); err != nil {
+		t.Fatal(err)
+	}
+	var commands []string
+	for _, j := range history {
+		commands = append(commands, j.Command)
+	}
+	if expected := []string{"down", "goto 9", "up 2"}; !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Incorrect history was: %v wanted %v", commands, expected)
+	}
+	if code, body := request(t, ts, http.MethodGet, "/jobs/"+history[2].ID); code != http.StatusOK || !strings.Contains(body, `"status":"succeeded"`) {
+		t.Errorf("Incorrect job was: %v %s", code, body)
+	}
+
+	s.mu.Lock()
+	s.running = &job{ID: "busy"}
+	s.last = versionState{version: 7}
+	s.mu.Unlock()
+	if _, body := request(t, ts, http.MethodGet, "/status"); strings.TrimSpace(body) != `{"version":7,"dirty":false}` {
+		t.Errorf("Incorrect status while a job runs was: %s", body)
+	}
+	if code, _ := request(t, ts, http.MethodGet, "/plan"); code != http.StatusConflict {
+		t.Errorf("Incorrect status of a plan while a job runs was: %v wanted %v", code, http.StatusConflict)
+	}
+	if code, _ := request(t, ts, http.MethodPost, "/up"); code != http.StatusConflict {
+		t.Errorf("Incorrect status of a second job was: %v wanted %v", code, http.StatusConflict)
+	}
+	if _, body := request(t, ts, http.MethodGet, "/lock"); strings.TrimSpace(body) != `{"locked":true,"job":"busy"}` {
+		t.Errorf("Incorrect lock was: %s", body)
+	}
+}
+
+func TestServeHistoryLimit(t *testing.T) {
+	s, ts := newTestServer(t)
+	for i := 0; i < maxJobs; i++ {
+		s.jobs = append(s.jobs, &job{ID: strconv.Itoa(i), Status: jobSucceeded})
+	}
+	runJob(t, ts, "/up?n=1")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.jobs) != maxJobs || s.jobs[0].ID != "1" || s.jobs[maxJobs-1].Command != "up 1" {
+		t.Errorf("Incorrect history: %v jobs from %v to %v", len(s.jobs), s.jobs[0].ID, s.jobs[len(s.jobs)-1].Command)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	var b strings.Builder
+	writeEvent(&b, "log", "first\nsecond\r\nthird")
+	if expected := "event: log\ndata: first\ndata: second\ndata: third\n\n"; b.String() != expected {
+		t.Errorf("Incorrect event was: %q wanted %q", b.String(), expected)
+	}
+}
+
+func TestServeCmdNoToken(t *testing.T) {
+	if err := serveCmd(nil, ":0", ""); err != errServeNoToken {
+		t.Errorf("Incorrect error was: %v wanted %v", err, errServeNoToken)
+	}
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+var (
+	errSquashNoPath = errors.New("squash requires the migrations directory, use -path or a file:// -source")
+	errSquashEmpty  = errors.New("no migrations to squash")
+	errSquashNoDown = errors.New("squash requires a database that can inspect its schema to write the down migration, like postgres and sqlite3")
+)
+
+// rename moves files, it is replaced in tests.
+var rename = os.Rename
+
+// squashCmd (meant to be called via a CLI command) replaces the migrations up to
+// version to in dir by a single migration holding the schema of the database
+// at that version. The up migration creates the schema, the down migration drops
+// it. The squashed migration is named for parser, the name of the parser of the
+// source, see source.Parsers. The database of m must be empty, it is migrated to
+// version to, or already be at version to. The original migrations are moved to
+// archiveDir, by default the directory dir with the suffix "_archive".
+//
+// Databases at version to or later never run the squashed migration. Databases
+// before version to have to be migrated to it with the original migrations first,
+// databases that have the schema, but were never migrated, have to be baselined
+// at version to, see migrate.Migrate.Baseline.
+func squashCmd(m *migrate.Migrate, dir string, parser string, archiveDir string, to uint, startTime time.Time) error {
+	if archiveDir == "" {
+		archiveDir = filepath.Clean(dir) + "_archive"
+	}
+
+	parse, err := source.ParserByName(parser)
+	if err != nil {
+		return err
+	}
+	files, target, err := squashFiles(dir, to, parse)
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	switch {
+	case errors.Is(err, migrate.ErrNilVersion):
+		if _, err := m.InspectSchema(); errors.Is(err, migrate.ErrSchemaInspectNotSupported) {
+			return errSquashNoDown
+		}
+		if err := m.Migrate(to); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	case dirty:
+		return migrate.ErrDirty{Version: int(version)}
+	case version != to:
+		return fmt.Errorf("database is at version %v, squash needs an empty database or one at version %v", version, to)
+	}
+
+	var up bytes.Buffer
+	if err := m.DumpSchema(&up); err != nil {
+		return err
+	}
+	down, err := squashDown(m)
+	if err != nil {
+		return err
+	}
+
+	header := fmt.Sprintf("-- Schema after migration %v, squashed from %d migration files on %s.\n"+
+		"-- The original migrations are archived in %s.\n\n",
+		to, len(files), startTime.UTC().Format("2006-01-02"), archiveDir)
+	squashed := squashedFiles(parser, target, []byte(header), up.Bytes(), down)
+	for _, f := range squashed {
+		if _, err := os.Stat(filepath.Join(dir, f.name)); err == nil {
+			return fmt.Errorf("squashed migration %s already exists", filepath.Join(dir, f.name))
+		}
+	}
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(archiveDir, f)); err == nil {
+			return fmt.Errorf("migration %s is already archived in %s", f, archiveDir)
+		}
+	}
+
+	// the squashed migration is written to temporary files first, which
+	// don't match the migration file names, and takes the place of the
+	// original migrations once they are archived
+	var tmpFiles []string
+	defer func() {
+		for _, tmp := range tmpFiles {
+			if errRemove := os.Remove(tmp); errRemove != nil && !os.IsNotExist(errRemove) {
+				log.Println(errRemove)
+			}
+		}
+	}()
+	for _, f := range squashed {
+		tmp := filepath.Join(dir, "."+f.name+".tmp")
+		if err := createFile(tmp, f.content); err != nil {
+			return err
+		}
+		tmpFiles = append(tmpFiles, tmp)
+	}
+	if err := archiveFiles(dir, archiveDir, files, func() error { return placeFiles(dir, squashed) }); err != nil {
+		return err
+	}
+
+	log.Printf("Squashed %d migration files into %s\n", len(files), filepath.Join(dir, squashed[0].name))
+	return nil
+}
+
+// squashDown returns the statements dropping the schema of the database of m.
+func squashDown(m *migrate.Migrate) ([]byte, error) {
+	schema, err := m.InspectSchema()
+	if errors.Is(err, migrate.ErrSchemaInspectNotSupported) {
+		return nil, errSquashNoDown
+	} else if err != nil {
+		return nil, err
+	}
+	statements, err := schemadiff.Diff(schema, &schemadiff.Schema{Dialect: schema.Dialect})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(statements, "\n\n") + "\n"), nil
+}
+
+// squashedFile is a file of the squashed migration.
+type squashedFile struct {
+	name    string
+	content []byte
+}
+
+// squashedFiles returns the files of the squashed migration named like target,
+// the last squashed migration file, for parser. They keep the version string of
+// target, so sequential versions keep their padding.
+func squashedFiles(parser string, target string, header []byte, up []byte, down []byte) []squashedFile {
+	ext := filepath.Ext(target)
+	section := func(upMarker string, downMarker string) []squashedFile {
+		content := append(append([]byte{}, header...), upMarker+"\n"...)
+		content = append(append(content, up...), "\n"+downMarker+"\n"...)
+		return []squashedFile{{
+			name:    target[:strings.Index(target, "_")] + "_squashed" + ext,
+			content: append(content, down...),
+		}}
+	}
+
+	switch parser {
+	case "flyway":
+		prefix := target[1:strings.Index(target, "__")]
+		return []squashedFile{
+			{name: "V" + prefix + "__squashed" + ext, content: append(append([]byte{}, header...), up...)},
+			{name: "U" + prefix + "__squashed" + ext, content: append(append([]byte{}, header...), down...)},
+		}
+	case "goose":
+		return section("-- +goose Up", "-- +goose Down")
+	case "dbmate":
+		return section("-- migrate:up", "-- migrate:down")
+	}
+
+	if m, err := source.Parse(target); err == nil && !m.SingleFile {
+		prefix := target[:strings.Index(target, "_")]
+		return []squashedFile{
+			{name: prefix + "_squashed.up" + ext, content: append(append([]byte{}, header...), up...)},
+			{name: prefix + "_squashed.down" + ext, content: append(append([]byte{}, header...), down...)},
+		}
+	}
+	return section("-- +migrate Up", "-- +migrate Down")
+}
+
+// placeFiles moves the temporary files of the squashed migration into place.
+// If moving a file fails, the files moved so far are moved back.
+func placeFiles(dir string, files []squashedFile) (err error) {
+	var placed []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for _, name := range placed {
+			if errRestore := rename(filepath.Join(dir, name), filepath.Join(dir, "."+name+".tmp")); errRestore != nil {
+				err = fmt.Errorf("%w (removing %s failed: %v)", err, name, errRestore)
+			}
+		}
+	}()
+	for _, f := range files {
+		if err := rename(filepath.Join(dir, "."+f.name+".tmp"), filepath.Join(dir, f.name)); err != nil {
+			return err
+		}
+		placed = append(placed, f.name)
+	}
+	return nil
+}
+
+// archiveFiles moves files from dir to archiveDir and calls done. If moving a
+// file or done fails, the files moved so far are moved back to dir.
+func archiveFiles(dir string, archiveDir string, files []string, done func() error) (err error) {
+	if err := os.MkdirAll(archiveDir, os.ModePerm); err != nil {
+		return err
+	}
+
+	var moved []string
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(moved) - 1; i >= 0; i-- {
+			if errRestore := rename(filepath.Join(archiveDir, moved[i]), filepath.Join(dir, moved[i])); errRestore != nil {
+				err = fmt.Errorf("%w (restoring %s failed: %v)", err, moved[i], errRestore)
+			}
+		}
+	}()
+	for _, f := range files {
+		if err := rename(filepath.Join(dir, f), filepath.Join(archiveDir, f)); err != nil {
+			return err
+		}
+		moved = append(moved, f)
+	}
+	return done()
+}
+
+// squashFiles returns the migration files in dir, read by parse, up to version
+// to, and the name of a file of version to.
+func squashFiles(dir string, to uint, parse source.Parser) (files []string, target string, err error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		mig, err := parse(e.Name())
+		if err != nil || mig.Repeatable || mig.Version > to {
+			continue
+		}
+		files = append(files, e.Name())
+		if mig.Version == to {
+			target = e.Name()
+		}
+	}
+	if len(files) == 0 {
+		return nil, "", errSquashEmpty
+	}
+	if target == "" {
+		return nil, "", fmt.Errorf("no migration file of version %v in %s", to, dir)
+	}
+	return files, target, nil
+}
+
+// squashDir returns the migrations directory given by path or a file:// source,
+// and the name of the parser set by the x-parser option of the source.
+func squashDir(path string, sourceURL string) (dir string, parser string, err error) {
+	if strings.HasPrefix(sourceURL, "file://") {
+		dir = strings.TrimPrefix(sourceURL, "file://")
+		if i := strings.Index(dir, "?"); i >= 0 {
+			query, err := url.ParseQuery(dir[i+1:])
+			if err != nil {
+				return "", "", err
+			}
+			dir, parser = dir[:i], query.Get("x-parser")
+		}
+	}
+	if path != "" {
+		dir = path
+	}
+	if dir == "" {
+		return "", "", errSquashNoPath
+	}
+	return dir, parser, nil
+}
@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+)
+
+func TestSquashCmd(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{
+		"001_users.up.sql", "001_users.down.sql",
+		"002_orders.up.sql", "002_orders.down.sql",
+		"003_items.up.sql", "003_items.down.sql",
+		"R_views.up.sql",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := dStub.WithInstance(nil, &dStub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.(*dStub.Stub).Schema = "CREATE TABLE users (id int);\n\n"
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "stub", &inspectingStub{
+		Stub: d.(*dStub.Stub),
+		schema: &schemadiff.Schema{
+			Dialect: schemadiff.SQLite,
+			Tables:  []schemadiff.Table{{Name: "users", Columns: []schemadiff.Column{{Name: "id", Type: "int"}}}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	startTime := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	if err := squashCmd(m, dir, "", "", 2, startTime); err != nil {
+		t.Fatal(err)
+	}
+
+	if version, _, err := m.Version(); err != nil || version != 2 {
+		t.Errorf("Incorrect version was: %v (%v) wanted 2", version, err)
+	}
+	expected := []string{"002_squashed.down.sql", "002_squashed.up.sql", "003_items.down.sql", "003_items.up.sql", "R_views.up.sql"}
+	if files := readDirNames(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Incorrect migrations was: %v wanted %v", files, expected)
+	}
+	expected = []string{"001_users.down.sql", "001_users.up.sql", "002_orders.down.sql", "002_orders.up.sql"}
+	if files := readDirNames(t, dir+"_archive"); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Incorrect archive was: %v wanted %v", files, expected)
+	}
+
+	squashed, err := ioutil.ReadFile(filepath.Join(dir, "002_squashed.up.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(squashed), "-- Schema after migration 2, squashed from 4 migration files on 2020-06-01.") ||
+		!strings.HasSuffix(string(squashed), "\n\nCREATE TABLE users (id int);\n\n") {
+		t.Errorf("Incorrect squashed migration was: %q", squashed)
+	}
+	squashed, err = ioutil.ReadFile(filepath.Join(dir, "002_squashed.down.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(squashed), "\n\nDROP TABLE \"users\";\n") {
+		t.Errorf("Incorrect squashed down migration was: %q", squashed)
+	}
+
+	// the database is past the squashed migrations now
+	if err := squashCmd(m, dir, "", "", 3, startTime); err == nil || !strings.Contains(err.Error(), "at version 2") {
+		t.Errorf("Incorrect error was: %v", err)
+	}
+}
+
+func TestSquashCmdRestores(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	files := []string{"001_users.down.sql", "001_users.up.sql", "002_orders.down.sql", "002_orders.up.sql"}
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := dStub.WithInstance(nil, &dStub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "stub", &inspectingStub{Stub: d.(*dStub.Stub)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errRename := errors.New("rename failed")
+	defer func() {
+		rename = os.Rename
+	}()
+
+	// the 3rd rename archives a migration, the 6th places the squashed down migration
+	for _, failing := range []int{3, 6} {
+		renamed := 0
+		rename = func(oldpath, newpath string) error {
+			if renamed++; renamed == failing {
+				return errRename
+			}
+			return os.Rename(oldpath, newpath)
+		}
+
+		if err := squashCmd(m, dir, "", "", 2, time.Now()); !errors.Is(err, errRename) {
+			t.Fatalf("Incorrect error was: %v wanted %v", err, errRename)
+		}
+		if names := readDirNames(t, dir); !reflect.DeepEqual(names, files) {
+			t.Errorf("Incorrect migrations after rename %d was: %v wanted %v", failing, names, files)
+		}
+		if names := readDirNames(t, dir+"_archive"); len(names) != 0 {
+			t.Errorf("Incorrect archive after rename %d was: %v wanted none", failing, names)
+		}
+	}
+}
+
+func TestSquashCmdParser(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"V1__users.sql", "U1__users.sql", "V2__orders.sql", "V3__items.sql", "R__views.sql"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("SELECT 1"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := dStub.WithInstance(nil, &dStub.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir+"?x-parser=flyway", "stub", &inspectingStub{Stub: d.(*dStub.Stub)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := squashCmd(m, dir, "flyway", "", 2, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"R__views.sql", "U2__squashed.sql", "V2__squashed.sql", "V3__items.sql"}
+	if files := readDirNames(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Incorrect migrations was: %v wanted %v", files, expected)
+	}
+}
+
+func TestSquashedFiles(t *testing.T) {
+	header, up, down := []byte("-- header\n\n"), []byte("CREATE TABLE t (id int);\n"), []byte("DROP TABLE t;\n")
+	for _, tc := range []struct {
+		parser   string
+		target   string
+		expected []squashedFile
+	}{
+		{"", "002_orders.up.sql", []squashedFile{
+			{"002_squashed.up.sql", []byte("-- header\n\nCREATE TABLE t (id int);\n")},
+			{"002_squashed.down.sql", []byte("-- header\n\nDROP TABLE t;\n")},
+		}},
+		{"single-file", "002_orders.sql", []squashedFile{
+			{"002_squashed.sql", []byte("-- header\n\n-- +migrate Up\nCREATE TABLE t (id int);\n\n-- +migrate Down\nDROP TABLE t;\n")},
+		}},
+		{"goose", "2_orders.sql", []squashedFile{
+			{"2_squashed.sql", []byte("-- header\n\n-- +goose Up\nCREATE TABLE t (id int);\n\n-- +goose Down\nDROP TABLE t;\n")},
+		}},
+		{"dbmate", "20200601_orders.sql", []squashedFile{
+			{"20200601_squashed.sql", []byte("-- header\n\n-- migrate:up\nCREATE TABLE t (id int);\n\n-- migrate:down\nDROP TABLE t;\n")},
+		}},
+		{"flyway", "V2__orders.sql", []squashedFile{
+			{"V2__squashed.sql", []byte("-- header\n\nCREATE TABLE t (id int);\n")},
+			{"U2__squashed.sql", []byte("-- header\n\nDROP TABLE t;\n")},
+		}},
+	} {
+		t.Run(tc.parser, func(t *testing.T) {
+			if files := squashedFiles(tc.parser, tc.target, header, up, down); !reflect.DeepEqual(files, tc.expected) {
+				t.Errorf("Incorrect files was: %q wanted %q", files, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSquashDir(t *testing.T) {
+	if dir, parser, err := squashDir("", "file://migrations"); err != nil || dir != "migrations" || parser != "" {
+		t.Errorf("Incorrect dir was: %q %q (%v)", dir, parser, err)
+	}
+	if dir, parser, err := squashDir("", "file://migrations?x-parser=single-file"); err != nil || dir != "migrations" || parser != "single-file" {
+		t.Errorf("Incorrect dir was: %q %q (%v)", dir, parser, err)
+	}
+	if dir, parser, err := squashDir("db", "file://migrations?x-parser=goose"); err != nil || dir != "db" || parser != "goose" {
+		t.Errorf("Incorrect dir was: %q %q (%v)", dir, parser, err)
+	}
+	if _, _, err := squashDir("", "s3://bucket/migrations"); err != errSquashNoPath {
+		t.Errorf("Incorrect error was: %v wanted %v", err, errSquashNoPath)
+	}
+}
+
+func TestSquashCmdNotSupported(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+	if err := os.Mkdir(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "001_users.up.sql"), []byte("SELECT 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.New("file://"+dir, "stub://")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := squashCmd(m, dir, "", "", 1, time.Now()); err != errSquashNoDown {
+		t.Errorf("Incorrect error was: %v wanted %v", err, errSquashNoDown)
+	}
+	if version, _, err := m.Version(); err != migrate.ErrNilVersion {
+		t.Errorf("Incorrect version was: %v (%v) wanted none", version, err)
+	}
+}
+
+// inspectingStub is a stub driver that can inspect its schema.
+type inspectingStub struct {
+	*dStub.Stub
+	schema *schemadiff.Schema
+}
+
+func (s *inspectingStub) InspectSchema() (*schemadiff.Schema, error) {
+	if s.schema == nil {
+		return &schemadiff.Schema{Dialect: schemadiff.SQLite}, nil
+	}
+	return s.schema, nil
+}
+
+func readDirNames(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/golang-migrate/migrate/v4/tracing"
+)
+
+var (
+	// tracer traces the run if -trace-otlp or -trace-file is set, otherwise
+	// it is nil.
+	tracer *tracing.Tracer
+
+	// rootSpan is the span of the command.
+	rootSpan *tracing.Span
+
+	// traceFile is the file of -trace-file.
+	traceFile *os.File
+)
+
+// startTracing creates the tracer exporting to the OTLP endpoint otlpURL or
+// to the file name and starts the span of the command.
+func startTracing(otlpURL string, name string, command string) error {
+	var exporter tracing.Exporter
+	if otlpURL != "" {
+		exporter = &tracing.OTLPExporter{URL: otlpURL}
+	} else {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return err
+		}
+		traceFile = f
+		exporter = &tracing.FileExporter{W: f}
+	}
+	tracer = tracing.New("migrate", exporter)
+	rootSpan = tracer.Start(command)
+	return nil
+}
+
+// endTracing ends the span of the command with err and exports the spans.
+func endTracing(err error) {
+	tracer.End(rootSpan, err)
+	if err := tracer.Flush(); err != nil {
+		log.Error("tracing: "+err.Error())
+	}
+	if traceFile != nil {
+		if err := traceFile.Close(); err != nil {
+			log.Error("tracing: "+err.Error())
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Package redact masks secrets such as passwords and tokens in log lines and
+// HTTP debug dumps.
+package redact
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Mask replaces secrets.
+const Mask = "xxxxx"
+
+// DefaultHeaders are the HTTP headers whose values are masked by a new
+// Redactor.
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+var (
+	// userPassword matches the user and password of a URL.
+	userPassword = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.\-]*://[^/?#\s:@]*):[^/?#\s@]*@`)
+
+	// user matches the user of a URL without password, which is usually a
+	// token then.
+	user = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.\-]*://)[^/?#\s:@]+@`)
+
+	// params matches secret query parameters and key=value parameters of
+	// connection strings.
+	params = regexp.MustCompile(`(?i)\b((?:password|sslpassword|x-tls-key)=)[^&#\s'"]*`)
+)
+
+// Redactor masks secrets in text. It is safe for concurrent use.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+	names   []string
+	headers *regexp.Regexp
+}
+
+// New returns a Redactor masking the passwords of URLs, the values of the
+// password, sslpassword and x-tls-key parameters and the values of the
+// DefaultHeaders.
+func New() *Redactor {
+	r := &Redactor{}
+	for _, name := range DefaultHeaders {
+		r.AddHeader(name)
+	}
+	return r
+}
+
+// AddSecret masks secret wherever it appears, e.g. the value of -token.
+// Empty secrets are ignored.
+func (r *Redactor) AddSecret(secret string) {
+	if secret == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = append(r.secrets, secret)
+}
+
+// AddHeader masks the values of the HTTP header name, matched case
+// insensitively, e.g. in "Authorization: Token abc".
+func (r *Redactor) AddHeader(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.names = append(r.names, regexp.QuoteMeta(name))
+	r.headers = regexp.MustCompile(`(?i)(\b(?:` + strings.Join(r.names, "|") + `)"?\s*:\s*"?)[^'"\r\n]*`)
+}
+
+// String returns s with all secrets masked.
+func (r *Redactor) String(s string) string {
+	s = userPassword.ReplaceAllString(s, "${1}:"+Mask+"@")
+	s = user.ReplaceAllString(s, "${1}"+Mask+"@")
+	s = params.ReplaceAllString(s, "${1}"+Mask)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.headers != nil {
+		s = r.headers.ReplaceAllString(s, "${1}"+Mask)
+	}
+	for _, secret := range r.secrets {
+		s = strings.Replace(s, secret, Mask, -1)
+	}
+	return s
+}
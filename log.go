@@ -10,3 +10,51 @@ type Logger interface {
 	// Verbose should return true when verbose logging output is wanted
 	Verbose() bool
 }
+
+// StructuredLogger is an optional interface for Loggers that log leveled
+// messages with key-value pairs, e.g. "version", 3, "driver", "postgres".
+// If Migrate.Log implements it, Migrate calls these methods instead of Printf
+// and passes the logger on to drivers implementing database.LoggerSetter.
+// See the logging package for adapters of log/slog, logrus and zap.
+type StructuredLogger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// structured returns l as StructuredLogger, wrapping Loggers that don't
+// implement it, or nil if l is nil.
+func structured(l Logger) StructuredLogger {
+	if l == nil {
+		return nil
+	}
+	if s, ok := l.(StructuredLogger); ok {
+		return s
+	}
+	return printfLogger{l}
+}
+
+// printfLogger is a StructuredLogger writing the messages of a Logger
+// without the key-value pairs, as Migrate did before StructuredLogger.
+type printfLogger struct {
+	Logger
+}
+
+func (l printfLogger) Debug(msg string, keysAndValues ...interface{}) {
+	if l.Verbose() {
+		l.Printf("%s\n", msg)
+	}
+}
+
+func (l printfLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.Printf("%s\n", msg)
+}
+
+func (l printfLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.Printf("warning: %s\n", msg)
+}
+
+func (l printfLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.Printf("error: %s\n", msg)
+}
@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+	sStub "github.com/golang-migrate/migrate/v4/source/stub"
+)
+
+type printfRecorder struct {
+	verbose bool
+	lines   []string
+}
+
+func (l *printfRecorder) Printf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *printfRecorder) Verbose() bool {
+	return l.verbose
+}
+
+type structuredRecorder struct {
+	printfRecorder
+}
+
+func (l *structuredRecorder) record(level string, msg string, keysAndValues []interface{}) {
+	l.lines = append(l.lines, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+func (l *structuredRecorder) Debug(msg string, keysAndValues ...interface{}) {
+	l.record("debug", msg, keysAndValues)
+}
+
+func (l *structuredRecorder) Info(msg string, keysAndValues ...interface{}) {
+	l.record("info", msg, keysAndValues)
+}
+
+func (l *structuredRecorder) Warn(msg string, keysAndValues ...interface{}) {
+	l.record("warn", msg, keysAndValues)
+}
+
+func (l *structuredRecorder) Error(msg string, keysAndValues ...interface{}) {
+	l.record("error", msg, keysAndValues)
+}
+
+// durations matches the durations in log lines.
+var durations = regexp.MustCompile(`[0-9.]+[µnm]?s\b`)
+
+func TestLogPrintf(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+	l := &printfRecorder{}
+	m.Log = l
+
+	if err := m.Migrate(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Steps(-1); err != nil {
+		t.Fatal(err)
+	}
+	expected := "1/u 1.up.stub (D)\n1/d 1.down.stub (D)\n"
+	if actual := durations.ReplaceAllString(strings.Join(l.lines, ""), "D"); actual != expected {
+		t.Errorf("Incorrect log was:\n%v\nwanted:\n%v", actual, expected)
+	}
+	if m.databaseDrv.(*dStub.Stub).Logger == nil {
+		t.Error("The driver did not get a logger")
+	}
+}
+
+func TestLogStructured(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+	l := &structuredRecorder{}
+	m.Log = l
+
+	if err := m.Migrate(1); err != nil {
+		t.Fatal(err)
+	}
+	var infos []string
+	for _, line := range l.lines {
+		if strings.HasPrefix(line, "info ") {
+			infos = append(infos, durations.ReplaceAllString(line, "D"))
+		}
+	}
+	expected := []string{"info 1/u 1.up.stub (D) [driver stub version 1 identifier 1.up.stub direction up read D ran D duration D]"}
+	if strings.Join(infos, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Incorrect log was: %v wanted %v", infos, expected)
+	}
+	if m.databaseDrv.(*dStub.Stub).Logger != l {
+		t.Error("The driver did not get the structured logger")
+	}
+}
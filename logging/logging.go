@@ -0,0 +1,16 @@
+// Package logging adapts structured loggers to migrate.Logger. The returned
+// loggers implement migrate.StructuredLogger, so Migrate logs leveled
+// messages with key-value pairs such as version, identifier, driver and
+// duration:
+//  m.Log = logging.Slog(slog.Default())
+package logging
+
+import (
+	"fmt"
+	"strings"
+)
+
+// message trims the newline off a message formatted for Printf.
+func message(format string, v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintf(format, v...), "\n")
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogrus(t *testing.T) {
+	var b bytes.Buffer
+	l := logrus.New()
+	l.Out = &b
+	l.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+
+	log := Logrus(l)
+	if log.Verbose() {
+		t.Error("Expected Verbose to be false at info level")
+	}
+	log.Printf("Finished after %v\n", 3)
+	s := log.(migrate.StructuredLogger)
+	s.Debug("hidden")
+	s.Warn("1/u init (2ms)", "version", 1, "identifier", "init", "odd")
+
+	expected := "level=info msg=\"Finished after 3\"\n" +
+		"level=warning msg=\"1/u init (2ms)\" !BADKEY=odd identifier=init version=1\n"
+	if b.String() != expected {
+		t.Errorf("Incorrect output was:\n%v\nwanted:\n%v", b.String(), expected)
+	}
+
+	l.Level = logrus.DebugLevel
+	if !Logrus(logrus.NewEntry(l)).Verbose() {
+		t.Error("Expected Verbose to be true at debug level")
+	}
+}
+
+type recordingZap struct {
+	lines []string
+}
+
+func (z *recordingZap) record(level string, msg string, keysAndValues []interface{}) {
+	z.lines = append(z.lines, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+func (z *recordingZap) Debugw(msg string, keysAndValues ...interface{}) {
+	z.record("debug", msg, keysAndValues)
+}
+
+func (z *recordingZap) Infow(msg string, keysAndValues ...interface{}) {
+	z.record("info", msg, keysAndValues)
+}
+
+func (z *recordingZap) Warnw(msg string, keysAndValues ...interface{}) {
+	z.record("warn", msg, keysAndValues)
+}
+
+func (z *recordingZap) Errorw(msg string, keysAndValues ...interface{}) {
+	z.record("error", msg, keysAndValues)
+}
+
+func TestZap(t *testing.T) {
+	z := &recordingZap{}
+	log := Zap(z)
+	log.Printf("Finished after %v\n", 3)
+	s := log.(migrate.StructuredLogger)
+	s.Debug("Scheduled 1/u init", "version", 1)
+	s.Error("dirty", "driver", "stub")
+
+	expected := []string{
+		"info Finished after 3 []",
+		"debug Scheduled 1/u init [version 1]",
+		"error dirty [driver stub]",
+	}
+	if !reflect.DeepEqual(z.lines, expected) {
+		t.Errorf("Incorrect lines were: %v wanted %v", z.lines, expected)
+	}
+	if strings.Contains(strings.Join(z.lines, ""), "\n") {
+		t.Error("Printf did not trim the newline")
+	}
+}
@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// Logrus returns a migrate.Logger logging to l, usually a *logrus.Logger or
+// *logrus.Entry. Printf logs at info level and Verbose reports whether l logs
+// at debug level.
+func Logrus(l logrus.FieldLogger) migrate.Logger {
+	return logrusLogger{l}
+}
+
+type logrusLogger struct {
+	l logrus.FieldLogger
+}
+
+func (l logrusLogger) Printf(format string, v ...interface{}) {
+	l.l.Info(message(format, v...))
+}
+
+func (l logrusLogger) Verbose() bool {
+	switch l := l.l.(type) {
+	case *logrus.Logger:
+		return l.IsLevelEnabled(logrus.DebugLevel)
+	case *logrus.Entry:
+		return l.Logger.IsLevelEnabled(logrus.DebugLevel)
+	}
+	return false
+}
+
+func (l logrusLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.l.WithFields(logrusFields(keysAndValues)).Debug(msg)
+}
+
+func (l logrusLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.l.WithFields(logrusFields(keysAndValues)).Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.l.WithFields(logrusFields(keysAndValues)).Warn(msg)
+}
+
+func (l logrusLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.l.WithFields(logrusFields(keysAndValues)).Error(msg)
+}
+
+// logrusFields returns the key-value pairs as logrus.Fields. A value without
+// key gets the key !BADKEY, like in log/slog.
+func logrusFields(keysAndValues []interface{}) logrus.Fields {
+	fields := logrus.Fields{}
+	for i := 0; i < len(keysAndValues); i += 2 {
+		if i+1 == len(keysAndValues) {
+			fields["!BADKEY"] = keysAndValues[i]
+			break
+		}
+		fields[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	return fields
+}
@@ -0,0 +1,44 @@
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// Slog returns a migrate.Logger logging to l. Printf logs at info level and
+// Verbose reports whether l logs at debug level.
+func Slog(l *slog.Logger) migrate.Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (l slogLogger) Printf(format string, v ...interface{}) {
+	l.l.Info(message(format, v...))
+}
+
+func (l slogLogger) Verbose() bool {
+	return l.l.Enabled(context.Background(), slog.LevelDebug)
+}
+
+func (l slogLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.l.Debug(msg, keysAndValues...)
+}
+
+func (l slogLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.l.Info(msg, keysAndValues...)
+}
+
+func (l slogLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.l.Warn(msg, keysAndValues...)
+}
+
+func (l slogLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.l.Error(msg, keysAndValues...)
+}
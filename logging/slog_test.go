@@ -0,0 +1,37 @@
+// +build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func TestSlog(t *testing.T) {
+	var b bytes.Buffer
+	h := slog.NewTextHandler(&b, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	log := Slog(slog.New(h))
+	if log.Verbose() {
+		t.Error("Expected Verbose to be false at info level")
+	}
+	log.Printf("Finished after %v\n", 3)
+	s := log.(migrate.StructuredLogger)
+	s.Debug("hidden")
+	s.Info("1/u init (2ms)", "version", 1, "identifier", "init")
+
+	expected := "level=INFO msg=\"Finished after 3\"\n" +
+		"level=INFO msg=\"1/u init (2ms)\" version=1 identifier=init\n"
+	if b.String() != expected {
+		t.Errorf("Incorrect output was:\n%v\nwanted:\n%v", b.String(), expected)
+	}
+}
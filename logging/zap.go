@@ -0,0 +1,48 @@
+package logging
+
+import "github.com/golang-migrate/migrate/v4"
+
+// ZapSugaredLogger is the part of *zap.SugaredLogger used by Zap, so that
+// migrate doesn't depend on zap.
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Zap returns a migrate.Logger logging to l, usually a *zap.SugaredLogger:
+//  m.Log = logging.Zap(zapLogger.Sugar())
+// Printf logs at info level. Verbose returns false, debug messages are
+// filtered by the level of l.
+func Zap(l ZapSugaredLogger) migrate.Logger {
+	return zapLogger{l}
+}
+
+type zapLogger struct {
+	l ZapSugaredLogger
+}
+
+func (l zapLogger) Printf(format string, v ...interface{}) {
+	l.l.Infow(message(format, v...))
+}
+
+func (l zapLogger) Verbose() bool {
+	return false
+}
+
+func (l zapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.l.Debugw(msg, keysAndValues...)
+}
+
+func (l zapLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.l.Infow(msg, keysAndValues...)
+}
+
+func (l zapLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.l.Warnw(msg, keysAndValues...)
+}
+
+func (l zapLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.l.Errorw(msg, keysAndValues...)
+}
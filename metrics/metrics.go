@@ -0,0 +1,281 @@
+// Package metrics collects metrics of migration runs and exposes them in the
+// Prometheus text format, either over HTTP or as a file for the textfile
+// collector of the node exporter.
+//
+// Set a Collector as Observer of a Migrate instance:
+//  c := metrics.New()
+//  m.Observer = c
+//  http.Handle("/metrics", c)
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the histogram buckets.
+var DefaultBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 300, 900}
+
+// contentType is the content type of the Prometheus text format.
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Collector collects the metrics of migration runs. It implements
+// migrate.Observer and http.Handler and is safe for concurrent use.
+type Collector struct {
+	mu       sync.Mutex
+	families []*family
+
+	applied     *family
+	failures    *family
+	duration    *family
+	lockWait    *family
+	lockErrors  *family
+	version     *family
+	dirty       *family
+	lastChanged *family
+	requests    *family
+}
+
+// New returns a new Collector using DefaultBuckets for its histograms.
+func New() *Collector {
+	c := &Collector{}
+	c.applied = c.add("migrate_migrations_applied_total", "Number of migrations applied.", "counter", "driver", "direction")
+	c.failures = c.add("migrate_migration_failures_total", "Number of migrations that failed.", "counter", "driver", "direction")
+	c.duration = c.add("migrate_migration_duration_seconds", "Time it took to run a migration.", "histogram", "driver")
+	c.lockWait = c.add("migrate_lock_wait_seconds", "Time it took to acquire the database lock.", "histogram", "driver")
+	c.lockErrors = c.add("migrate_lock_failures_total", "Number of times the database lock could not be acquired.", "counter", "driver")
+	c.version = c.add("migrate_version", "Current migration version of the database, -1 if none is applied.", "gauge", "driver")
+	c.dirty = c.add("migrate_dirty", "1 if the database is dirty, 0 otherwise.", "gauge", "driver")
+	c.lastChanged = c.add("migrate_version_timestamp_seconds", "Unix time the version of the database was last set or reported.", "gauge", "driver")
+	c.requests = c.add("migrate_seed_requests_total", "Number of requests sent by the seed commands by status code, error if the request failed.", "counter", "seeder", "status")
+	return c
+}
+
+func (c *Collector) add(name, help, typ string, labels ...string) *family {
+	f := &family{name: name, help: help, typ: typ, labels: labels, series: map[string]*series{}}
+	c.families = append(c.families, f)
+	return f
+}
+
+// MigrationRun implements migrate.Observer.
+func (c *Collector) MigrationRun(databaseName string, migr *migrate.Migration, duration time.Duration, err error) {
+	direction := migr.Direction()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.duration.get(databaseName).observe(duration.Seconds())
+	if err != nil {
+		c.failures.get(databaseName, direction).value++
+	} else {
+		c.applied.get(databaseName, direction).value++
+	}
+}
+
+// LockAcquired implements migrate.Observer.
+func (c *Collector) LockAcquired(databaseName string, wait time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lockWait.get(databaseName).observe(wait.Seconds())
+	if err != nil {
+		c.lockErrors.get(databaseName).value++
+	}
+}
+
+// VersionChanged implements migrate.Observer. Call it with the result of
+// Migrate.Version to report the version if no migration ran.
+func (c *Collector) VersionChanged(databaseName string, version int, dirty bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.version.get(databaseName).value = float64(version)
+	c.dirty.get(databaseName).value = 0
+	if dirty {
+		c.dirty.get(databaseName).value = 1
+	}
+	c.lastChanged.get(databaseName).value = float64(time.Now().UnixNano()) / 1e9
+}
+
+// RequestSent counts a request sent by a seeder, e.g. elastic or http, with
+// the status code of the response or the error if the request failed.
+func (c *Collector) RequestSent(seeder string, statusCode int, err error) {
+	status := strconv.Itoa(statusCode)
+	if err != nil {
+		status = "error"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests.get(seeder, status).value++
+}
+
+// WriteTo writes the metrics in the Prometheus text format to w.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, f := range c.families {
+		f.writeTo(cw)
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// WriteFile writes the metrics in the Prometheus text format to the file
+// name. The file is replaced atomically, so that it can be read by the
+// textfile collector of the node exporter at any time.
+func (c *Collector) WriteFile(name string) (err error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := c.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// ServeHTTP implements http.Handler and writes the metrics in the Prometheus
+// text format.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	c.WriteTo(w)
+}
+
+// family is a metric with all its label values.
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+	series map[string]*series
+}
+
+// series is the value of a metric with a set of label values.
+type series struct {
+	labels []string
+	value  float64
+
+	// counts, sum and count of the observations of histograms
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (f *family) get(labels ...string) *series {
+	key := strings.Join(labels, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labels: labels}
+		if f.typ == "histogram" {
+			s.counts = make([]uint64, len(DefaultBuckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+func (s *series) observe(v float64) {
+	for i, bound := range DefaultBuckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (f *family) writeTo(w *countWriter) {
+	if len(f.series) == 0 {
+		return
+	}
+	w.printf("# HELP %s %s\n", f.name, f.help)
+	w.printf("# TYPE %s %s\n", f.name, f.typ)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s := f.series[key]
+		if f.typ != "histogram" {
+			w.printf("%s%s %s\n", f.name, f.labelString(s, "", ""), formatFloat(s.value))
+			continue
+		}
+		for i, bound := range DefaultBuckets {
+			w.printf("%s_bucket%s %d\n", f.name, f.labelString(s, "le", formatFloat(bound)), s.counts[i])
+		}
+		w.printf("%s_bucket%s %d\n", f.name, f.labelString(s, "le", "+Inf"), s.count)
+		w.printf("%s_sum%s %s\n", f.name, f.labelString(s, "", ""), formatFloat(s.sum))
+		w.printf("%s_count%s %d\n", f.name, f.labelString(s, "", ""), s.count)
+	}
+}
+
+// labelString returns the labels of s, and the extra label if not empty, in
+// the Prometheus text format, e.g. {driver="postgres"}.
+func (f *family) labelString(s *series, extra, extraValue string) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, name+"="+quote(s.labels[i]))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra+"="+quote(extraValue))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// countWriter counts the bytes written and keeps the first error.
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (w *countWriter) printf(format string, v ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, v...)
+	w.n += int64(n)
+	w.err = err
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+func TestCollector(t *testing.T) {
+	c := New()
+	c.LockAcquired("postgres", 20*time.Millisecond, nil)
+	c.MigrationRun("postgres", &migrate.Migration{Version: 1, TargetVersion: 1}, 2*time.Second, nil)
+	c.MigrationRun("postgres", &migrate.Migration{Version: 2, TargetVersion: 1}, 0, errors.New("syntax error"))
+	c.VersionChanged("postgres", 2, true)
+	c.RequestSent("elastic", 201, nil)
+	c.RequestSent("elastic", 0, errors.New("connection refused"))
+
+	var b strings.Builder
+	n, err := c.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(n) != b.Len() {
+		t.Errorf("Incorrect length was: %v wanted %v", n, b.Len())
+	}
+
+	for _, expected := range []string{
+		"# TYPE migrate_migrations_applied_total counter\nmigrate_migrations_applied_total{driver=\"postgres\",direction=\"up\"} 1\n",
+		"migrate_migration_failures_total{driver=\"postgres\",direction=\"down\"} 1\n",
+		"migrate_migration_duration_seconds_bucket{driver=\"postgres\",le=\"1\"} 1\n",
+		"migrate_migration_duration_seconds_bucket{driver=\"postgres\",le=\"5\"} 2\n",
+		"migrate_migration_duration_seconds_bucket{driver=\"postgres\",le=\"+Inf\"} 2\n",
+		"migrate_migration_duration_seconds_sum{driver=\"postgres\"} 2\n",
+		"migrate_migration_duration_seconds_count{driver=\"postgres\"} 2\n",
+		"migrate_lock_wait_seconds_bucket{driver=\"postgres\",le=\"0.01\"} 0\n",
+		"migrate_lock_wait_seconds_bucket{driver=\"postgres\",le=\"0.05\"} 1\n",
+		"migrate_version{driver=\"postgres\"} 2\n",
+		"migrate_dirty{driver=\"postgres\"} 1\n",
+		"migrate_seed_requests_total{seeder=\"elastic\",status=\"201\"} 1\nmigrate_seed_requests_total{seeder=\"elastic\",status=\"error\"} 1\n",
+	} {
+		if !strings.Contains(b.String(), expected) {
+			t.Errorf("Metrics are missing %q:\n%v", expected, b.String())
+		}
+	}
+	if strings.Contains(b.String(), "migrate_lock_failures_total") {
+		t.Errorf("Metrics without values should be omitted:\n%v", b.String())
+	}
+}
+
+func TestCollectorQuote(t *testing.T) {
+	c := New()
+	c.VersionChanged("a\"b\\c\n", 1, false)
+
+	var b strings.Builder
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `migrate_version{driver="a\"b\\c\n"} 1`; !strings.Contains(b.String(), expected) {
+		t.Errorf("Metrics are missing %q:\n%v", expected, b.String())
+	}
+}
+
+func TestCollectorWriteFile(t *testing.T) {
+	c := New()
+	c.VersionChanged("stub", 3, false)
+
+	name := filepath.Join(t.TempDir(), "migrate.prom")
+	if err := c.WriteFile(name); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "migrate_version{driver=\"stub\"} 3\n") {
+		t.Errorf("Incorrect file was:\n%s", b)
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("Temporary file was not removed: %v", files)
+	}
+}
+
+func TestCollectorServeHTTP(t *testing.T) {
+	c := New()
+	c.VersionChanged("stub", 3, false)
+
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != contentType {
+		t.Errorf("Incorrect content type was: %v", ct)
+	}
+	if !strings.Contains(w.Body.String(), "migrate_dirty{driver=\"stub\"} 0\n") {
+		t.Errorf("Incorrect body was:\n%v", w.Body.String())
+	}
+}
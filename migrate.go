@@ -5,8 +5,13 @@
 package migrate
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"sync"
 	"time"
@@ -34,6 +39,15 @@ var (
 	ErrInvalidVersion = errors.New("version must be >= -1")
 	ErrLocked         = errors.New("database locked")
 	ErrLockTimeout    = errors.New("timeout: can't acquire database lock")
+
+	ErrNamespacesNotSupported    = errors.New("database driver does not support namespaces")
+	ErrRepeatablesNotSupported   = errors.New("database driver does not support repeatable migrations")
+	ErrPhasesNotSupported        = errors.New("database driver does not support phases")
+	ErrBaselineNotEmpty          = errors.New("database already has migrations applied, refusing to baseline")
+	ErrGraphNamespaces           = errors.New("dependency graph does not support namespaces")
+	ErrSchemaDumpNotSupported    = errors.New("database driver can't dump its schema")
+	ErrSchemaInspectNotSupported = errors.New("database driver can't inspect its schema")
+	ErrTagsNotSupported          = errors.New("database driver does not record skipped migrations, tags are not supported")
 )
 
 // ErrShortLimit is an error returned when not enough migrations
@@ -64,6 +78,9 @@ type Migrate struct {
 	// Log accepts a Logger interface
 	Log Logger
 
+	// Observer accepts an Observer interface
+	Observer Observer
+
 	// GracefulStop accepts `true` and will stop executing migrations
 	// as soon as possible at a safe break point, so that the database
 	// is not corrupted.
@@ -80,6 +97,10 @@ type Migrate struct {
 	// LockTimeout defaults to DefaultLockTimeout,
 	// but can be set per Migrate instance.
 	LockTimeout time.Duration
+
+	// tags and excludeTags select the migrations to run, see WithTags.
+	tags        []string
+	excludeTags []string
 }
 
 // New returns a new Migrate instance from a source URL and a database URL.
@@ -103,6 +124,9 @@ func New(sourceURL, databaseURL string) (*Migrate, error) {
 	if err != nil {
 		return nil, err
 	}
+	if sourceDrv, err = withGoMigrations(sourceDrv); err != nil {
+		return nil, err
+	}
 	m.sourceDrv = sourceDrv
 
 	databaseDrv, err := database.Open(databaseURL)
@@ -133,6 +157,9 @@ func NewWithDatabaseInstance(sourceURL string, databaseName string, databaseInst
 	if err != nil {
 		return nil, err
 	}
+	if sourceDrv, err = withGoMigrations(sourceDrv); err != nil {
+		return nil, err
+	}
 	m.sourceDrv = sourceDrv
 
 	m.databaseDrv = databaseInstance
@@ -161,7 +188,11 @@ func NewWithSourceInstance(sourceName string, sourceInstance source.Driver, data
 	}
 	m.databaseDrv = databaseDrv
 
-	m.sourceDrv = sourceInstance
+	sourceDrv, err := withGoMigrations(sourceInstance)
+	if err != nil {
+		return nil, err
+	}
+	m.sourceDrv = sourceDrv
 
 	return m, nil
 }
@@ -176,7 +207,11 @@ func NewWithInstance(sourceName string, sourceInstance source.Driver, databaseNa
 	m.sourceName = sourceName
 	m.databaseName = databaseName
 
-	m.sourceDrv = sourceInstance
+	sourceDrv, err := withGoMigrations(sourceInstance)
+	if err != nil {
+		return nil, err
+	}
+	m.sourceDrv = sourceDrv
 	m.databaseDrv = databaseInstance
 
 	return m, nil
@@ -196,7 +231,7 @@ func (m *Migrate) Close() (source error, database error) {
 	databaseSrvClose := make(chan error)
 	sourceSrvClose := make(chan error)
 
-	m.logVerbosePrintf("Closing source and database\n")
+	m.logDebug("Closing source and database")
 
 	go func() {
 		databaseSrvClose <- m.databaseDrv.Close()
@@ -225,10 +260,22 @@ func (m *Migrate) Migrate(version uint) error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	// migrations applied by UpPhase above the version are reverted first
+	reverted := 0
+	if !m.before(curVersion, int(version)) {
+		if reverted, err = m.downPhases(curVersion, -1); err != nil {
+			return m.unlockErr(err)
+		}
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 	go m.read(curVersion, int(version), ret)
 
-	return m.unlockErr(m.runMigrations(ret))
+	err = m.runMigrations(ret)
+	if reverted > 0 && errors.Is(err, ErrNoChange) {
+		err = nil
+	}
+	return m.unlockErr(err)
 }
 
 // Steps looks at the currently active migration version.
@@ -251,20 +298,39 @@ func (m *Migrate) Steps(n int) error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	// migrations applied by UpPhase above the version are reverted first
+	reverted := 0
+	if n < 0 {
+		if reverted, err = m.downPhases(curVersion, -n); err != nil {
+			return m.unlockErr(err)
+		}
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 
 	if n > 0 {
 		go m.readUp(curVersion, n, ret)
 	} else {
-		go m.readDown(curVersion, -n, ret)
+		go m.readDown(curVersion, -n-reverted, ret)
 	}
 
-	return m.unlockErr(m.runMigrations(ret))
+	err = m.runMigrations(ret)
+	if reverted > 0 && errors.Is(err, ErrNoChange) {
+		err = nil
+	}
+	return m.unlockErr(err)
 }
 
 // Up looks at the currently active migration version
 // and will migrate all the way up (applying all up migrations).
+// Afterwards all repeatable migrations that changed since they were last
+// applied are run, see source.RepeatableReader.
+// If the source provides namespaces, every namespace is migrated up in turn.
 func (m *Migrate) Up() error {
+	if namespaces := m.Namespaces(); len(namespaces) > 0 {
+		return m.eachNamespace(namespaces, (*Migrate).Up)
+	}
+
 	if err := m.lock(); err != nil {
 		return err
 	}
@@ -278,10 +344,26 @@ func (m *Migrate) Up() error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
-	ret := make(chan interface{}, m.PrefetchMigrations)
+	upSkipped, err := m.upSkipped(curVersion)
+	if err != nil {
+		return m.unlockErr(err)
+	}
 
-	go m.readUp(curVersion, -1, ret)
-	return m.unlockErr(m.runMigrations(ret))
+	if tracker, ok := m.appliedTracker(); ok {
+		err = m.upGraph(tracker, curVersion)
+	} else {
+		ret := make(chan interface{}, m.PrefetchMigrations)
+
+		go m.readUp(curVersion, -1, ret)
+		err = m.runMigrations(ret)
+	}
+	if upSkipped && errors.Is(err, ErrNoChange) {
+		err = nil
+	}
+	if err == nil || errors.Is(err, ErrNoChange) {
+		err = m.runRepeatables(err)
+	}
+	return m.unlockErr(err)
 }
 
 // Up looks at the currently active migration version
@@ -314,7 +396,17 @@ func (m *Migrate) SeedDown() error {
 
 // Down looks at the currently active migration version
 // and will migrate all the way down (applying all down migrations).
+// If the source provides namespaces, every namespace is migrated down
+// in reverse order.
 func (m *Migrate) Down() error {
+	if namespaces := m.Namespaces(); len(namespaces) > 0 {
+		reversed := make([]string, 0, len(namespaces))
+		for i := len(namespaces) - 1; i >= 0; i-- {
+			reversed = append(reversed, namespaces[i])
+		}
+		return m.eachNamespace(reversed, (*Migrate).Down)
+	}
+
 	if err := m.lock(); err != nil {
 		return err
 	}
@@ -328,9 +420,20 @@ func (m *Migrate) Down() error {
 		return m.unlockErr(ErrDirty{curVersion})
 	}
 
+	// migrations applied by UpPhase above the version are reverted first
+	reverted, err := m.downPhases(curVersion, -1)
+	if err != nil {
+		return m.unlockErr(err)
+	}
+
 	ret := make(chan interface{}, m.PrefetchMigrations)
 	go m.readDown(curVersion, -1, ret)
-	return m.unlockErr(m.runMigrations(ret))
+
+	err = m.runMigrations(ret)
+	if reverted > 0 && errors.Is(err, ErrNoChange) {
+		err = nil
+	}
+	return m.unlockErr(err)
 }
 
 // Drop deletes everything in the database.
@@ -372,9 +475,9 @@ func (m *Migrate) Run(migration ...*Migration) error {
 		defer close(ret)
 		for _, migr := range migration {
 			if m.PrefetchMigrations > 0 && migr.Body != nil {
-				m.logVerbosePrintf("Start buffering %v\n", migr.LogString())
+				m.logDebug("Start buffering "+migr.LogString(), migrationFields(migr)...)
 			} else {
-				m.logVerbosePrintf("Scheduled %v\n", migr.LogString())
+				m.logDebug("Scheduled "+migr.LogString(), migrationFields(migr)...)
 			}
 
 			ret <- migr
@@ -401,10 +504,135 @@ func (m *Migrate) Force(version int) error {
 		return err
 	}
 
-	if err := m.databaseDrv.SetVersion(version, false); err != nil {
+	if err := m.setVersion(version, false); err != nil {
+		return m.unlockErr(err)
+	}
+
+	return m.unlock()
+}
+
+// Namespaces returns the namespaces provided by the source driver, see
+// source.Namespacer. It returns nil if the source has no namespaces.
+// Up and Down run against all namespaces, all other methods only act on
+// the root namespace. Use WithNamespace to target a single namespace.
+func (m *Migrate) Namespaces() []string {
+	if ns, ok := m.sourceDrv.(source.Namespacer); ok {
+		return ns.Namespaces()
+	}
+	return nil
+}
+
+// WithNamespace returns a Migrate instance that reads the migrations of the
+// given namespace and tracks its version separately from other namespaces.
+// The returned instance shares the source and database with m,
+// so it must not be closed.
+func (m *Migrate) WithNamespace(name string) (*Migrate, error) {
+	ns, ok := m.sourceDrv.(source.Namespacer)
+	if !ok {
+		return nil, fmt.Errorf("source driver %v does not support namespaces", m.sourceName)
+	}
+	sourceDrv, err := ns.Namespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseDrv := m.databaseDrv
+	if name != "" {
+		dn, ok := m.databaseDrv.(database.Namespacer)
+		if !ok {
+			return nil, ErrNamespacesNotSupported
+		}
+		if databaseDrv, err = dn.Namespace(name); err != nil {
+			return nil, err
+		}
+	}
+
+	nm := newCommon()
+	nm.sourceName = m.sourceName
+	nm.sourceDrv = sourceDrv
+	nm.databaseName = m.databaseName
+	nm.databaseDrv = databaseDrv
+	nm.Log = m.Log
+	nm.Observer = m.Observer
+	nm.GracefulStop = m.GracefulStop
+	nm.PrefetchMigrations = m.PrefetchMigrations
+	nm.LockTimeout = m.LockTimeout
+	nm.tags = m.tags
+	nm.excludeTags = m.excludeTags
+	return nm, nil
+}
+
+// eachNamespace calls fn for every namespace in order. It returns ErrNoChange
+// only if fn returned ErrNoChange for all namespaces.
+func (m *Migrate) eachNamespace(namespaces []string, fn func(*Migrate) error) error {
+	changed := false
+	for _, name := range namespaces {
+		nm, err := m.WithNamespace(name)
+		if err != nil {
+			return err
+		}
+
+		m.logDebug(fmt.Sprintf("Namespace %q", name), "namespace", name)
+		if err := fn(nm); err == nil {
+			changed = true
+		} else if !errors.Is(err, ErrNoChange) {
+			return fmt.Errorf("namespace %q: %w", name, err)
+		}
+
+		if nm.isGracefulStop {
+			m.isGracefulStop = true
+			return nil
+		}
+	}
+	if !changed {
+		return ErrNoChange
+	}
+	return nil
+}
+
+// Baseline marks an existing database, that was not managed by migrate yet,
+// as being at version without running any migrations. Up will start with
+// the migration following version. It refuses to baseline a database that
+// already has a version, see ErrBaselineNotEmpty.
+func (m *Migrate) Baseline(version uint) error {
+	return m.BaselineWithDescription(version, "")
+}
+
+// BaselineWithDescription is like Baseline, but also passes a description
+// to database drivers that keep a history of their baselines, see database.Baseliner.
+func (m *Migrate) BaselineWithDescription(version uint, description string) error {
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	curVersion, dirty, err := m.databaseDrv.Version()
+	if err != nil {
 		return m.unlockErr(err)
 	}
 
+	if dirty {
+		return m.unlockErr(ErrDirty{curVersion})
+	}
+
+	if curVersion != database.NilVersion {
+		return m.unlockErr(ErrBaselineNotEmpty)
+	}
+
+	if err := m.versionExists(version); err != nil {
+		return m.unlockErr(err)
+	}
+
+	if err := m.setVersion(int(version), false); err != nil {
+		return m.unlockErr(err)
+	}
+
+	if b, ok := m.databaseDrv.(database.Baseliner); ok {
+		if err := b.Baseline(int(version), description); err != nil {
+			return m.unlockErr(err)
+		}
+	}
+
+	m.logInfo(fmt.Sprintf("Baselined at version %v", version), "version", version)
 	return m.unlock()
 }
 
@@ -452,7 +680,7 @@ func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 		return
 	}
 
-	if from < to {
+	if m.before(from, to) {
 		// it's going up
 		// apply first migration if from is nil version
 		if from == -1 {
@@ -479,7 +707,7 @@ func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 		}
 
 		// run until we reach target ...
-		for from < to {
+		for m.before(from, to) {
 			if m.stop() {
 				return
 			}
@@ -509,7 +737,7 @@ func (m *Migrate) read(from int, to int, ret chan<- interface{}) {
 	} else {
 		// it's going down
 		// run until we reach target ...
-		for from > to && from >= 0 {
+		for m.before(to, from) && from >= 0 {
 			if m.stop() {
 				return
 			}
@@ -751,6 +979,15 @@ func (m *Migrate) readDown(from int, limit int, ret chan<- interface{}) {
 // to stop execution because it might have received a stop signal on the
 // GracefulStop channel.
 func (m *Migrate) runMigrations(ret <-chan interface{}) error {
+	skipped, err := m.skippedVersions()
+	if err != nil {
+		return err
+	}
+	phased, err := m.phaseVersions()
+	if err != nil {
+		return err
+	}
+
 	for r := range ret {
 
 		if m.stop() {
@@ -764,35 +1001,73 @@ func (m *Migrate) runMigrations(ret <-chan interface{}) error {
 		case *Migration:
 			migr := r
 
+			var skip bool
+			if migr.isDown() {
+				skip, err = skipOnDown(migr, skipped)
+			} else {
+				skip, err = m.skipByTags(migr)
+			}
+			if err != nil {
+				return err
+			}
+
+			applied := false
+			if !skip {
+				if applied, err = appliedInPhase(migr, phased); err != nil {
+					return err
+				}
+			}
+
 			// set version with dirty state
-			if err := m.databaseDrv.SetVersion(migr.TargetVersion, true); err != nil {
+			if err := m.setVersion(migr.TargetVersion, true); err != nil {
 				return err
 			}
 
-			if migr.Body != nil {
-				m.logVerbosePrintf("Read and execute %v\n", migr.LogString())
-				if err := m.databaseDrv.Run(migr.BufferedBody); err != nil {
+			if skip {
+				// not run
+			} else if migr.Body != nil && !applied {
+				m.logDebug("Read and execute "+migr.LogString(), migrationFields(migr)...)
+				if err := m.observeRun(migr, func() error { return m.run(migr.BufferedBody) }); err != nil {
+					return err
+				}
+			} else if migr.Func != nil && !applied {
+				m.logDebug("Execute "+migr.LogString(), migrationFields(migr)...)
+				if err := m.observeRun(migr, func() error { return m.runFunc(migr.Func) }); err != nil {
 					return err
 				}
 			}
 
+			if err := m.recordSkip(migr, skip, skipped); err != nil {
+				return err
+			}
+
+			if err := m.recordPhase(migr, phased); err != nil {
+				return err
+			}
+
+			if err := m.recordApplied(migr); err != nil {
+				return err
+			}
+
 			// set clean state
-			if err := m.databaseDrv.SetVersion(migr.TargetVersion, false); err != nil {
+			if err := m.setVersion(migr.TargetVersion, false); err != nil {
 				return err
 			}
 
+			if skip {
+				m.logInfo("Skipped "+migr.LogString(), migrationFields(migr)...)
+				continue
+			}
+			if applied {
+				m.logDebug("Already applied in phase "+migr.LogString(), migrationFields(migr)...)
+				continue
+			}
+
 			endTime := time.Now()
 			readTime := migr.FinishedReading.Sub(migr.StartedBuffering)
 			runTime := endTime.Sub(migr.FinishedReading)
 
-			// log either verbose or normal
-			if m.Log != nil {
-				if m.Log.Verbose() {
-					m.logPrintf("Finished %v (read %v, ran %v)\n", migr.LogString(), readTime, runTime)
-				} else {
-					m.logPrintf("%v (%v)\n", migr.LogString(), readTime+runTime)
-				}
-			}
+			m.logFinished(migr, readTime, runTime)
 
 		default:
 			return fmt.Errorf("unknown type: %T with value: %+v", r, r)
@@ -815,9 +1090,23 @@ func (m *Migrate) runSeedMigrations(ret <-chan interface{}) error {
 		case *Migration:
 			migr := r
 
+			skip, err := m.skipByTags(migr)
+			if err != nil {
+				return err
+			}
+			if skip {
+				m.logInfo("Skipped "+migr.LogString(), migrationFields(migr)...)
+				continue
+			}
+
 			if migr.Body != nil {
-				m.logVerbosePrintf("Read and execute %v\n", migr.LogString())
-				if err := m.databaseDrv.Run(migr.BufferedBody); err != nil {
+				m.logDebug("Read and execute "+migr.LogString(), migrationFields(migr)...)
+				if err := m.run(migr.BufferedBody); err != nil {
+					return err
+				}
+			} else if migr.Func != nil {
+				m.logDebug("Execute "+migr.LogString(), migrationFields(migr)...)
+				if err := m.runFunc(migr.Func); err != nil {
 					return err
 				}
 			}
@@ -826,14 +1115,7 @@ func (m *Migrate) runSeedMigrations(ret <-chan interface{}) error {
 			readTime := migr.FinishedReading.Sub(migr.StartedBuffering)
 			runTime := endTime.Sub(migr.FinishedReading)
 
-			// log either verbose or normal
-			if m.Log != nil {
-				if m.Log.Verbose() {
-					m.logPrintf("Finished %v (read %v, ran %v)\n", migr.LogString(), readTime, runTime)
-				} else {
-					m.logPrintf("%v (%v)\n", migr.LogString(), readTime+runTime)
-				}
-			}
+			m.logFinished(migr, readTime, runTime)
 
 		default:
 			return fmt.Errorf("unknown type: %T with value: %+v", r, r)
@@ -842,6 +1124,114 @@ func (m *Migrate) runSeedMigrations(ret <-chan interface{}) error {
 	return nil
 }
 
+// runRepeatables applies all repeatable migrations whose checksum differs from
+// the one recorded by the database. prevErr is returned if none of them had to
+// be applied, so that an ErrNoChange of the versioned migrations is kept.
+func (m *Migrate) runRepeatables(prevErr error) error {
+	rr, ok := m.sourceDrv.(source.RepeatableReader)
+	if !ok {
+		return prevErr
+	}
+
+	identifiers, err := rr.Repeatables()
+	if err != nil {
+		return err
+	}
+	if len(identifiers) == 0 {
+		return prevErr
+	}
+
+	tracker, ok := m.databaseDrv.(database.RepeatableTracker)
+	if !ok {
+		return ErrRepeatablesNotSupported
+	}
+
+	for _, identifier := range identifiers {
+		if m.stop() {
+			return nil
+		}
+
+		applied, err := m.runRepeatable(rr, tracker, identifier)
+		if err != nil {
+			return err
+		}
+		if applied {
+			prevErr = nil
+		}
+	}
+	return prevErr
+}
+
+// runRepeatable applies a single repeatable migration if its checksum changed
+// and reports whether it was applied.
+func (m *Migrate) runRepeatable(rr source.RepeatableReader, tracker database.RepeatableTracker, identifier string) (bool, error) {
+	startTime := time.Now()
+
+	body, checksum, err := readRepeatable(rr, identifier)
+	if err != nil {
+		return false, err
+	}
+
+	recorded, err := tracker.RepeatableChecksum(identifier)
+	if err != nil {
+		return false, err
+	}
+	if recorded == checksum {
+		m.logDebug("Unchanged R/"+identifier, "identifier", identifier)
+		return false, nil
+	}
+
+	m.logDebug("Read and execute R/"+identifier, "identifier", identifier)
+	if err := m.run(bytes.NewReader(body)); err != nil {
+		return false, err
+	}
+	if err := tracker.SetRepeatableChecksum(identifier, checksum); err != nil {
+		return false, err
+	}
+
+	duration := time.Since(startTime)
+	m.logInfo(fmt.Sprintf("R/%v (%v)", identifier, duration), "identifier", identifier, "duration", duration)
+	return true, nil
+}
+
+// readRepeatable reads the repeatable migration identifier and returns its
+// body with the checksum of the body.
+func readRepeatable(rr source.RepeatableReader, identifier string) (body []byte, checksum string, err error) {
+	r, err := rr.ReadRepeatable(identifier)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err = ioutil.ReadAll(r)
+	if errClose := r.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return body, hex.EncodeToString(sum[:]), nil
+}
+
+// run runs migration against the database. Drivers implementing
+// database.OptionsRunner get the options set by the directives in the
+// migration header, all other drivers run the migration as is. Drivers
+// implementing database.LoggerSetter get the logger of m first.
+func (m *Migrate) run(migration io.Reader) error {
+	if setter, ok := m.databaseDrv.(database.LoggerSetter); ok && m.Log != nil {
+		setter.SetLogger(structured(m.Log))
+	}
+	runner, ok := m.databaseDrv.(database.OptionsRunner)
+	if !ok {
+		return m.databaseDrv.Run(migration)
+	}
+	options, migration, err := database.ParseDirectives(migration)
+	if err != nil {
+		return err
+	}
+	return runner.RunWithOptions(migration, options)
+}
+
 // versionExists checks the source if either the up or down migration for
 // the specified migration version exists.
 func (m *Migrate) versionExists(version uint) (result error) {
@@ -903,7 +1293,26 @@ func (m *Migrate) stop() bool {
 func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, error) {
 	var migr *Migration
 
-	if targetVersion >= int(version) {
+	direction := source.Up
+	if m.before(targetVersion, int(version)) {
+		direction = source.Down
+	}
+	fn, identifier, err := m.readFunc(version, direction)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if fn != nil {
+		// create migration written in Go
+		if migr, err = NewMigration(nil, identifier, version, targetVersion); err != nil {
+			return nil, err
+		}
+		migr.Func = fn
+		migr.down = direction == source.Down
+		m.logDebug("Scheduled "+migr.LogString(), migrationFields(migr)...)
+		return migr, nil
+	}
+
+	if direction == source.Up {
 		r, identifier, err := m.sourceDrv.ReadUp(version)
 		if errors.Is(err, os.ErrNotExist) {
 			// create "empty" migration
@@ -943,11 +1352,12 @@ func (m *Migrate) newMigration(version uint, targetVersion int) (*Migration, err
 			}
 		}
 	}
+	migr.down = direction == source.Down
 
 	if m.PrefetchMigrations > 0 && migr.Body != nil {
-		m.logVerbosePrintf("Start buffering %v\n", migr.LogString())
+		m.logDebug("Start buffering "+migr.LogString(), migrationFields(migr)...)
 	} else {
-		m.logVerbosePrintf("Scheduled %v\n", migr.LogString())
+		m.logDebug("Scheduled "+migr.LogString(), migrationFields(migr)...)
 	}
 
 	return migr, nil
@@ -973,6 +1383,7 @@ func (m *Migrate) lock() error {
 	errchan := make(chan error, 2)
 
 	// start timeout goroutine
+	start := time.Now()
 	timeout := time.After(m.LockTimeout)
 	go func() {
 		for {
@@ -1000,6 +1411,9 @@ func (m *Migrate) lock() error {
 	if err == nil {
 		m.isLocked = true
 	}
+	if m.Observer != nil {
+		m.Observer.LockAcquired(m.databaseName, time.Since(start), err)
+	}
 	return err
 }
 
@@ -1028,23 +1442,50 @@ func (m *Migrate) unlockErr(prevErr error) error {
 	return prevErr
 }
 
-// logPrintf writes to m.Log if not nil
-func (m *Migrate) logPrintf(format string, v ...interface{}) {
-	if m.Log != nil {
-		m.Log.Printf(format, v...)
+// logDebug writes msg and the key-value pairs keysAndValues to m.Log if not
+// nil. Use for verbose logging output.
+func (m *Migrate) logDebug(msg string, keysAndValues ...interface{}) {
+	if l := structured(m.Log); l != nil {
+		l.Debug(msg, m.logFields(keysAndValues)...)
 	}
 }
 
-// logVerbosePrintf writes to m.Log if not nil. Use for verbose logging output.
-func (m *Migrate) logVerbosePrintf(format string, v ...interface{}) {
-	if m.Log != nil && m.Log.Verbose() {
-		m.Log.Printf(format, v...)
+// logInfo writes msg and the key-value pairs keysAndValues to m.Log if not
+// nil.
+func (m *Migrate) logInfo(msg string, keysAndValues ...interface{}) {
+	if l := structured(m.Log); l != nil {
+		l.Info(msg, m.logFields(keysAndValues)...)
 	}
 }
 
 // logErr writes error to m.Log if not nil
 func (m *Migrate) logErr(err error) {
-	if m.Log != nil {
-		m.Log.Printf("error: %v", err)
+	if l := structured(m.Log); l != nil {
+		l.Error(err.Error(), m.logFields(nil)...)
+	}
+}
+
+// logFinished writes the time it took to read and run migr to m.Log if not
+// nil, with the times broken down for verbose logging output.
+func (m *Migrate) logFinished(migr *Migration, readTime, runTime time.Duration) {
+	if m.Log == nil {
+		return
 	}
+	fields := migrationFields(migr, "read", readTime, "ran", runTime, "duration", readTime+runTime)
+	if m.Log.Verbose() {
+		m.logInfo(fmt.Sprintf("Finished %v (read %v, ran %v)", migr.LogString(), readTime, runTime), fields...)
+	} else {
+		m.logInfo(fmt.Sprintf("%v (%v)", migr.LogString(), readTime+runTime), fields...)
+	}
+}
+
+// logFields adds the database driver to the key-value pairs keysAndValues.
+func (m *Migrate) logFields(keysAndValues []interface{}) []interface{} {
+	return append([]interface{}{"driver", m.databaseName}, keysAndValues...)
+}
+
+// migrationFields returns the key-value pairs describing migr followed by
+// keysAndValues.
+func migrationFields(migr *Migration, keysAndValues ...interface{}) []interface{} {
+	return append([]interface{}{"version", migr.Version, "identifier", migr.Identifier, "direction", migr.Direction()}, keysAndValues...)
 }
@@ -2,18 +2,25 @@ package migrate
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"errors"
 	"io/ioutil"
 	"log"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 import (
+	"github.com/golang-migrate/migrate/v4/database"
 	dStub "github.com/golang-migrate/migrate/v4/database/stub"
 	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/gofunc"
+	"github.com/golang-migrate/migrate/v4/source/graph"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 	sStub "github.com/golang-migrate/migrate/v4/source/stub"
 )
 
@@ -1006,6 +1013,36 @@ func TestForce(t *testing.T) {
 	}
 }
 
+func TestBaseline(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.Baseline(2); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist for unknown version, got %v", err)
+	}
+
+	if err := m.BaselineWithDescription(4, "legacy schema"); err != nil {
+		t.Fatal(err)
+	}
+	if dbDrv.CurrentVersion != 4 || dbDrv.IsDirty {
+		t.Fatalf("expected clean version 4, got %v (dirty: %v)", dbDrv.CurrentVersion, dbDrv.IsDirty)
+	}
+	if len(dbDrv.Baselines) != 1 || dbDrv.Baselines[0] != "4 legacy schema" {
+		t.Fatalf("expected baseline to be recorded, got %v", dbDrv.Baselines)
+	}
+
+	if err := m.Baseline(4); err != ErrBaselineNotEmpty {
+		t.Fatalf("expected ErrBaselineNotEmpty, got %v", err)
+	}
+
+	// up starts after the baseline
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("CREATE 7")}, dbDrv)
+}
+
 func TestForceDirty(t *testing.T) {
 	m, _ := New("stub://", "stub://")
 	dbDrv := m.databaseDrv.(*dStub.Stub)
@@ -1309,6 +1346,537 @@ func TestReadDown(t *testing.T) {
 	}
 }
 
+func TestUpRepeatables(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	migrations := source.NewMigrations()
+	migrations.Append(&source.Migration{Version: 1, Direction: source.Up, Identifier: "CREATE 1"})
+	migrations.Append(&source.Migration{Identifier: "views", Raw: "CREATE VIEW", Repeatable: true})
+	m.sourceDrv.(*sStub.Stub).Migrations = migrations
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("CREATE VIEW")}, dbDrv)
+
+	// unchanged repeatable migrations are not applied again
+	if err := m.Up(); err != ErrNoChange {
+		t.Fatalf("expected ErrNoChange, got %v", err)
+	}
+
+	// a changed repeatable migration is applied on the next up
+	migrations.Repeatables()[0].Raw = "CREATE OR REPLACE VIEW"
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("CREATE VIEW"), mr("CREATE OR REPLACE VIEW")}, dbDrv)
+}
+
+func TestUpDirectives(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	migrations := source.NewMigrations()
+	migrations.Append(&source.Migration{Version: 1, Direction: source.Up, Identifier: "-- migrate:no-transaction\nCREATE 1"})
+	migrations.Append(&source.Migration{Version: 2, Direction: source.Up, Identifier: "-- migrate:timeout=\nCREATE 2"})
+	m.sourceDrv.(*sStub.Stub).Migrations = migrations
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.Steps(1); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("-- migrate:no-transaction\nCREATE 1")}, dbDrv)
+	if !dbDrv.LastRunOptions.NoTransaction {
+		t.Fatalf("expected no-transaction directive to be passed to the driver, got %+v", dbDrv.LastRunOptions)
+	}
+
+	// invalid directives fail the migration
+	if err := m.Steps(1); err == nil {
+		t.Fatal("expected error for invalid directive")
+	}
+}
+
+func TestUpWithTags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_foo.up.sql":        &fstest.MapFile{Data: []byte("CREATE 1")},
+		"1_foo.down.sql":      &fstest.MapFile{Data: []byte("DROP 1")},
+		"2_demo@dev.up.sql":   &fstest.MapFile{Data: []byte("INSERT 2")},
+		"2_demo@dev.down.sql": &fstest.MapFile{Data: []byte("DELETE 2")},
+		"3_fix.up.sql":        &fstest.MapFile{Data: []byte("-- migrate:tags=prod\nUPDATE 3")},
+		"4_bar.up.sql":        &fstest.MapFile{Data: []byte("CREATE 4")},
+	}
+	newMigrate := func() (*Migrate, *dStub.Stub) {
+		srcDrv, err := iofs.New(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		dbDrv := m.databaseDrv.(*dStub.Stub)
+
+		if err := m.WithTags([]string{"prod"}, nil).Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:tags=prod\nUPDATE 3"), mr("CREATE 4")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 {
+			t.Fatalf("expected version 4, got %v", dbDrv.CurrentVersion)
+		}
+		if !reflect.DeepEqual(dbDrv.Skipped, map[int]string{2: "demo@dev"}) {
+			t.Fatalf("expected skipped migration 2, got %v", dbDrv.Skipped)
+		}
+		return m, dbDrv
+	}
+
+	t.Run("down", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.Down(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("-- migrate:tags=prod\nUPDATE 3"), mr("CREATE 4"), mr("DROP 1")}, dbDrv)
+		if len(dbDrv.Skipped) != 0 {
+			t.Fatalf("expected no skipped migrations, got %v", dbDrv.Skipped)
+		}
+	})
+
+	t.Run("up without tags", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.WithTags(nil, nil).Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("-- migrate:tags=prod\nUPDATE 3"), mr("CREATE 4"), mr("INSERT 2")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 || dbDrv.IsDirty {
+			t.Fatalf("expected clean version 4, got %v", dbDrv.CurrentVersion)
+		}
+		if len(dbDrv.Skipped) != 0 {
+			t.Fatalf("expected no skipped migrations, got %v", dbDrv.Skipped)
+		}
+		if err := m.Up(); err != ErrNoChange {
+			t.Fatalf("expected ErrNoChange, got %v", err)
+		}
+	})
+
+	t.Run("dotted qualifier", func(t *testing.T) {
+		srcDrv, err := iofs.New(fstest.MapFS{
+			"1_foo.up.sql":      &fstest.MapFile{Data: []byte("CREATE 1")},
+			"4_demo.dev.up.sql": &fstest.MapFile{Data: []byte("INSERT 4")},
+		}, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		dbDrv := m.databaseDrv.(*dStub.Stub)
+
+		if err := m.WithTags(nil, []string{"dev"}).Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1")}, dbDrv)
+		if !reflect.DeepEqual(dbDrv.Skipped, map[int]string{4: "demo.dev"}) {
+			t.Fatalf("expected skipped migration 4, got %v", dbDrv.Skipped)
+		}
+	})
+
+	t.Run("driver without ledger", func(t *testing.T) {
+		srcDrv, err := iofs.New(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		m.databaseDrv = struct{ database.Driver }{m.databaseDrv}
+
+		if err := m.WithTags([]string{"prod"}, nil).Up(); err != ErrTagsNotSupported {
+			t.Fatalf("expected ErrTagsNotSupported, got %v", err)
+		}
+	})
+}
+
+func TestUpPhase(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_add_a.up.sql":   &fstest.MapFile{Data: []byte("CREATE 1")},
+		"1_add_a.down.sql": &fstest.MapFile{Data: []byte("DROP 1")},
+		"2_drop_b.up.sql":  &fstest.MapFile{Data: []byte("-- migrate:phase=contract\nDROP 2")},
+		"3_add_c.up.sql":   &fstest.MapFile{Data: []byte("-- migrate:phase=expand\nCREATE 3")},
+		"3_add_c.down.sql": &fstest.MapFile{Data: []byte("DROP 3")},
+		"4_drop_d.up.sql":  &fstest.MapFile{Data: []byte("-- migrate:phase=contract\nDROP 4")},
+	}
+	newMigrate := func() (*Migrate, *dStub.Stub) {
+		srcDrv, err := iofs.New(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		return m, m.databaseDrv.(*dStub.Stub)
+	}
+
+	t.Run("expand then contract", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.UpPhase("contract"); err != (ErrPhaseOrder{Version: 2, Expand: 1}) {
+			t.Fatalf("expected ErrPhaseOrder, got %v", err)
+		}
+
+		if err := m.UpPhase("expand"); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3")}, dbDrv)
+		if dbDrv.CurrentVersion != 1 || !dbDrv.Phases["expand"][3] {
+			t.Fatalf("expected version 1 and expand version 3, got %v and %v", dbDrv.CurrentVersion, dbDrv.Phases["expand"])
+		}
+
+		if err := m.UpPhase("expand"); err != ErrNoChange {
+			t.Fatalf("expected ErrNoChange, got %v", err)
+		}
+
+		if err := m.UpPhase("contract"); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3"),
+			mr("-- migrate:phase=contract\nDROP 2"), mr("-- migrate:phase=contract\nDROP 4")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 || dbDrv.IsDirty {
+			t.Fatalf("expected clean version 4, got %v (dirty %v)", dbDrv.CurrentVersion, dbDrv.IsDirty)
+		}
+		if len(dbDrv.Phases["expand"]) != 0 || len(dbDrv.Phases["contract"]) != 0 {
+			t.Fatalf("expected no versions ahead of the version, got %v", dbDrv.Phases)
+		}
+	})
+
+	t.Run("expand then down then up", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.UpPhase("expand"); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Down(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3"), mr("DROP 3"), mr("DROP 1")}, dbDrv)
+		if dbDrv.CurrentVersion != database.NilVersion || len(dbDrv.Phases["expand"]) != 0 {
+			t.Fatalf("expected nil version and no expand versions, got %v and %v", dbDrv.CurrentVersion, dbDrv.Phases["expand"])
+		}
+
+		if err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3"), mr("DROP 3"), mr("DROP 1"),
+			mr("CREATE 1"), mr("-- migrate:phase=contract\nDROP 2"), mr("-- migrate:phase=expand\nCREATE 3"), mr("-- migrate:phase=contract\nDROP 4")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 {
+			t.Fatalf("expected version 4, got %v", dbDrv.CurrentVersion)
+		}
+	})
+
+	t.Run("expand then steps", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.UpPhase("expand"); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Steps(-1); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3"), mr("DROP 3")}, dbDrv)
+		if dbDrv.CurrentVersion != 1 || len(dbDrv.Phases["expand"]) != 0 {
+			t.Fatalf("expected version 1 and no expand versions, got %v and %v", dbDrv.CurrentVersion, dbDrv.Phases["expand"])
+		}
+	})
+
+	t.Run("expand then up", func(t *testing.T) {
+		m, dbDrv := newMigrate()
+
+		if err := m.UpPhase("expand"); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:phase=expand\nCREATE 3"),
+			mr("-- migrate:phase=contract\nDROP 2"), mr("-- migrate:phase=contract\nDROP 4")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 {
+			t.Fatalf("expected version 4, got %v", dbDrv.CurrentVersion)
+		}
+	})
+
+	t.Run("dependency graph", func(t *testing.T) {
+		srcDrv, err := iofs.New(fstest.MapFS{
+			"1_base.up.sql":   &fstest.MapFile{Data: []byte("CREATE 1")},
+			"4_orders.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=5\nCREATE 4")},
+			"5_users.up.sql":  &fstest.MapFile{Data: []byte("CREATE 5")},
+		}, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		if err := m.WithDependencyGraph(); err != nil {
+			t.Fatal(err)
+		}
+		dbDrv := m.databaseDrv.(*dStub.Stub)
+
+		if err := m.UpPhase("expand"); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("CREATE 5"), mr("-- migrate:depends_on=5\nCREATE 4")}, dbDrv)
+		if dbDrv.CurrentVersion != 4 {
+			t.Fatalf("expected version 4, got %v", dbDrv.CurrentVersion)
+		}
+	})
+
+	t.Run("unknown phase", func(t *testing.T) {
+		m, _ := newMigrate()
+		if err := m.UpPhase("migrate"); err == nil {
+			t.Fatal("expected error for unknown phase")
+		}
+	})
+}
+
+func TestUpDependencyGraph(t *testing.T) {
+	newMigrate := func(fsys fstest.MapFS, dbDrv *dStub.Stub) *Migrate {
+		srcDrv, err := iofs.New(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := NewWithInstance("iofs", srcDrv, "stub", dbDrv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.WithDependencyGraph(); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	t.Run("merged late", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_base.up.sql":     &fstest.MapFile{Data: []byte("CREATE 1")},
+			"1_base.down.sql":   &fstest.MapFile{Data: []byte("DROP 1")},
+			"3_orders.up.sql":   &fstest.MapFile{Data: []byte("-- migrate:depends_on=1\nCREATE 3")},
+			"3_orders.down.sql": &fstest.MapFile{Data: []byte("DROP 3")},
+		}
+		dbDrv, _ := dStub.WithInstance(nil, &dStub.Config{})
+		stub := dbDrv.(*dStub.Stub)
+
+		if err := newMigrate(fsys, stub).Up(); err != nil {
+			t.Fatal(err)
+		}
+
+		// a migration of another branch, with a lower version, is merged
+		fsys["2_users.up.sql"] = &fstest.MapFile{Data: []byte("-- migrate:depends_on=1\nCREATE 2")}
+		fsys["2_users.down.sql"] = &fstest.MapFile{Data: []byte("DROP 2")}
+		m := newMigrate(fsys, stub)
+		if err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("-- migrate:depends_on=1\nCREATE 3"),
+			mr("-- migrate:depends_on=1\nCREATE 2")}, stub)
+		if stub.CurrentVersion != 3 || len(stub.Applied) != 3 {
+			t.Fatalf("expected version 3 with 3 applied migrations, got %v and %v", stub.CurrentVersion, stub.Applied)
+		}
+
+		if err := m.Up(); err != ErrNoChange {
+			t.Fatalf("expected ErrNoChange, got %v", err)
+		}
+
+		if err := m.Down(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("-- migrate:depends_on=1\nCREATE 3"),
+			mr("-- migrate:depends_on=1\nCREATE 2"), mr("DROP 3"), mr("DROP 2"), mr("DROP 1")}, stub)
+		if stub.CurrentVersion != database.NilVersion || len(stub.Applied) != 0 {
+			t.Fatalf("expected nil version without applied migrations, got %v and %v", stub.CurrentVersion, stub.Applied)
+		}
+	})
+
+	t.Run("dependency on a higher version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_base.up.sql":     &fstest.MapFile{Data: []byte("CREATE 1")},
+			"4_orders.up.sql":   &fstest.MapFile{Data: []byte("-- migrate:depends_on=5\nCREATE 4")},
+			"4_orders.down.sql": &fstest.MapFile{Data: []byte("DROP 4")},
+			"5_users.up.sql":    &fstest.MapFile{Data: []byte("CREATE 5")},
+			"5_users.down.sql":  &fstest.MapFile{Data: []byte("DROP 5")},
+		}
+		dbDrv, _ := dStub.WithInstance(nil, &dStub.Config{})
+		stub := dbDrv.(*dStub.Stub)
+		m := newMigrate(fsys, stub)
+
+		if err := m.Up(); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("CREATE 5"), mr("-- migrate:depends_on=5\nCREATE 4")}, stub)
+		if stub.CurrentVersion != 4 {
+			t.Fatalf("expected version 4, got %v", stub.CurrentVersion)
+		}
+
+		if err := m.Steps(-2); err != nil {
+			t.Fatal(err)
+		}
+		equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("CREATE 5"), mr("-- migrate:depends_on=5\nCREATE 4"),
+			mr("DROP 4"), mr("DROP 5")}, stub)
+		if stub.CurrentVersion != 1 {
+			t.Fatalf("expected version 1, got %v", stub.CurrentVersion)
+		}
+
+		if err := m.Migrate(4); err != nil {
+			t.Fatal(err)
+		}
+		if stub.CurrentVersion != 4 || !stub.Applied[4] || !stub.Applied[5] {
+			t.Fatalf("expected version 4 with 4 and 5 applied, got %v and %v", stub.CurrentVersion, stub.Applied)
+		}
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"1_a.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=2\nCREATE 1")},
+			"2_b.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=1\nCREATE 2")},
+		}
+		srcDrv, err := iofs.New(fsys, ".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+		var errCycle graph.ErrCycle
+		if err := m.WithDependencyGraph(); !errors.As(err, &errCycle) {
+			t.Fatalf("expected ErrCycle, got %v", err)
+		}
+	})
+}
+
+func TestUpAndDownGoMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_foo.up.sql":   &fstest.MapFile{Data: []byte("CREATE 1")},
+		"1_foo.down.sql": &fstest.MapFile{Data: []byte("DROP 1")},
+		"3_foo.up.sql":   &fstest.MapFile{Data: []byte("CREATE 3")},
+		"3_foo.down.sql": &fstest.MapFile{Data: []byte("DROP 3")},
+	}
+	fsDrv, err := iofs.New(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goRun := func(body string) gofunc.Func {
+		return func(ctx context.Context, db database.Driver) error {
+			return db.Run(strings.NewReader(body))
+		}
+	}
+	srcDrv, err := gofunc.WithInstance(fsDrv,
+		&gofunc.Migration{Version: 2, Identifier: "backfill", Up: goRun("GO UP 2"), Down: goRun("GO DOWN 2")},
+		&gofunc.Migration{Version: 4, Identifier: "fail", Up: func(ctx context.Context, db database.Driver) error {
+			return errors.New("go migration failed")
+		}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("gofunc", srcDrv, "stub://")
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.Up(); err == nil {
+		t.Fatal("expected error of Go migration 4")
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("GO UP 2"), mr("CREATE 3")}, dbDrv)
+	if dbDrv.CurrentVersion != 4 || !dbDrv.IsDirty {
+		t.Fatalf("expected dirty version 4, got %v (dirty %v)", dbDrv.CurrentVersion, dbDrv.IsDirty)
+	}
+
+	if err := m.Force(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Down(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("GO UP 2"), mr("CREATE 3"), mr("DROP 3"), mr("GO DOWN 2"), mr("DROP 1")}, dbDrv)
+}
+
+func TestRegisterGoMigration(t *testing.T) {
+	defer func() {
+		goMigrations = nil
+	}()
+	RegisterGoMigration(2, goMigrationUp, nil)
+
+	m, _ := New("stub://", "stub://")
+	fr, ok := m.sourceDrv.(gofunc.FuncReader)
+	if !ok {
+		t.Fatal("expected registered Go migrations to be merged with the source")
+	}
+	fn, identifier, err := fr.ReadFunc(2, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn == nil || identifier != "v4.goMigrationUp" {
+		t.Fatalf("expected func v4.goMigrationUp, got %q", identifier)
+	}
+}
+
+func goMigrationUp(ctx context.Context, db database.Driver) error {
+	return nil
+}
+
+func TestUpPhaseGoMigrations(t *testing.T) {
+	defer func() {
+		goMigrations = nil
+	}()
+	RegisterGoMigration(3, func(ctx context.Context, db database.Driver) error {
+		return db.Run(strings.NewReader("GO UP 3"))
+	}, nil)
+
+	fsys := fstest.MapFS{
+		"1_add_a.up.sql":  &fstest.MapFile{Data: []byte("CREATE 1")},
+		"2_drop_b.up.sql": &fstest.MapFile{Data: []byte("-- migrate:phase=contract\nDROP 2")},
+	}
+	srcDrv, err := iofs.New(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.UpPhase("expand"); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("CREATE 1"), mr("GO UP 3")}, dbDrv)
+	if dbDrv.CurrentVersion != 1 || !dbDrv.Phases["expand"][3] {
+		t.Fatalf("expected version 1 and expand version 3, got %v and %v", dbDrv.CurrentVersion, dbDrv.Phases["expand"])
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 1, migrationSequence{mr("CREATE 1"), mr("GO UP 3"), mr("-- migrate:phase=contract\nDROP 2")}, dbDrv)
+	if dbDrv.CurrentVersion != 3 {
+		t.Fatalf("expected version 3, got %v", dbDrv.CurrentVersion)
+	}
+}
+
+func TestUpAndDownNamespaces(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_foo.up.sql":         &fstest.MapFile{Data: []byte("CREATE 1")},
+		"1_foo.down.sql":       &fstest.MapFile{Data: []byte("DROP 1")},
+		"http/1_foo.up.json":   &fstest.MapFile{Data: []byte("HTTP CREATE 1")},
+		"http/1_foo.down.json": &fstest.MapFile{Data: []byte("HTTP DROP 1")},
+		"http/2_foo.up.json":   &fstest.MapFile{Data: []byte("HTTP CREATE 2")},
+		"http/2_foo.down.json": &fstest.MapFile{Data: []byte("HTTP DROP 2")},
+	}
+	srcDrv, err := iofs.NewWithConfig(fsys, ".", &iofs.Config{Namespaces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 0, migrationSequence{mr("CREATE 1")}, dbDrv)
+	equalDbSeq(t, 1, migrationSequence{mr("HTTP CREATE 1"), mr("HTTP CREATE 2")}, dbDrv.Namespaces["http"])
+	if dbDrv.CurrentVersion != 1 || dbDrv.Namespaces["http"].CurrentVersion != 2 {
+		t.Fatalf("expected versions 1 and 2, got %v and %v", dbDrv.CurrentVersion, dbDrv.Namespaces["http"].CurrentVersion)
+	}
+
+	if err := m.Up(); err != ErrNoChange {
+		t.Fatalf("expected ErrNoChange, got %v", err)
+	}
+
+	if err := m.Down(); err != nil {
+		t.Fatal(err)
+	}
+	equalDbSeq(t, 2, migrationSequence{mr("CREATE 1"), mr("DROP 1")}, dbDrv)
+	equalDbSeq(t, 3, migrationSequence{mr("HTTP CREATE 1"), mr("HTTP CREATE 2"), mr("HTTP DROP 2"), mr("HTTP DROP 1")}, dbDrv.Namespaces["http"])
+}
+
 func TestLock(t *testing.T) {
 	m, _ := New("stub://", "stub://")
 	if err := m.lock(); err != nil {
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"time"
+
+	"github.com/golang-migrate/migrate/v4/source/gofunc"
 )
 
 // DefaultBufferSize sets the in memory buffer size (in Bytes) for every
@@ -33,6 +35,10 @@ type Migration struct {
 	// BufferedBody holds an buffered io.Reader to the underlying Body.
 	BufferedBody io.Reader
 
+	// Func holds a migration written in Go, see RegisterGoMigration.
+	// Body is nil for such migrations.
+	Func gofunc.Func
+
 	// BufferSize defaults to DefaultBufferSize
 	BufferSize uint
 
@@ -54,6 +60,10 @@ type Migration struct {
 
 	// BytesRead holds the number of Bytes read from the migration source.
 	BytesRead int64
+
+	// down is set for down migrations read by Migrate, whose TargetVersion
+	// may be higher than Version if the source is not in version order.
+	down bool
 }
 
 // NewMigration returns a new Migration and sets the body, identifier,
@@ -110,12 +120,25 @@ func (m *Migration) String() string {
 // LogString returns a string describing this migration to humans.
 func (m *Migration) LogString() string {
 	directionStr := "u"
-	if m.TargetVersion < int(m.Version) {
+	if m.isDown() {
 		directionStr = "d"
 	}
 	return fmt.Sprintf("%v/%v %v", m.Version, directionStr, m.Identifier)
 }
 
+// Direction returns "down" for down migrations and "up" otherwise.
+func (m *Migration) Direction() string {
+	if m.isDown() {
+		return "down"
+	}
+	return "up"
+}
+
+// isDown reports whether m is a down migration.
+func (m *Migration) isDown() bool {
+	return m.down || m.TargetVersion < int(m.Version)
+}
+
 // Buffer buffers Body up to BufferSize.
 // Calling this function blocks. Call with goroutine.
 func (m *Migration) Buffer() error {
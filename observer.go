@@ -0,0 +1,67 @@
+package migrate
+
+import "time"
+
+// Observer is an interface so you can watch the migrations run by Migrate,
+// e.g. to collect metrics or trace migrations, see the metrics and tracing
+// packages.
+type Observer interface {
+
+	// MigrationRun is called after a migration ran against the database
+	// with the time it took and the error of the migration, if any.
+	MigrationRun(databaseName string, migr *Migration, duration time.Duration, err error)
+
+	// LockAcquired is called after waiting for the database lock with the
+	// time it took and the error, if the lock could not be acquired.
+	LockAcquired(databaseName string, wait time.Duration, err error)
+
+	// VersionChanged is called after the version of the database was set.
+	VersionChanged(databaseName string, version int, dirty bool)
+}
+
+// MultiObserver returns an Observer that notifies all observers in order,
+// e.g. to collect metrics and trace migrations.
+func MultiObserver(observers ...Observer) Observer {
+	return multiObserver(observers)
+}
+
+type multiObserver []Observer
+
+func (o multiObserver) MigrationRun(databaseName string, migr *Migration, duration time.Duration, err error) {
+	for _, observer := range o {
+		observer.MigrationRun(databaseName, migr, duration, err)
+	}
+}
+
+func (o multiObserver) LockAcquired(databaseName string, wait time.Duration, err error) {
+	for _, observer := range o {
+		observer.LockAcquired(databaseName, wait, err)
+	}
+}
+
+func (o multiObserver) VersionChanged(databaseName string, version int, dirty bool) {
+	for _, observer := range o {
+		observer.VersionChanged(databaseName, version, dirty)
+	}
+}
+
+// observeRun calls run and notifies the Observer about the migration.
+func (m *Migrate) observeRun(migr *Migration, run func() error) error {
+	start := time.Now()
+	err := run()
+	if m.Observer != nil {
+		m.Observer.MigrationRun(m.databaseName, migr, time.Since(start), err)
+	}
+	return err
+}
+
+// setVersion sets the version of the database and notifies the Observer.
+func (m *Migrate) setVersion(version int, dirty bool) error {
+	if err := m.databaseDrv.SetVersion(version, dirty); err != nil {
+		return err
+	}
+	if m.Observer != nil {
+		m.Observer.VersionChanged(m.databaseName, version, dirty)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package migrate
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	sStub "github.com/golang-migrate/migrate/v4/source/stub"
+)
+
+type recordingObserver struct {
+	events []string
+}
+
+func (o *recordingObserver) MigrationRun(databaseName string, migr *Migration, duration time.Duration, err error) {
+	o.events = append(o.events, fmt.Sprintf("run %v %v->%v %v", databaseName, migr.Version, migr.TargetVersion, err))
+}
+
+func (o *recordingObserver) LockAcquired(databaseName string, wait time.Duration, err error) {
+	o.events = append(o.events, fmt.Sprintf("lock %v %v", databaseName, err))
+}
+
+func (o *recordingObserver) VersionChanged(databaseName string, version int, dirty bool) {
+	o.events = append(o.events, fmt.Sprintf("version %v %v %v", databaseName, version, dirty))
+}
+
+func TestObserver(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+	o := &recordingObserver{}
+	m.Observer = o
+
+	if err := m.Migrate(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Steps(-1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Force(3); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"lock stub <nil>",
+		"version stub 1 true",
+		"run stub 1->1 <nil>",
+		"version stub 1 false",
+		"lock stub <nil>",
+		"version stub -1 true",
+		"run stub 1->-1 <nil>",
+		"version stub -1 false",
+		"lock stub <nil>",
+		"version stub 3 false",
+	}
+	if !reflect.DeepEqual(o.events, expected) {
+		t.Errorf("Incorrect events were: %v wanted %v", o.events, expected)
+	}
+}
+
+func TestMultiObserver(t *testing.T) {
+	a, b := &recordingObserver{}, &recordingObserver{}
+	o := MultiObserver(a, b)
+	o.LockAcquired("stub", 0, nil)
+	o.MigrationRun("stub", &Migration{Version: 1, TargetVersion: 1}, 0, nil)
+	o.VersionChanged("stub", 1, false)
+
+	expected := []string{"lock stub <nil>", "run stub 1->1 <nil>", "version stub 1 false"}
+	for _, r := range []*recordingObserver{a, b} {
+		if !reflect.DeepEqual(r.events, expected) {
+			t.Errorf("Incorrect events were: %v wanted %v", r.events, expected)
+		}
+	}
+}
@@ -0,0 +1,333 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+// ErrPhaseOrder is returned by UpPhase if a pending contract migration
+// follows an expand migration that is not applied yet.
+type ErrPhaseOrder struct {
+	Version uint
+	Expand  uint
+}
+
+// Error implements the error interface.
+func (e ErrPhaseOrder) Error() string {
+	return fmt.Sprintf("contract migration %v requires expand migration %v to be applied first", e.Version, e.Expand)
+}
+
+// phasedVersion is a version of the source with the phase of its up migration.
+type phasedVersion struct {
+	version uint
+	phase   string
+}
+
+// UpPhase applies the pending migrations of phase, database.PhaseExpand or
+// database.PhaseContract, in order. Migrations declare their phase with a
+// "-- migrate:phase=expand" directive, migrations without phase belong to
+// the expand phase. A contract migration is only applied once all expand
+// migrations preceding it are applied, otherwise ErrPhaseOrder is returned.
+//
+// The database driver tracks the versions applied per phase, see
+// database.PhaseTracker. The regular version moves up to the highest version
+// up to which all migrations are applied, so Up and UpPhase can be mixed.
+// Down, Steps and Migrate revert the migrations applied by UpPhase above the
+// version before they move the version down.
+func (m *Migrate) UpPhase(phase string) error {
+	if phase != database.PhaseExpand && phase != database.PhaseContract {
+		return fmt.Errorf("unknown phase %q, must be %q or %q", phase, database.PhaseExpand, database.PhaseContract)
+	}
+
+	if namespaces := m.Namespaces(); len(namespaces) > 0 {
+		return m.eachNamespace(namespaces, func(nm *Migrate) error {
+			return nm.UpPhase(phase)
+		})
+	}
+
+	tracker, ok := m.databaseDrv.(database.PhaseTracker)
+	if !ok {
+		return ErrPhasesNotSupported
+	}
+
+	if err := m.lock(); err != nil {
+		return err
+	}
+
+	curVersion, dirty, err := m.databaseDrv.Version()
+	if err != nil {
+		return m.unlockErr(err)
+	}
+
+	if dirty {
+		return m.unlockErr(ErrDirty{curVersion})
+	}
+
+	versions, err := m.phasedVersions()
+	if err != nil {
+		return m.unlockErr(err)
+	}
+
+	applied, err := m.phaseVersions()
+	if err != nil {
+		return m.unlockErr(err)
+	}
+	isApplied := func(pv phasedVersion) bool {
+		return !m.before(curVersion, int(pv.version)) || applied[pv.version] != ""
+	}
+
+	changed := false
+	for i, pv := range versions {
+		if m.stop() {
+			break
+		}
+		if pv.phase != phase || isApplied(pv) {
+			continue
+		}
+
+		if phase == database.PhaseContract {
+			for _, prev := range versions[:i] {
+				if prev.phase == database.PhaseExpand && !isApplied(prev) {
+					return m.unlockErr(ErrPhaseOrder{Version: pv.version, Expand: prev.version})
+				}
+			}
+		}
+
+		if err := m.runPhase(tracker, pv, curVersion); err != nil {
+			return m.unlockErr(err)
+		}
+		applied[pv.version] = phase
+		changed = true
+
+		// move the version up to the last migration all predecessors of
+		// which are applied, too
+		for _, next := range versions {
+			if !m.before(curVersion, int(next.version)) {
+				continue
+			}
+			if !isApplied(next) {
+				break
+			}
+			curVersion = int(next.version)
+		}
+		if err := m.setVersion(curVersion, false); err != nil {
+			return m.unlockErr(err)
+		}
+
+		// the version covers these now
+		for v := range applied {
+			if m.before(curVersion, int(v)) {
+				continue
+			}
+			if err := forgetPhase(tracker, applied, v); err != nil {
+				return m.unlockErr(err)
+			}
+		}
+	}
+
+	if !changed {
+		return m.unlockErr(ErrNoChange)
+	}
+	return m.unlockErr(nil)
+}
+
+// runPhase applies the up migration of pv and records it for its phase.
+// The database is marked dirty at curVersion while the migration runs.
+func (m *Migrate) runPhase(tracker database.PhaseTracker, pv phasedVersion, curVersion int) error {
+	startTime := time.Now()
+
+	migr, err := m.newMigration(pv.version, int(pv.version))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := migr.Buffer(); err != nil {
+			m.logErr(err)
+		}
+	}()
+
+	if err := m.setVersion(curVersion, true); err != nil {
+		return err
+	}
+
+	if migr.Body != nil {
+		m.logDebug("Read and execute "+migr.LogString(), migrationFields(migr)...)
+		if err := m.observeRun(migr, func() error { return m.run(migr.BufferedBody) }); err != nil {
+			return err
+		}
+	} else if migr.Func != nil {
+		m.logDebug("Execute "+migr.LogString(), migrationFields(migr)...)
+		if err := m.observeRun(migr, func() error { return m.runFunc(migr.Func) }); err != nil {
+			return err
+		}
+	}
+
+	if err := tracker.SetPhaseApplied(pv.phase, int(pv.version), true); err != nil {
+		return err
+	}
+
+	duration := time.Since(startTime)
+	m.logInfo(fmt.Sprintf("%v (%v, %v)", migr.LogString(), pv.phase, duration), migrationFields(migr, "phase", pv.phase, "duration", duration)...)
+	return nil
+}
+
+// downPhases reverts up to limit, or all if limit is -1, of the migrations
+// applied by UpPhase above curVersion, the highest first. The database is
+// marked dirty at curVersion while each runs. It returns the number of
+// reverted migrations.
+func (m *Migrate) downPhases(curVersion int, limit int) (int, error) {
+	tracker, ok := m.databaseDrv.(database.PhaseTracker)
+	if !ok || limit == 0 {
+		return 0, nil
+	}
+
+	applied, err := m.phaseVersions()
+	if err != nil {
+		return 0, err
+	}
+	var versions []int
+	for v := range applied {
+		if m.before(curVersion, int(v)) {
+			versions = append(versions, int(v))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return m.before(versions[j], versions[i]) })
+
+	count := 0
+	for _, v := range versions {
+		if m.stop() || (limit != -1 && count >= limit) {
+			break
+		}
+
+		migr, err := m.newMigration(uint(v), curVersion)
+		if err != nil {
+			return count, err
+		}
+		go func() {
+			if err := migr.Buffer(); err != nil {
+				m.logErr(err)
+			}
+		}()
+
+		if err := m.runAt(migr, curVersion); err != nil {
+			return count, err
+		}
+		if err := forgetPhase(tracker, applied, uint(v)); err != nil {
+			return count, err
+		}
+		if err := m.setVersion(curVersion, false); err != nil {
+			return count, err
+		}
+		count++
+		m.logInfo("Reverted "+migr.LogString(), migrationFields(migr)...)
+	}
+	return count, nil
+}
+
+// phaseVersions returns the phases of the versions applied by UpPhase that
+// the version didn't move past yet, or nil if the database driver doesn't
+// track phases.
+func (m *Migrate) phaseVersions() (map[uint]string, error) {
+	tracker, ok := m.databaseDrv.(database.PhaseTracker)
+	if !ok {
+		return nil, nil
+	}
+	applied := make(map[uint]string)
+	for _, phase := range []string{database.PhaseExpand, database.PhaseContract} {
+		versions, err := tracker.PhaseVersions(phase)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			applied[uint(v)] = phase
+		}
+	}
+	return applied, nil
+}
+
+// forgetPhase removes version from the versions applied by UpPhase, once it
+// is reverted or the version moved past it.
+func forgetPhase(tracker database.PhaseTracker, applied map[uint]string, version uint) error {
+	phase, ok := applied[version]
+	if !ok {
+		return nil
+	}
+	delete(applied, version)
+	return tracker.SetPhaseApplied(phase, int(version), false)
+}
+
+// phasedVersions returns all versions of the source in order.
+func (m *Migrate) phasedVersions() ([]phasedVersion, error) {
+	var versions []phasedVersion
+	version, err := m.sourceDrv.First()
+	for {
+		if errors.Is(err, os.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		phase, errPhase := m.phaseOf(version)
+		if errPhase != nil {
+			return nil, errPhase
+		}
+		versions = append(versions, phasedVersion{version: version, phase: phase})
+
+		version, err = m.sourceDrv.Next(version)
+	}
+}
+
+// phaseOf returns the phase declared by the up migration of version.
+func (m *Migrate) phaseOf(version uint) (string, error) {
+	r, _, err := m.sourceDrv.ReadUp(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return database.PhaseExpand, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	options, _, err := database.ParseDirectives(r)
+	if errClose := r.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return "", err
+	}
+	if options.Phase == "" {
+		return database.PhaseExpand, nil
+	}
+	return options.Phase, nil
+}
+
+// appliedInPhase reports whether the up migration migr was already applied
+// by UpPhase, see phaseVersions. The body of such a migration is drained.
+func appliedInPhase(migr *Migration, applied map[uint]string) (bool, error) {
+	if migr.isDown() || applied[migr.Version] == "" {
+		return false, nil
+	}
+	if migr.Body != nil {
+		if _, err := io.Copy(ioutil.Discard, migr.BufferedBody); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// recordPhase forgets the version of migr as applied by UpPhase, once the
+// version moved past it.
+func (m *Migrate) recordPhase(migr *Migration, applied map[uint]string) error {
+	tracker, ok := m.databaseDrv.(database.PhaseTracker)
+	if !ok {
+		return nil
+	}
+	return forgetPhase(tracker, applied, migr.Version)
+}
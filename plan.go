@@ -0,0 +1,392 @@
+package migrate
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// PlannedMigration is a migration that Up, Down, Steps or Migrate would run,
+// see PlanUp, PlanDown and PlanMigrate.
+type PlannedMigration struct {
+	// Namespace is the namespace of the migration, "" for the root namespace.
+	Namespace  string
+	Version    uint
+	Identifier string
+	Direction  source.Direction
+	// Skipped is set if the migration is not run, but the version moves past
+	// it, e.g. because it is not selected by the tags of m.
+	Skipped bool
+	// Repeatable is set for repeatable migrations, which have no version.
+	Repeatable bool
+}
+
+// PlanUp returns the migrations that Up would run, or Steps(limit) if limit
+// is not -1, in order. It reads the database, but changes nothing.
+func (m *Migrate) PlanUp(limit int) ([]PlannedMigration, error) {
+	if limit == -1 {
+		if namespaces := m.Namespaces(); len(namespaces) > 0 {
+			return m.planNamespaces(namespaces, func(nm *Migrate) ([]PlannedMigration, error) {
+				return nm.PlanUp(limit)
+			})
+		}
+	}
+
+	curVersion, err := m.planVersion()
+	if err != nil {
+		return nil, err
+	}
+	if limit != -1 {
+		return m.plan(func(ret chan<- interface{}) { m.readUp(curVersion, limit, ret) })
+	}
+
+	planned, err := m.planSkipped(curVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var up []PlannedMigration
+	if tracker, ok := m.appliedTracker(); ok {
+		up, err = m.planGraph(tracker, curVersion)
+	} else {
+		up, err = m.plan(func(ret chan<- interface{}) { m.readUp(curVersion, -1, ret) })
+	}
+	if err != nil {
+		return nil, err
+	}
+	planned = append(planned, up...)
+
+	repeatables, err := m.planRepeatables()
+	if err != nil {
+		return nil, err
+	}
+	return append(planned, repeatables...), nil
+}
+
+// PlanDown returns the migrations that Down would run, or Steps(-limit) if
+// limit is not -1, in order. It reads the database, but changes nothing.
+func (m *Migrate) PlanDown(limit int) ([]PlannedMigration, error) {
+	if limit == -1 {
+		if namespaces := m.Namespaces(); len(namespaces) > 0 {
+			reversed := make([]string, 0, len(namespaces))
+			for i := len(namespaces) - 1; i >= 0; i-- {
+				reversed = append(reversed, namespaces[i])
+			}
+			return m.planNamespaces(reversed, func(nm *Migrate) ([]PlannedMigration, error) {
+				return nm.PlanDown(limit)
+			})
+		}
+	}
+
+	curVersion, err := m.planVersion()
+	if err != nil {
+		return nil, err
+	}
+	planned, err := m.planPhases(curVersion, limit)
+	if err != nil {
+		return nil, err
+	}
+	if limit != -1 {
+		limit -= len(planned)
+	}
+	down, err := m.plan(func(ret chan<- interface{}) { m.readDown(curVersion, limit, ret) })
+	if err != nil {
+		return nil, err
+	}
+	return append(planned, down...), nil
+}
+
+// PlanMigrate returns the migrations that Migrate(version) would run, in
+// order. It reads the database, but changes nothing.
+func (m *Migrate) PlanMigrate(version uint) ([]PlannedMigration, error) {
+	curVersion, err := m.planVersion()
+	if err != nil {
+		return nil, err
+	}
+	var planned []PlannedMigration
+	if !m.before(curVersion, int(version)) {
+		if planned, err = m.planPhases(curVersion, -1); err != nil {
+			return nil, err
+		}
+	}
+	migrations, err := m.plan(func(ret chan<- interface{}) { m.read(curVersion, int(version), ret) })
+	if err != nil {
+		return nil, err
+	}
+	return append(planned, migrations...), nil
+}
+
+// planNamespaces returns the migrations fn plans for each namespace in turn.
+func (m *Migrate) planNamespaces(namespaces []string, fn func(*Migrate) ([]PlannedMigration, error)) ([]PlannedMigration, error) {
+	var planned []PlannedMigration
+	for _, name := range namespaces {
+		nm, err := m.WithNamespace(name)
+		if err != nil {
+			return nil, err
+		}
+		migrations, err := fn(nm)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range migrations {
+			p.Namespace = name
+			planned = append(planned, p)
+		}
+	}
+	return planned, nil
+}
+
+// planVersion returns the version of the database, unless it is dirty.
+func (m *Migrate) planVersion() (int, error) {
+	curVersion, dirty, err := m.databaseDrv.Version()
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, ErrDirty{curVersion}
+	}
+	return curVersion, nil
+}
+
+// plan returns the migrations sent by read, see runMigrations. The bodies of
+// the migrations are drained without running them.
+func (m *Migrate) plan(read func(ret chan<- interface{})) (planned []PlannedMigration, err error) {
+	skipped, err := m.skippedVersions()
+	if err != nil {
+		return nil, err
+	}
+	phased, err := m.phaseVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(chan interface{}, m.PrefetchMigrations)
+	go read(ret)
+	defer func() {
+		// unblock the reader and the buffering of the remaining migrations
+		for r := range ret {
+			if migr, ok := r.(*Migration); ok {
+				if errDrain := drain(migr); err == nil {
+					err = errDrain
+				}
+			}
+		}
+	}()
+
+	planned = []PlannedMigration{}
+	for r := range ret {
+		switch r := r.(type) {
+		case error:
+			if errors.Is(r, ErrNoChange) {
+				return planned, nil
+			}
+			return nil, r
+
+		case *Migration:
+			var skip bool
+			if r.isDown() {
+				skip, err = skipOnDown(r, skipped)
+			} else {
+				skip, err = m.skipByTags(r)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if !skip {
+				if skip, err = appliedInPhase(r, phased); err != nil {
+					return nil, err
+				}
+			}
+			if err := drain(r); err != nil {
+				return nil, err
+			}
+			planned = append(planned, plannedMigration(r, skip))
+		}
+	}
+	return planned, nil
+}
+
+// planSkipped returns the migrations skipped before that Up applies now,
+// see upSkipped.
+func (m *Migrate) planSkipped(curVersion int) ([]PlannedMigration, error) {
+	sr, ok := m.databaseDrv.(database.SkipRecorder)
+	if !ok {
+		return nil, nil
+	}
+	versions, err := sr.SkippedVersions()
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(versions)
+
+	var planned []PlannedMigration
+	for _, v := range versions {
+		if !m.before(v, curVersion) && v != curVersion {
+			continue
+		}
+		migr, err := m.newMigration(uint(v), v)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			if err := migr.Buffer(); err != nil {
+				m.logErr(err)
+			}
+		}()
+		skip, err := m.skipByTags(migr)
+		if err == nil {
+			err = drain(migr)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !skip {
+			planned = append(planned, plannedMigration(migr, false))
+		}
+	}
+	return planned, nil
+}
+
+// planGraph returns the migrations that upGraph applies. Unlike
+// appliedVersions, it doesn't record the migrations up to curVersion as
+// applied if none are recorded yet.
+func (m *Migrate) planGraph(tracker database.AppliedTracker, curVersion int) ([]PlannedMigration, error) {
+	versions, err := tracker.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[uint]bool, len(versions))
+	for _, v := range versions {
+		applied[uint(v)] = true
+	}
+	assumeApplied := len(applied) == 0 && curVersion != database.NilVersion
+
+	var pending []uint
+	version, err := m.sourceDrv.First()
+	for ; err == nil; version, err = m.sourceDrv.Next(version) {
+		if assumeApplied {
+			assumeApplied = int(version) != curVersion
+			continue
+		}
+		if !applied[version] {
+			pending = append(pending, version)
+		}
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return m.plan(func(ret chan<- interface{}) {
+		defer close(ret)
+		for _, v := range pending {
+			migr, err := m.newMigration(v, int(v))
+			if err != nil {
+				ret <- err
+				return
+			}
+			ret <- migr
+			go func() {
+				if err := migr.Buffer(); err != nil {
+					m.logErr(err)
+				}
+			}()
+		}
+	})
+}
+
+// planPhases returns the migrations applied by UpPhase above curVersion that
+// downPhases reverts, the highest first.
+func (m *Migrate) planPhases(curVersion int, limit int) ([]PlannedMigration, error) {
+	if _, ok := m.databaseDrv.(database.PhaseTracker); !ok || limit == 0 {
+		return nil, nil
+	}
+	applied, err := m.phaseVersions()
+	if err != nil {
+		return nil, err
+	}
+	var versions []int
+	for v := range applied {
+		if m.before(curVersion, int(v)) {
+			versions = append(versions, int(v))
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return m.before(versions[j], versions[i]) })
+	if limit != -1 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+
+	var planned []PlannedMigration
+	for _, v := range versions {
+		migr, err := m.newMigration(uint(v), curVersion)
+		if err != nil {
+			return nil, err
+		}
+		if migr.Body != nil {
+			if err := migr.Body.Close(); err != nil {
+				return nil, err
+			}
+		}
+		planned = append(planned, plannedMigration(migr, false))
+	}
+	return planned, nil
+}
+
+// planRepeatables returns the repeatable migrations that changed since they
+// were last applied, see runRepeatables.
+func (m *Migrate) planRepeatables() ([]PlannedMigration, error) {
+	rr, ok := m.sourceDrv.(source.RepeatableReader)
+	if !ok {
+		return nil, nil
+	}
+	identifiers, err := rr.Repeatables()
+	if err != nil || len(identifiers) == 0 {
+		return nil, err
+	}
+	tracker, ok := m.databaseDrv.(database.RepeatableTracker)
+	if !ok {
+		return nil, ErrRepeatablesNotSupported
+	}
+
+	var planned []PlannedMigration
+	for _, identifier := range identifiers {
+		_, checksum, err := readRepeatable(rr, identifier)
+		if err != nil {
+			return nil, err
+		}
+		recorded, err := tracker.RepeatableChecksum(identifier)
+		if err != nil {
+			return nil, err
+		}
+		if recorded != checksum {
+			planned = append(planned, PlannedMigration{Identifier: identifier, Direction: source.Up, Repeatable: true})
+		}
+	}
+	return planned, nil
+}
+
+func plannedMigration(migr *Migration, skipped bool) PlannedMigration {
+	p := PlannedMigration{
+		Version:    migr.Version,
+		Identifier: migr.Identifier,
+		Direction:  source.Up,
+		Skipped:    skipped,
+	}
+	if migr.isDown() {
+		p.Direction = source.Down
+	}
+	return p
+}
+
+// drain reads the rest of the body of migr, so that its buffering finishes.
+func drain(migr *Migration) error {
+	if migr.Body == nil {
+		return nil
+	}
+	_, err := io.Copy(ioutil.Discard, migr.BufferedBody)
+	return err
+}
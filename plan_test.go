@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func TestPlan(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_foo.up.sql":        &fstest.MapFile{Data: []byte("CREATE 1")},
+		"1_foo.down.sql":      &fstest.MapFile{Data: []byte("DROP 1")},
+		"2_demo.dev.up.sql":   &fstest.MapFile{Data: []byte("INSERT 2")},
+		"2_demo.dev.down.sql": &fstest.MapFile{Data: []byte("DELETE 2")},
+		"3_bar.up.sql":        &fstest.MapFile{Data: []byte("CREATE 3")},
+		"3_bar.down.sql":      &fstest.MapFile{Data: []byte("DROP 3")},
+	}
+	srcDrv, err := iofs.New(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+	m.WithTags(nil, []string{"dev"})
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+
+	planned, err := m.PlanUp(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PlannedMigration{
+		{Version: 1, Identifier: "foo", Direction: source.Up},
+		{Version: 2, Identifier: "demo.dev", Direction: source.Up, Skipped: true},
+		{Version: 3, Identifier: "bar", Direction: source.Up},
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("Incorrect up plan was: %+v wanted %+v", planned, expected)
+	}
+	if len(dbDrv.MigrationSequence) != 0 || dbDrv.CurrentVersion != -1 {
+		t.Fatalf("The plan changed the database: %v", dbDrv.MigrationSequence)
+	}
+
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if planned, err = m.PlanUp(-1); err != nil || len(planned) != 0 {
+		t.Errorf("Incorrect plan after up was: %+v, %v", planned, err)
+	}
+
+	planned, err = m.PlanDown(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []PlannedMigration{
+		{Version: 3, Identifier: "bar", Direction: source.Down},
+		{Version: 2, Identifier: "demo.dev", Direction: source.Down, Skipped: true},
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("Incorrect down plan was: %+v wanted %+v", planned, expected)
+	}
+
+	planned, err = m.PlanMigrate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("Incorrect goto plan was: %+v wanted %+v", planned, expected)
+	}
+}
+
+func TestPlanDependencyGraph(t *testing.T) {
+	srcDrv, err := iofs.New(fstest.MapFS{
+		"1_base.up.sql":   &fstest.MapFile{Data: []byte("CREATE 1")},
+		"4_orders.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=5\nCREATE 4")},
+		"5_users.up.sql":  &fstest.MapFile{Data: []byte("CREATE 5")},
+	}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+	if err := m.WithDependencyGraph(); err != nil {
+		t.Fatal(err)
+	}
+
+	planned, err := m.PlanUp(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []uint
+	for _, p := range planned {
+		versions = append(versions, p.Version)
+	}
+	if expected := []uint{1, 5, 4}; !reflect.DeepEqual(versions, expected) {
+		t.Errorf("Incorrect plan was: %v wanted %v", versions, expected)
+	}
+}
@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"io"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/schemadiff"
+)
+
+// DumpSchema writes the schema of the database to w, see database.SchemaDumper.
+// It returns ErrSchemaDumpNotSupported if the database driver can't dump its schema.
+func (m *Migrate) DumpSchema(w io.Writer) error {
+	d, ok := m.databaseDrv.(database.SchemaDumper)
+	if !ok {
+		return ErrSchemaDumpNotSupported
+	}
+	return d.DumpSchema(w)
+}
+
+// InspectSchema returns the schema of the database, see database.SchemaInspector.
+// It returns ErrSchemaInspectNotSupported if the database driver can't inspect its schema.
+func (m *Migrate) InspectSchema() (*schemadiff.Schema, error) {
+	d, ok := m.databaseDrv.(database.SchemaInspector)
+	if !ok {
+		return nil, ErrSchemaInspectNotSupported
+	}
+	return d.InspectSchema()
+}
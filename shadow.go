@@ -0,0 +1,161 @@
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	iurl "github.com/golang-migrate/migrate/v4/internal/url"
+)
+
+var (
+	// ErrShadowNotEmpty is returned by ValidateOnShadow if the shadow database
+	// has a version or a schema. Shadow databases are dropped, so migrate
+	// refuses to use a database that may hold data.
+	ErrShadowNotEmpty = errors.New("shadow database is not empty, refusing to use it")
+
+	// ErrShadowNotSupported is returned by ValidateOnShadow if the driver of
+	// the shadow database can neither dump nor inspect its schema, so migrate
+	// can't tell whether the database is empty.
+	ErrShadowNotSupported = errors.New("shadow database driver can't tell whether the database is empty, refusing to use it")
+
+	// ErrShadowNamespaces is returned by ValidateOnShadow if the source has
+	// namespaces. Only the root namespace would be replayed on the shadow
+	// database.
+	ErrShadowNamespaces = errors.New("shadow database does not support namespaced migrations")
+)
+
+// ErrShadow is returned by ValidateOnShadow if the migrations failed on the
+// shadow database.
+type ErrShadow struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e ErrShadow) Error() string {
+	return fmt.Sprintf("shadow database: %v", e.Err)
+}
+
+// Unwrap returns the error of the shadow run.
+func (e ErrShadow) Unwrap() error {
+	return e.Err
+}
+
+// ValidateOnShadow proves that migrations apply cleanly before they are run
+// against the database of m. It opens the empty shadow database at
+// databaseURL, replays the migrations of m up to the current version of m's
+// database, calls run with a Migrate instance for the shadow database and
+// finally drops everything inside the shadow database.
+//
+// run should apply the migrations that are about to be applied to m, e.g.
+//  func(shadow *Migrate) error { return shadow.Up() }
+// ErrNoChange returned by run is not an error. Other errors of the shadow run
+// are returned as ErrShadow. The shadow database must have no version and an
+// empty schema, see ErrShadowNotEmpty, and sources with namespaces are not
+// supported, see ErrShadowNamespaces.
+func (m *Migrate) ValidateOnShadow(databaseURL string, run func(shadow *Migrate) error) (err error) {
+	databaseName, err := iurl.SchemeFromURL(databaseURL)
+	if err != nil {
+		return err
+	}
+	databaseDrv, err := database.Open(databaseURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if errClose := databaseDrv.Close(); err == nil {
+			err = errClose
+		}
+	}()
+	return m.ValidateOnShadowInstance(databaseName, databaseDrv, run)
+}
+
+// ValidateOnShadowInstance is like ValidateOnShadow, but uses an existing
+// database instance as shadow database. Use any string that can serve as an
+// identifier during logging as databaseName. You are responsible for closing
+// the underlying database client if necessary.
+func (m *Migrate) ValidateOnShadowInstance(databaseName string, databaseInstance database.Driver, run func(shadow *Migrate) error) error {
+	if len(m.Namespaces()) > 0 {
+		return ErrShadowNamespaces
+	}
+	if err := checkShadowEmpty(databaseInstance); err != nil {
+		return err
+	}
+
+	curVersion, dirty, err := m.Version()
+	if err != nil && err != ErrNilVersion {
+		return err
+	}
+	if dirty {
+		return ErrDirty{int(curVersion)}
+	}
+
+	shadow := newCommon()
+	shadow.sourceName = m.sourceName
+	shadow.sourceDrv = m.sourceDrv
+	shadow.databaseName = databaseName
+	shadow.databaseDrv = databaseInstance
+	shadow.Log = m.Log
+	shadow.PrefetchMigrations = m.PrefetchMigrations
+	shadow.LockTimeout = m.LockTimeout
+	shadow.tags = m.tags
+	shadow.excludeTags = m.excludeTags
+
+	err = m.runOnShadow(shadow, err == ErrNilVersion, curVersion, run)
+	if errDrop := shadow.Drop(); err == nil {
+		err = errDrop
+	}
+	return err
+}
+
+// checkShadowEmpty returns ErrShadowNotEmpty unless the shadow database has
+// no version and an empty schema. The schema is read with
+// database.SchemaDumper or database.SchemaInspector.
+func checkShadowEmpty(d database.Driver) error {
+	version, _, err := d.Version()
+	if err != nil {
+		return err
+	}
+	if version != database.NilVersion {
+		return ErrShadowNotEmpty
+	}
+
+	switch d := d.(type) {
+	case database.SchemaDumper:
+		var schema bytes.Buffer
+		if err := d.DumpSchema(&schema); err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(schema.Bytes())) > 0 {
+			return ErrShadowNotEmpty
+		}
+	case database.SchemaInspector:
+		schema, err := d.InspectSchema()
+		if err != nil {
+			return err
+		}
+		if len(schema.Tables) > 0 || len(schema.Views) > 0 {
+			return ErrShadowNotEmpty
+		}
+	default:
+		return ErrShadowNotSupported
+	}
+	return nil
+}
+
+// runOnShadow replays the migrations up to version, unless the database of m
+// has no version, and calls run.
+func (m *Migrate) runOnShadow(shadow *Migrate, nilVersion bool, version uint, run func(shadow *Migrate) error) error {
+	if !nilVersion {
+		m.logDebug(fmt.Sprintf("Replaying migrations up to version %v on shadow database", version), "version", version)
+		if err := shadow.Migrate(version); err != nil && err != ErrNoChange {
+			return ErrShadow{err}
+		}
+	}
+	m.logDebug("Applying pending migrations on shadow database")
+	if err := run(shadow); err != nil && err != ErrNoChange {
+		return ErrShadow{err}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package migrate
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	dStub "github.com/golang-migrate/migrate/v4/database/stub"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	sStub "github.com/golang-migrate/migrate/v4/source/stub"
+)
+
+func TestValidateOnShadow(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+	dbDrv := m.databaseDrv.(*dStub.Stub)
+	if err := m.Migrate(3); err != nil {
+		t.Fatal(err)
+	}
+
+	shadowDrv, _ := dStub.WithInstance(nil, &dStub.Config{})
+	shadow := shadowDrv.(*dStub.Stub)
+	if err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return s.Up()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"CREATE 1", "CREATE 3", "CREATE 4", "CREATE 7", dStub.DROP}
+	if !reflect.DeepEqual(shadow.MigrationSequence, expected) {
+		t.Errorf("Incorrect shadow sequence was: %v wanted %v", shadow.MigrationSequence, expected)
+	}
+	if version, _, _ := m.Version(); version != 3 || len(dbDrv.MigrationSequence) != 2 {
+		t.Errorf("The shadow run changed the database: version %v, sequence %v", version, dbDrv.MigrationSequence)
+	}
+
+	// nothing to apply
+	if err := m.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return s.Up()
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateOnShadowErrors(t *testing.T) {
+	m, _ := New("stub://", "stub://")
+	m.sourceDrv.(*sStub.Stub).Migrations = sourceStubMigrations
+
+	shadowDrv, _ := dStub.WithInstance(nil, &dStub.Config{})
+	shadow := shadowDrv.(*dStub.Stub)
+	errRun := errors.New("migration failed")
+	err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return errRun
+	})
+	var shadowErr ErrShadow
+	if !errors.As(err, &shadowErr) || !errors.Is(err, errRun) {
+		t.Errorf("Incorrect error was: %v wanted %v", err, ErrShadow{errRun})
+	}
+	if shadow.CurrentVersion != -1 || len(shadow.MigrationSequence) != 1 || shadow.MigrationSequence[0] != dStub.DROP {
+		t.Errorf("The shadow database was not dropped: version %v, sequence %v", shadow.CurrentVersion, shadow.MigrationSequence)
+	}
+
+	if err := shadowDrv.SetVersion(1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return s.Up()
+	}); err != ErrShadowNotEmpty {
+		t.Errorf("Incorrect error was: %v wanted %v", err, ErrShadowNotEmpty)
+	}
+
+	shadowDrv, _ = dStub.WithInstance(nil, &dStub.Config{})
+	shadowDrv.(*dStub.Stub).Schema = "CREATE TABLE t (id int);"
+	if err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return s.Up()
+	}); err != ErrShadowNotEmpty {
+		t.Errorf("Incorrect error was: %v wanted %v", err, ErrShadowNotEmpty)
+	}
+	if seq := shadowDrv.(*dStub.Stub).MigrationSequence; len(seq) != 0 {
+		t.Errorf("The shadow database was changed: sequence %v", seq)
+	}
+
+	if err := m.ValidateOnShadow("stub://", func(s *Migrate) error {
+		return s.Up()
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateOnShadowNamespaces(t *testing.T) {
+	fsys := fstest.MapFS{
+		"1_foo.up.sql":       &fstest.MapFile{Data: []byte("CREATE 1")},
+		"http/1_foo.up.json": &fstest.MapFile{Data: []byte("HTTP CREATE 1")},
+	}
+	srcDrv, err := iofs.NewWithConfig(fsys, ".", &iofs.Config{Namespaces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, _ := NewWithSourceInstance("iofs", srcDrv, "stub://")
+
+	shadowDrv, _ := dStub.WithInstance(nil, &dStub.Config{})
+	if err := m.ValidateOnShadowInstance("stub", shadowDrv, func(s *Migrate) error {
+		return s.Up()
+	}); err != ErrShadowNamespaces {
+		t.Errorf("Incorrect error was: %v wanted %v", err, ErrShadowNamespaces)
+	}
+	if seq := shadowDrv.(*dStub.Stub).MigrationSequence; len(seq) != 0 {
+		t.Errorf("The shadow database was changed: sequence %v", seq)
+	}
+}
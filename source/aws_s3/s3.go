@@ -28,6 +28,10 @@ type s3Driver struct {
 type Config struct {
 	Bucket string
 	Prefix string
+
+	// Parser turns file names into migrations. Defaults to
+	// source.DefaultParse.
+	Parser source.Parser
 }
 
 func (s *s3Driver) Open(folder string) (source.Driver, error) {
@@ -69,10 +73,16 @@ func parseURI(uri string) (*Config, error) {
 		prefix += "/"
 	}
 
-	return &Config{
+	config := &Config{
 		Bucket: u.Host,
 		Prefix: prefix,
-	}, nil
+	}
+	if s := u.Query().Get("x-parser"); len(s) > 0 {
+		if config.Parser, err = source.ParserByName(s); err != nil {
+			return nil, fmt.Errorf("Unable to parse option x-parser: %w", err)
+		}
+	}
+	return config, nil
 }
 
 func (s *s3Driver) loadMigrations() error {
@@ -84,9 +94,13 @@ func (s *s3Driver) loadMigrations() error {
 	if err != nil {
 		return err
 	}
+	parse := s.config.Parser
+	if parse == nil {
+		parse = source.DefaultParse
+	}
 	for _, object := range output.Contents {
 		_, fileName := path.Split(aws.StringValue(object.Key))
-		m, err := source.DefaultParse(fileName)
+		m, err := parse(fileName)
 		if err != nil {
 			continue
 		}
@@ -148,5 +162,12 @@ func (s *s3Driver) open(m *source.Migration) (io.ReadCloser, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	if m.SingleFile {
+		body, err := source.ReadSection(object.Body, m.Direction, key)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, m.Identifier, nil
+	}
 	return object.Body, m.Identifier, nil
 }
@@ -176,8 +176,7 @@ func (b *Bitbucket) ReadUp(version uint) (r io.ReadCloser, identifier string, er
 			return nil, "", err
 		}
 		if file != nil {
-			r := file.Content
-			return ioutil.NopCloser(strings.NewReader(string(r))), m.Identifier, nil
+			return b.open(m, file.Content)
 		}
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: b.config.Path, Err: os.ErrNotExist}
@@ -199,10 +198,22 @@ func (b *Bitbucket) ReadDown(version uint) (r io.ReadCloser, identifier string,
 			return nil, "", err
 		}
 		if file != nil {
-			r := file.Content
-
-			return ioutil.NopCloser(strings.NewReader(string(r))), m.Identifier, nil
+			return b.open(m, file.Content)
 		}
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: b.config.Path, Err: os.ErrNotExist}
 }
+
+// open returns the content of the file of m, the section of the direction of
+// m for single file migrations.
+func (b *Bitbucket) open(m *source.Migration, content []byte) (io.ReadCloser, string, error) {
+	r := ioutil.NopCloser(strings.NewReader(string(content)))
+	if m.SingleFile {
+		body, err := source.ReadSection(r, m.Direction, m.Raw)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, m.Identifier, nil
+	}
+	return r, m.Identifier, nil
+}
@@ -72,6 +72,42 @@ type Driver interface {
 	ReadDown(version uint) (r io.ReadCloser, identifier string, err error)
 }
 
+// RepeatableReader is an optional interface for source drivers that provide
+// repeatable migrations. Repeatable migrations have no version, Migrate
+// applies them after all versioned migrations whenever their content changed.
+type RepeatableReader interface {
+	// Repeatables returns the identifiers of all repeatable migrations, sorted.
+	Repeatables() ([]string, error)
+
+	// ReadRepeatable returns the body of the repeatable migration with the
+	// given identifier. If there is no such migration, it must return
+	// os.ErrNotExist. Do not start reading, just return the ReadCloser!
+	ReadRepeatable(identifier string) (r io.ReadCloser, err error)
+}
+
+// Namespacer is an optional interface for source drivers that group their
+// migrations into namespaces, each with its own independent version sequence.
+// Migrate runs every namespace on its own, see migrate.Migrate.Namespaces.
+type Namespacer interface {
+	// Namespaces returns the sorted names of all namespaces available to the
+	// driver. The root namespace is named "". Drivers that were not configured
+	// to use namespaces must return nil.
+	Namespaces() []string
+
+	// Namespace returns a driver that only reads the migrations of the
+	// given namespace. Closing it must not close the parent driver.
+	Namespace(name string) (Driver, error)
+}
+
+// Orderer is an optional interface for source drivers whose First, Next and
+// Prev walk the migrations in another order than the numeric order of their
+// versions, e.g. the dependency order of source/graph.
+type Orderer interface {
+	// Position returns the position of version in the order of First and
+	// Next, and false if there is no such version.
+	Position(version uint) (pos int, ok bool)
+}
+
 // Open returns a new driver instance.
 func Open(url string) (Driver, error) {
 	u, err := nurl.Parse(url)
@@ -1,9 +1,11 @@
 package file
 
 import (
+	"fmt"
 	nurl "net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/golang-migrate/migrate/v4/source"
 )
@@ -42,3 +44,34 @@ func parseURL(url string) (string, error) {
 	}
 	return p, nil
 }
+
+// options holds the x- options of a file URL.
+type options struct {
+	recursive  bool
+	namespaces bool
+	parser     source.Parser
+}
+
+// parseOptions reads the x-recursive, x-namespaces and x-parser options from url.
+func parseOptions(url string) (*options, error) {
+	u, err := nurl.Parse(url)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	o := &options{}
+	if s := q.Get("x-recursive"); len(s) > 0 {
+		if o.recursive, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("Unable to parse option x-recursive: %w", err)
+		}
+	}
+	if s := q.Get("x-namespaces"); len(s) > 0 {
+		if o.namespaces, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("Unable to parse option x-namespaces: %w", err)
+		}
+	}
+	if o.parser, err = source.ParserByName(q.Get("x-parser")); err != nil {
+		return nil, fmt.Errorf("Unable to parse option x-parser: %w", err)
+	}
+	return o, nil
+}
@@ -3,6 +3,7 @@
 package file
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/golang-migrate/migrate/v4/source"
@@ -21,11 +22,19 @@ func (f *File) Open(url string) (source.Driver, error) {
 		return nil, err
 	}
 
+	o, err := parseOptions(url)
+	if err != nil {
+		return nil, err
+	}
+	if o.recursive || o.namespaces {
+		return nil, errors.New("x-recursive and x-namespaces require go1.16")
+	}
+
 	nf := &File{
 		url:  url,
 		path: p,
 	}
-	if err := nf.Init(http.Dir(p), ""); err != nil {
+	if err := nf.InitWithParser(http.Dir(p), "", o.parser); err != nil {
 		return nil, err
 	}
 	return nf, nil
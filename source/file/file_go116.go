@@ -20,11 +20,20 @@ func (f *File) Open(url string) (source.Driver, error) {
 	if err != nil {
 		return nil, err
 	}
+	o, err := parseOptions(url)
+	if err != nil {
+		return nil, err
+	}
 	nf := &File{
 		url:  url,
 		path: p,
 	}
-	if err := nf.Init(os.DirFS(p), "."); err != nil {
+	config := &iofs.Config{
+		Recursive:  o.recursive,
+		Namespaces: o.namespaces,
+		Parser:     o.parser,
+	}
+	if err := nf.InitWithConfig(os.DirFS(p), ".", config); err != nil {
 		return nil, err
 	}
 	return nf, nil
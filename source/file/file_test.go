@@ -242,3 +242,95 @@ func BenchmarkNext(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+func TestOpenRecursive(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestOpenRecursive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := os.Mkdir(filepath.Join(tmpDir, "http"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	mustWriteFile(t, tmpDir, "1_foobar.up.sql", "")
+	mustWriteFile(t, filepath.Join(tmpDir, "http"), "2_foobar.up.json", "")
+
+	f := &File{}
+	d, err := f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Next(1); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected subdirectories to be ignored, got %v", err)
+	}
+
+	d, err = f.Open("file://" + tmpDir + "?x-recursive=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, err := d.Next(1); err != nil || next != 2 {
+		t.Fatalf("expected next version 2, got %v (%v)", next, err)
+	}
+
+	d, err = f.Open("file://" + tmpDir + "?x-namespaces=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns := d.(*File).Namespaces(); len(ns) != 2 || ns[1] != "http" {
+		t.Fatalf("expected namespaces [\"\" http], got %v", ns)
+	}
+
+	if _, err := f.Open("file://" + tmpDir + "?x-recursive=foo"); err == nil {
+		t.Fatal("expected err")
+	}
+}
+
+func TestOpenWithParser(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "TestOpenWithParser")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	mustWriteFile(t, tmpDir, "V1__create_users.sql", "1 up")
+	mustWriteFile(t, tmpDir, "U1__create_users.sql", "1 down")
+	mustWriteFile(t, tmpDir, "V2__add_email.sql", "2 up")
+
+	f := &File{}
+	d, err := f.Open("file://" + tmpDir + "?x-parser=flyway")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, err := d.Next(1); err != nil || next != 2 {
+		t.Fatalf("expected next version 2, got %v (%v)", next, err)
+	}
+	r, identifier, err := d.ReadDown(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if identifier != "create_users" {
+		t.Fatalf("expected identifier create_users, got %v", identifier)
+	}
+
+	d, err = f.Open("file://" + tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.First(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected default parser to ignore flyway files, got %v", err)
+	}
+
+	if _, err := f.Open("file://" + tmpDir + "?x-parser=foo"); err == nil {
+		t.Fatal("expected err")
+	}
+}
@@ -39,6 +39,10 @@ type Config struct {
 	Repo  string
 	Path  string
 	Ref   string
+
+	// Parser turns file names into migrations. Defaults to
+	// source.DefaultParse.
+	Parser source.Parser
 }
 
 func (g *Github) Open(url string) (source.Driver, error) {
@@ -70,6 +74,10 @@ func (g *Github) Open(url string) (source.Driver, error) {
 
 	gn.ensureFields()
 
+	if gn.config.Parser, err = source.ParserByName(u.Query().Get("x-parser")); err != nil {
+		return nil, fmt.Errorf("Unable to parse option x-parser: %w", err)
+	}
+
 	// set owner, repo and path in repo
 	gn.config.Owner = u.Host
 	pe := strings.Split(strings.Trim(u.Path, "/"), "/")
@@ -121,8 +129,13 @@ func (g *Github) readDirectory() error {
 		return ErrNoDir
 	}
 
+	parse := g.config.Parser
+	if parse == nil {
+		parse = source.DefaultParse
+	}
+
 	for _, fi := range dirContents {
-		m, err := source.DefaultParse(*fi.Name)
+		m, err := parse(*fi.Name)
 		if err != nil {
 			continue // ignore files that we can't parse
 		}
@@ -194,7 +207,13 @@ func (g *Github) ReadUp(version uint) (r io.ReadCloser, identifier string, err e
 			if err != nil {
 				return nil, "", err
 			}
-			return ioutil.NopCloser(strings.NewReader(r)), m.Identifier, nil
+			body := ioutil.NopCloser(strings.NewReader(r))
+			if m.SingleFile {
+				if body, err = source.ReadSection(body, m.Direction, m.Raw); err != nil {
+					return nil, "", err
+				}
+			}
+			return body, m.Identifier, nil
 		}
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: g.config.Path, Err: os.ErrNotExist}
@@ -220,7 +239,13 @@ func (g *Github) ReadDown(version uint) (r io.ReadCloser, identifier string, err
 			if err != nil {
 				return nil, "", err
 			}
-			return ioutil.NopCloser(strings.NewReader(r)), m.Identifier, nil
+			body := ioutil.NopCloser(strings.NewReader(r))
+			if m.SingleFile {
+				if body, err = source.ReadSection(body, m.Direction, m.Raw); err != nil {
+					return nil, "", err
+				}
+			}
+			return body, m.Identifier, nil
 		}
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: g.config.Path, Err: os.ErrNotExist}
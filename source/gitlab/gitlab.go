@@ -8,7 +8,6 @@ import (
 	"net/http"
 	nurl "net/url"
 	"os"
-	"strconv"
 	"strings"
 )
 
@@ -40,9 +39,13 @@ type Gitlab struct {
 	listOptions *gitlab.ListTreeOptions
 	getOptions  *gitlab.GetFileOptions
 	migrations  *source.Migrations
+	parser      source.Parser
 }
 
 type Config struct {
+	// Parser turns file names into migrations. Defaults to
+	// source.DefaultParse.
+	Parser source.Parser
 }
 
 func (g *Gitlab) Open(url string) (source.Driver, error) {
@@ -60,10 +63,16 @@ func (g *Gitlab) Open(url string) (source.Driver, error) {
 		return nil, ErrNoAccessToken
 	}
 
+	parser, err := source.ParserByName(u.Query().Get("x-parser"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse option x-parser: %w", err)
+	}
+
 	gn := &Gitlab{
 		client:     gitlab.NewClient(nil, password),
 		url:        url,
 		migrations: source.NewMigrations(),
+		parser:     parser,
 	}
 
 	if u.Host != "" {
@@ -111,6 +120,9 @@ func WithInstance(client *gitlab.Client, config *Config) (source.Driver, error)
 		client:     client,
 		migrations: source.NewMigrations(),
 	}
+	if config != nil {
+		gn.parser = config.Parser
+	}
 	if err := gn.readDirectory(); err != nil {
 		return nil, err
 	}
@@ -151,20 +163,16 @@ func (g *Gitlab) readDirectory() error {
 }
 
 func (g *Gitlab) nodeToMigration(node *gitlab.TreeNode) (*source.Migration, error) {
-	m := source.Regex.FindStringSubmatch(node.Name)
-	if len(m) == 5 {
-		versionUint64, err := strconv.ParseUint(m[1], 10, 64)
-		if err != nil {
-			return nil, err
-		}
-		return &source.Migration{
-			Version:    uint(versionUint64),
-			Identifier: m[2],
-			Direction:  source.Direction(m[3]),
-			Raw:        g.path + "/" + node.Name,
-		}, nil
-	}
-	return nil, source.ErrParse
+	parse := g.parser
+	if parse == nil {
+		parse = source.DefaultParse
+	}
+	m, err := parse(node.Name)
+	if err != nil {
+		return nil, err
+	}
+	m.Raw = g.path + "/" + node.Name
+	return m, nil
 }
 
 func (g *Gitlab) Close() error {
@@ -211,7 +219,13 @@ func (g *Gitlab) ReadUp(version uint) (r io.ReadCloser, identifier string, err e
 			return nil, "", err
 		}
 
-		return ioutil.NopCloser(strings.NewReader(string(content))), m.Identifier, nil
+		body := ioutil.NopCloser(strings.NewReader(string(content)))
+		if m.SingleFile {
+			if body, err = source.ReadSection(body, m.Direction, m.Raw); err != nil {
+				return nil, "", err
+			}
+		}
+		return body, m.Identifier, nil
 	}
 
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: g.path, Err: os.ErrNotExist}
@@ -233,7 +247,13 @@ func (g *Gitlab) ReadDown(version uint) (r io.ReadCloser, identifier string, err
 			return nil, "", err
 		}
 
-		return ioutil.NopCloser(strings.NewReader(string(content))), m.Identifier, nil
+		body := ioutil.NopCloser(strings.NewReader(string(content)))
+		if m.SingleFile {
+			if body, err = source.ReadSection(body, m.Direction, m.Raw); err != nil {
+				return nil, "", err
+			}
+		}
+		return body, m.Identifier, nil
 	}
 
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: g.path, Err: os.ErrNotExist}
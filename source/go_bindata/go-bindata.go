@@ -98,22 +98,30 @@ func (b *Bindata) Next(version uint) (nextVersion uint, err error) {
 
 func (b *Bindata) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := b.migrations.Up(version); ok {
-		body, err := b.assetSource.AssetFunc(m.Raw)
-		if err != nil {
-			return nil, "", err
-		}
-		return ioutil.NopCloser(bytes.NewReader(body)), m.Identifier, nil
+		return b.open(m)
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: b.path, Err: os.ErrNotExist}
 }
 
 func (b *Bindata) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := b.migrations.Down(version); ok {
-		body, err := b.assetSource.AssetFunc(m.Raw)
-		if err != nil {
+		return b.open(m)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: b.path, Err: os.ErrNotExist}
+}
+
+// open returns the asset of m, the section of the direction of m for single
+// file migrations.
+func (b *Bindata) open(m *source.Migration) (io.ReadCloser, string, error) {
+	body, err := b.assetSource.AssetFunc(m.Raw)
+	if err != nil {
+		return nil, "", err
+	}
+	r := ioutil.NopCloser(bytes.NewReader(body))
+	if m.SingleFile {
+		if r, err = source.ReadSection(r, m.Direction, m.Raw); err != nil {
 			return nil, "", err
 		}
-		return ioutil.NopCloser(bytes.NewReader(body)), m.Identifier, nil
 	}
-	return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: b.path, Err: os.ErrNotExist}
+	return r, m.Identifier, nil
 }
@@ -0,0 +1,246 @@
+// Package gofunc provides migrations written in Go, for data migrations
+// that need more than SQL (batch transforms, calling an API, re-hashing).
+// Go migrations are merged with the migrations of another source driver,
+// so that Migrate interleaves SQL files and Go functions by version.
+package gofunc
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// ErrTxNotSupported is returned by a Func created with Tx if the database
+// driver does not implement database.TxBeginner.
+var ErrTxNotSupported = errors.New("gofunc: database driver does not support transactions")
+
+// Func is a migration written in Go. db is the database driver Migrate runs
+// the migrations against.
+type Func func(ctx context.Context, db database.Driver) error
+
+// Tx returns a Func that runs fn in a transaction, which is committed if
+// fn returns nil. The database driver must implement database.TxBeginner.
+func Tx(fn func(tx *sql.Tx) error) Func {
+	return func(ctx context.Context, db database.Driver) error {
+		b, ok := db.(database.TxBeginner)
+		if !ok {
+			return ErrTxNotSupported
+		}
+		tx, err := b.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			if errRollback := tx.Rollback(); errRollback != nil {
+				return fmt.Errorf("%w (rollback failed: %v)", err, errRollback)
+			}
+			return err
+		}
+		return tx.Commit()
+	}
+}
+
+// Migration is a versioned migration written in Go.
+// Up or Down may be nil, like a missing up or down file.
+type Migration struct {
+	Version    uint
+	Identifier string
+	Up         Func
+	Down       Func
+}
+
+// FuncReader is implemented by source drivers that provide Go migrations.
+// Migrate runs the Func returned by ReadFunc instead of the body returned
+// by ReadUp or ReadDown.
+type FuncReader interface {
+	// ReadFunc returns the Func and identifier of the Go migration of version
+	// in direction. If version is not a Go migration or has no Func in
+	// direction, it must return os.ErrNotExist.
+	ReadFunc(version uint, direction source.Direction) (fn Func, identifier string, err error)
+}
+
+// Driver is a source driver merging Go migrations with the migrations of
+// another source driver.
+type Driver struct {
+	src        source.Driver
+	migrations map[uint]*Migration
+
+	// versions holds the versions of src and of migrations, sorted.
+	versions []uint
+}
+
+// WithInstance returns a driver providing migrations in addition to the
+// migrations of src. src may be nil to use Go migrations only.
+// A version must not be provided by both src and migrations.
+// Closing the driver closes src.
+func WithInstance(src source.Driver, migrations ...*Migration) (source.Driver, error) {
+	d := &Driver{
+		src:        src,
+		migrations: make(map[uint]*Migration),
+	}
+	for _, m := range migrations {
+		if _, dup := d.migrations[m.Version]; dup {
+			return nil, fmt.Errorf("gofunc: duplicate Go migration version %v", m.Version)
+		}
+		d.migrations[m.Version] = m
+		d.versions = append(d.versions, m.Version)
+	}
+
+	if src != nil {
+		version, err := src.First()
+		for err == nil {
+			if _, dup := d.migrations[version]; dup {
+				return nil, fmt.Errorf("gofunc: version %v is provided by the source and by a Go migration", version)
+			}
+			d.versions = append(d.versions, version)
+			version, err = src.Next(version)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	sort.Slice(d.versions, func(i, j int) bool { return d.versions[i] < d.versions[j] })
+	return d, nil
+}
+
+// Open is not supported, Go migrations can't be configured by a URL.
+// Use WithInstance or migrate.RegisterGoMigration.
+func (d *Driver) Open(url string) (source.Driver, error) {
+	return nil, errors.New("gofunc: Open is not supported, use WithInstance")
+}
+
+// Close closes the wrapped source driver.
+func (d *Driver) Close() error {
+	if d.src == nil {
+		return nil
+	}
+	return d.src.Close()
+}
+
+// First implements source.Driver.
+func (d *Driver) First() (version uint, err error) {
+	if len(d.versions) == 0 {
+		return 0, &os.PathError{Op: "first", Path: "gofunc", Err: os.ErrNotExist}
+	}
+	return d.versions[0], nil
+}
+
+// Prev implements source.Driver.
+func (d *Driver) Prev(version uint) (prevVersion uint, err error) {
+	if i, ok := d.findPos(version); ok && i > 0 {
+		return d.versions[i-1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: "gofunc", Err: os.ErrNotExist}
+}
+
+// Next implements source.Driver.
+func (d *Driver) Next(version uint) (nextVersion uint, err error) {
+	if i, ok := d.findPos(version); ok && i+1 < len(d.versions) {
+		return d.versions[i+1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: "gofunc", Err: os.ErrNotExist}
+}
+
+// ReadUp implements source.Driver. The body of a Go migration is empty,
+// Migrate runs the Func returned by ReadFunc instead.
+func (d *Driver) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := d.migrations[version]; ok {
+		return d.read(m, m.Up, source.Up)
+	}
+	if d.src == nil {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: "gofunc", Err: os.ErrNotExist}
+	}
+	return d.src.ReadUp(version)
+}
+
+// ReadDown implements source.Driver. The body of a Go migration is empty,
+// Migrate runs the Func returned by ReadFunc instead.
+func (d *Driver) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	if m, ok := d.migrations[version]; ok {
+		return d.read(m, m.Down, source.Down)
+	}
+	if d.src == nil {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v", version), Path: "gofunc", Err: os.ErrNotExist}
+	}
+	return d.src.ReadDown(version)
+}
+
+func (d *Driver) read(m *Migration, fn Func, direction source.Direction) (io.ReadCloser, string, error) {
+	if fn == nil {
+		return nil, "", &os.PathError{Op: fmt.Sprintf("read version %v %v", m.Version, direction), Path: "gofunc", Err: os.ErrNotExist}
+	}
+	return ioutil.NopCloser(bytes.NewReader(nil)), m.Identifier, nil
+}
+
+// ReadFunc implements FuncReader.
+func (d *Driver) ReadFunc(version uint, direction source.Direction) (fn Func, identifier string, err error) {
+	if m, ok := d.migrations[version]; ok {
+		fn = m.Up
+		if direction == source.Down {
+			fn = m.Down
+		}
+		if fn != nil {
+			return fn, m.Identifier, nil
+		}
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read func version %v %v", version, direction), Path: "gofunc", Err: os.ErrNotExist}
+}
+
+// Repeatables implements source.RepeatableReader by passing through the
+// repeatable migrations of the wrapped source driver.
+func (d *Driver) Repeatables() ([]string, error) {
+	if rr, ok := d.src.(source.RepeatableReader); ok {
+		return rr.Repeatables()
+	}
+	return nil, nil
+}
+
+// ReadRepeatable implements source.RepeatableReader.
+func (d *Driver) ReadRepeatable(identifier string) (r io.ReadCloser, err error) {
+	if rr, ok := d.src.(source.RepeatableReader); ok {
+		return rr.ReadRepeatable(identifier)
+	}
+	return nil, &os.PathError{Op: "read repeatable " + identifier, Path: "gofunc", Err: os.ErrNotExist}
+}
+
+// Namespaces implements source.Namespacer by passing through the namespaces
+// of the wrapped source driver. Go migrations belong to the root namespace.
+func (d *Driver) Namespaces() []string {
+	if ns, ok := d.src.(source.Namespacer); ok {
+		return ns.Namespaces()
+	}
+	return nil
+}
+
+// Namespace implements source.Namespacer.
+func (d *Driver) Namespace(name string) (source.Driver, error) {
+	ns, ok := d.src.(source.Namespacer)
+	if !ok {
+		return nil, fmt.Errorf("gofunc: source driver does not support namespaces")
+	}
+	nd, err := ns.Namespace(name)
+	if err != nil || name != "" {
+		return nd, err
+	}
+
+	migrations := make([]*Migration, 0, len(d.migrations))
+	for _, m := range d.migrations {
+		migrations = append(migrations, m)
+	}
+	return WithInstance(nd, migrations...)
+}
+
+func (d *Driver) findPos(version uint) (int, bool) {
+	i := sort.Search(len(d.versions), func(i int) bool { return d.versions[i] >= version })
+	return i, i < len(d.versions) && d.versions[i] == version
+}
@@ -0,0 +1,87 @@
+package gofunc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	st "github.com/golang-migrate/migrate/v4/source/testing"
+)
+
+func noop(ctx context.Context, db database.Driver) error {
+	return nil
+}
+
+func newFS(t *testing.T) source.Driver {
+	fsys := fstest.MapFS{
+		"1_foobar.up.sql":   &fstest.MapFile{Data: []byte("1 up")},
+		"1_foobar.down.sql": &fstest.MapFile{Data: []byte("1 down")},
+		"4_foobar.up.sql":   &fstest.MapFile{Data: []byte("4 up")},
+		"4_foobar.down.sql": &fstest.MapFile{Data: []byte("4 down")},
+		"7_foobar.up.sql":   &fstest.MapFile{Data: []byte("7 up")},
+		"7_foobar.down.sql": &fstest.MapFile{Data: []byte("7 down")},
+	}
+	d, err := iofs.New(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func Test(t *testing.T) {
+	d, err := WithInstance(newFS(t),
+		&Migration{Version: 3, Identifier: "backfill", Up: noop},
+		&Migration{Version: 5, Identifier: "rehash", Down: noop},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.Test(t, d)
+}
+
+func TestWithInstanceDuplicate(t *testing.T) {
+	if _, err := WithInstance(nil, &Migration{Version: 1, Up: noop}, &Migration{Version: 1, Up: noop}); err == nil {
+		t.Error("expected error for duplicate Go migration")
+	}
+	if _, err := WithInstance(newFS(t), &Migration{Version: 4, Up: noop}); err == nil {
+		t.Error("expected error for Go migration colliding with the source")
+	}
+}
+
+func TestReadFunc(t *testing.T) {
+	d, err := WithInstance(nil, &Migration{Version: 3, Identifier: "backfill", Up: noop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fr := d.(FuncReader)
+
+	fn, identifier, err := fr.ReadFunc(3, source.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn == nil || identifier != "backfill" {
+		t.Errorf("expected func backfill, got %v", identifier)
+	}
+
+	if _, _, err := fr.ReadFunc(3, source.Down); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+	if _, _, err := fr.ReadFunc(4, source.Up); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestTxNotSupported(t *testing.T) {
+	fn := Tx(func(tx *sql.Tx) error {
+		return nil
+	})
+	if err := fn(context.Background(), nil); err != ErrTxNotSupported {
+		t.Errorf("expected ErrTxNotSupported, got %v", err)
+	}
+}
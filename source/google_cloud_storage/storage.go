@@ -115,5 +115,12 @@ func (g *gcs) open(m *source.Migration) (io.ReadCloser, string, error) {
 	if err != nil {
 		return nil, "", err
 	}
+	if m.SingleFile {
+		body, err := source.ReadSection(reader, m.Direction, objectPath)
+		if err != nil {
+			return nil, "", err
+		}
+		return body, m.Identifier, nil
+	}
 	return reader, m.Identifier, nil
 }
@@ -0,0 +1,258 @@
+// Package graph orders the migrations of another source driver by their
+// dependencies instead of by version. Migrations declare the versions they
+// depend on with a "-- migrate:depends_on=V1,V2" directive in their header,
+// so that migrations written on different branches don't conflict over the
+// next version number.
+package graph
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/gofunc"
+)
+
+// ErrCycle is returned by WithInstance if migrations depend on each other
+// in a cycle.
+type ErrCycle struct {
+	// Versions are the versions of the migrations that could not be ordered,
+	// the cycle and the migrations depending on it.
+	Versions []uint
+}
+
+// Error implements the error interface.
+func (e ErrCycle) Error() string {
+	versions := make([]string, 0, len(e.Versions))
+	for _, v := range e.Versions {
+		versions = append(versions, fmt.Sprint(v))
+	}
+	return "graph: dependency cycle between migrations " + strings.Join(versions, ", ")
+}
+
+// ErrMissingDependency is returned by WithInstance if a migration depends on
+// a version the source does not provide.
+type ErrMissingDependency struct {
+	Version    uint
+	Dependency uint
+}
+
+// Error implements the error interface.
+func (e ErrMissingDependency) Error() string {
+	return fmt.Sprintf("graph: migration %v depends on missing migration %v", e.Version, e.Dependency)
+}
+
+// Driver is a source driver walking the migrations of another source driver
+// in topological order: every migration follows its dependencies, migrations
+// without a dependency between them are ordered by version.
+type Driver struct {
+	src source.Driver
+
+	// order holds the versions in topological order, pos the position
+	// of each version in order.
+	order        []uint
+	pos          map[uint]int
+	dependencies map[uint][]uint
+}
+
+// WithInstance returns a driver ordering the migrations of src by the
+// dependencies declared in their up migrations. Closing the driver closes src.
+func WithInstance(src source.Driver) (source.Driver, error) {
+	d := &Driver{
+		src:          src,
+		pos:          make(map[uint]int),
+		dependencies: make(map[uint][]uint),
+	}
+
+	var versions []uint
+	version, err := src.First()
+	for err == nil {
+		deps, errDeps := readDependencies(src, version)
+		if errDeps != nil {
+			return nil, errDeps
+		}
+		versions = append(versions, version)
+		d.dependencies[version] = deps
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if d.order, err = sortTopological(versions, d.dependencies); err != nil {
+		return nil, err
+	}
+	for i, v := range d.order {
+		d.pos[v] = i
+	}
+	return d, nil
+}
+
+// readDependencies returns the versions declared by the depends_on directive
+// of the up migration of version.
+func readDependencies(src source.Driver, version uint) ([]uint, error) {
+	r, _, err := src.ReadUp(version)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	options, _, err := database.ParseDirectives(r)
+	if errClose := r.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		return nil, fmt.Errorf("graph: migration %v: %w", version, err)
+	}
+	return options.DependsOn, nil
+}
+
+// sortTopological orders versions such that every version follows its
+// dependencies. Of the versions whose dependencies are ordered, the lowest
+// comes first, so the order is the version order unless a dependency says
+// otherwise.
+func sortTopological(versions []uint, dependencies map[uint][]uint) ([]uint, error) {
+	pending := make(map[uint]int, len(versions))
+	dependents := make(map[uint][]uint)
+	for _, v := range versions {
+		for _, dep := range dependencies[v] {
+			if _, ok := dependencies[dep]; !ok {
+				return nil, ErrMissingDependency{Version: v, Dependency: dep}
+			}
+			pending[v]++
+			dependents[dep] = append(dependents[dep], v)
+		}
+	}
+
+	ready := &versionHeap{}
+	for _, v := range versions {
+		if pending[v] == 0 {
+			heap.Push(ready, v)
+		}
+	}
+	order := make([]uint, 0, len(versions))
+	for ready.Len() > 0 {
+		v := heap.Pop(ready).(uint)
+		order = append(order, v)
+		for _, dependent := range dependents[v] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				heap.Push(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) < len(versions) {
+		var cycle []uint
+		for _, v := range versions {
+			if pending[v] > 0 {
+				cycle = append(cycle, v)
+			}
+		}
+		return nil, ErrCycle{Versions: cycle}
+	}
+	return order, nil
+}
+
+// versionHeap is a min-heap of versions.
+type versionHeap []uint
+
+func (h versionHeap) Len() int            { return len(h) }
+func (h versionHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h versionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *versionHeap) Push(x interface{}) { *h = append(*h, x.(uint)) }
+func (h *versionHeap) Pop() interface{} {
+	old := *h
+	v := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return v
+}
+
+// Open is not supported, use WithInstance or migrate.Migrate.WithDependencyGraph.
+func (d *Driver) Open(url string) (source.Driver, error) {
+	return nil, errors.New("graph: Open is not supported, use WithInstance")
+}
+
+// Close closes the wrapped source driver.
+func (d *Driver) Close() error {
+	return d.src.Close()
+}
+
+// First implements source.Driver.
+func (d *Driver) First() (version uint, err error) {
+	if len(d.order) == 0 {
+		return 0, &os.PathError{Op: "first", Path: "graph", Err: os.ErrNotExist}
+	}
+	return d.order[0], nil
+}
+
+// Prev implements source.Driver.
+func (d *Driver) Prev(version uint) (prevVersion uint, err error) {
+	if i, ok := d.pos[version]; ok && i > 0 {
+		return d.order[i-1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("prev for version %v", version), Path: "graph", Err: os.ErrNotExist}
+}
+
+// Next implements source.Driver.
+func (d *Driver) Next(version uint) (nextVersion uint, err error) {
+	if i, ok := d.pos[version]; ok && i+1 < len(d.order) {
+		return d.order[i+1], nil
+	}
+	return 0, &os.PathError{Op: fmt.Sprintf("next for version %v", version), Path: "graph", Err: os.ErrNotExist}
+}
+
+// ReadUp implements source.Driver.
+func (d *Driver) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
+	return d.src.ReadUp(version)
+}
+
+// ReadDown implements source.Driver.
+func (d *Driver) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
+	return d.src.ReadDown(version)
+}
+
+// Position implements source.Orderer.
+func (d *Driver) Position(version uint) (pos int, ok bool) {
+	pos, ok = d.pos[version]
+	return pos, ok
+}
+
+// Dependencies returns the versions the migration of version depends on.
+func (d *Driver) Dependencies(version uint) []uint {
+	deps := append([]uint(nil), d.dependencies[version]...)
+	sort.Slice(deps, func(i, j int) bool { return deps[i] < deps[j] })
+	return deps
+}
+
+// ReadFunc implements gofunc.FuncReader by passing through the Go migrations
+// of the wrapped source driver.
+func (d *Driver) ReadFunc(version uint, direction source.Direction) (fn gofunc.Func, identifier string, err error) {
+	if fr, ok := d.src.(gofunc.FuncReader); ok {
+		return fr.ReadFunc(version, direction)
+	}
+	return nil, "", &os.PathError{Op: fmt.Sprintf("read func version %v %v", version, direction), Path: "graph", Err: os.ErrNotExist}
+}
+
+// Repeatables implements source.RepeatableReader by passing through the
+// repeatable migrations of the wrapped source driver.
+func (d *Driver) Repeatables() ([]string, error) {
+	if rr, ok := d.src.(source.RepeatableReader); ok {
+		return rr.Repeatables()
+	}
+	return nil, nil
+}
+
+// ReadRepeatable implements source.RepeatableReader.
+func (d *Driver) ReadRepeatable(identifier string) (r io.ReadCloser, err error) {
+	if rr, ok := d.src.(source.RepeatableReader); ok {
+		return rr.ReadRepeatable(identifier)
+	}
+	return nil, &os.PathError{Op: "read repeatable " + identifier, Path: "graph", Err: os.ErrNotExist}
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+func newSource(t *testing.T, fsys fstest.MapFS) source.Driver {
+	src, err := iofs.New(fsys, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func walk(t *testing.T, d source.Driver) []uint {
+	var versions []uint
+	version, err := d.First()
+	for err == nil {
+		versions = append(versions, version)
+		version, err = d.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	return versions
+}
+
+func TestWithInstance(t *testing.T) {
+	d, err := WithInstance(newSource(t, fstest.MapFS{
+		"1_base.up.sql":     &fstest.MapFile{Data: []byte("CREATE 1")},
+		"2_orders.up.sql":   &fstest.MapFile{Data: []byte("-- migrate:depends_on=4, 3\nCREATE 2")},
+		"2_orders.down.sql": &fstest.MapFile{Data: []byte("DROP 2")},
+		"3_users.up.sql":    &fstest.MapFile{Data: []byte("-- migrate:depends_on=1\nCREATE 3")},
+		"4_items.up.sql":    &fstest.MapFile{Data: []byte("CREATE 4")},
+		"5_index.down.sql":  &fstest.MapFile{Data: []byte("DROP 5")},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if order := walk(t, d); !reflect.DeepEqual(order, []uint{1, 3, 4, 2, 5}) {
+		t.Fatalf("Incorrect order was: %v", order)
+	}
+	if prev, err := d.Prev(2); err != nil || prev != 4 {
+		t.Errorf("Incorrect prev of 2 was: %v (%v) wanted 4", prev, err)
+	}
+	if _, err := d.Prev(1); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Incorrect error was: %v wanted os.ErrNotExist", err)
+	}
+	if pos, ok := d.(source.Orderer).Position(2); !ok || pos != 3 {
+		t.Errorf("Incorrect position of 2 was: %v %v wanted 3", pos, ok)
+	}
+	if deps := d.(*Driver).Dependencies(2); !reflect.DeepEqual(deps, []uint{3, 4}) {
+		t.Errorf("Incorrect dependencies of 2 was: %v", deps)
+	}
+
+	r, identifier, err := d.ReadDown(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if body, _ := ioutil.ReadAll(r); string(body) != "DROP 2" || identifier != "orders" {
+		t.Errorf("Incorrect migration was: %q %q", identifier, body)
+	}
+}
+
+func TestWithInstanceErrors(t *testing.T) {
+	_, err := WithInstance(newSource(t, fstest.MapFS{
+		"1_a.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=7\nCREATE 1")},
+	}))
+	if expected := (ErrMissingDependency{Version: 1, Dependency: 7}); err != expected {
+		t.Errorf("Incorrect error was: %v wanted %v", err, expected)
+	}
+
+	_, err = WithInstance(newSource(t, fstest.MapFS{
+		"1_a.up.sql": &fstest.MapFile{Data: []byte("CREATE 1")},
+		"2_b.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=3\nCREATE 2")},
+		"3_c.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=2\nCREATE 3")},
+		"4_d.up.sql": &fstest.MapFile{Data: []byte("-- migrate:depends_on=3\nCREATE 4")},
+	}))
+	var errCycle ErrCycle
+	if !errors.As(err, &errCycle) || !reflect.DeepEqual(errCycle.Versions, []uint{2, 3, 4}) {
+		t.Errorf("Incorrect error was: %v", err)
+	}
+}
@@ -24,6 +24,15 @@ func New(fs http.FileSystem, path string) (source.Driver, error) {
 	return &d, nil
 }
 
+// NewWithParser is like New, but reads file names with parser.
+func NewWithParser(fs http.FileSystem, path string, parser source.Parser) (source.Driver, error) {
+	var d driver
+	if err := d.InitWithParser(fs, path, parser); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
 // Open completes the implementetion of source.Driver interface. Other methods
 // are implemented by the embedded PartialDriver struct.
 func (d *driver) Open(url string) (source.Driver, error) {
@@ -26,6 +26,16 @@ type PartialDriver struct {
 // Init prepares not initialized PartialDriver instance to read migrations from a
 // http.FileSystem instance and a relative path.
 func (p *PartialDriver) Init(fs http.FileSystem, path string) error {
+	return p.InitWithParser(fs, path, nil)
+}
+
+// InitWithParser is like Init, but reads file names with parser instead of
+// source.DefaultParse. A nil parser falls back to source.DefaultParse.
+func (p *PartialDriver) InitWithParser(fs http.FileSystem, path string, parser source.Parser) error {
+	if parser == nil {
+		parser = source.DefaultParse
+	}
+
 	root, err := fs.Open(path)
 	if err != nil {
 		return err
@@ -46,7 +56,7 @@ func (p *PartialDriver) Init(fs http.FileSystem, path string) error {
 			continue
 		}
 
-		m, err := source.DefaultParse(file.Name())
+		m, err := parser(file.Name())
 		if err != nil {
 			continue // ignore files that we can't parse
 		}
@@ -109,7 +119,7 @@ func (p *PartialDriver) Next(version uint) (nextVersion uint, err error) {
 // ReadUp is part of source.Driver interface implementation.
 func (p *PartialDriver) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := p.migrations.Up(version); ok {
-		body, err := p.open(path.Join(p.path, m.Raw))
+		body, err := p.read(m)
 		if err != nil {
 			return nil, "", err
 		}
@@ -125,7 +135,7 @@ func (p *PartialDriver) ReadUp(version uint) (r io.ReadCloser, identifier string
 // ReadDown is part of source.Driver interface implementation.
 func (p *PartialDriver) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := p.migrations.Down(version); ok {
-		body, err := p.open(path.Join(p.path, m.Raw))
+		body, err := p.read(m)
 		if err != nil {
 			return nil, "", err
 		}
@@ -138,6 +148,42 @@ func (p *PartialDriver) ReadDown(version uint) (r io.ReadCloser, identifier stri
 	}
 }
 
+// Repeatables is part of source.RepeatableReader interface implementation.
+func (p *PartialDriver) Repeatables() ([]string, error) {
+	ms := p.migrations.Repeatables()
+	identifiers := make([]string, 0, len(ms))
+	for _, m := range ms {
+		identifiers = append(identifiers, m.Identifier)
+	}
+	return identifiers, nil
+}
+
+// ReadRepeatable is part of source.RepeatableReader interface implementation.
+func (p *PartialDriver) ReadRepeatable(identifier string) (r io.ReadCloser, err error) {
+	if m, ok := p.migrations.Repeatable(identifier); ok {
+		return p.open(path.Join(p.path, m.Raw))
+	}
+	return nil, &os.PathError{
+		Op:   "read repeatable " + identifier,
+		Path: p.path,
+		Err:  os.ErrNotExist,
+	}
+}
+
+// read opens the file of m. Single file migrations are split and only the
+// section for m.Direction is returned.
+func (p *PartialDriver) read(m *source.Migration) (io.ReadCloser, error) {
+	name := path.Join(p.path, m.Raw)
+	body, err := p.open(name)
+	if err != nil {
+		return nil, err
+	}
+	if !m.SingleFile {
+		return body, nil
+	}
+	return source.ReadSection(body, m.Direction, name)
+}
+
 func (p *PartialDriver) open(path string) (http.File, error) {
 	f, err := p.fs.Open(path)
 	if err == nil {
@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/fs"
 	"path"
+	"sort"
 	"strconv"
 
 	"github.com/golang-migrate/migrate/v4/source"
@@ -17,10 +18,30 @@ type driver struct {
 	PartialDriver
 }
 
+// Config holds optional settings for the iofs driver.
+type Config struct {
+	// Recursive makes Init walk all subdirectories of path, too.
+	// Versions must be unique across the whole tree.
+	Recursive bool
+
+	// Namespaces treats every subdirectory as a namespace with its own
+	// version sequence. Namespaces implies Recursive.
+	Namespaces bool
+
+	// Parser turns file names into migrations. Defaults to
+	// source.DefaultParse.
+	Parser source.Parser
+}
+
 // New returns a new Driver from io/fs#FS and a relative path.
 func New(fsys fs.FS, path string) (source.Driver, error) {
+	return NewWithConfig(fsys, path, &Config{})
+}
+
+// NewWithConfig returns a new Driver from io/fs#FS, a relative path and a Config.
+func NewWithConfig(fsys fs.FS, path string, config *Config) (source.Driver, error) {
 	var i driver
-	if err := i.Init(fsys, path); err != nil {
+	if err := i.InitWithConfig(fsys, path, config); err != nil {
 		return nil, fmt.Errorf("failed to init driver with path %s: %w", path, err)
 	}
 	return &i, nil
@@ -32,6 +53,24 @@ func (d *driver) Open(url string) (source.Driver, error) {
 	return nil, errors.New("Open() cannot be called on the iofs passthrough driver")
 }
 
+// namespaceDriver reads the migrations of a single namespace. It shares the
+// file system with the driver it was created from.
+type namespaceDriver struct {
+	PartialDriver
+}
+
+// Open is part of source.Driver interface implementation.
+// Open cannot be called on a namespace driver.
+func (d *namespaceDriver) Open(url string) (source.Driver, error) {
+	return nil, errors.New("Open() cannot be called on an iofs namespace driver")
+}
+
+// Close is part of source.Driver interface implementation. This is a no-op,
+// the file system is closed by the driver the namespace was created from.
+func (d *namespaceDriver) Close() error {
+	return nil
+}
+
 // PartialDriver is a helper service for creating new source drivers working with
 // io/fs.FS instances. It implements all source.Driver interface methods
 // except for Open(). New driver could embed this struct and add missing Open()
@@ -40,6 +79,7 @@ func (d *driver) Open(url string) (source.Driver, error) {
 // To prepare PartialDriver for use Init() function.
 type PartialDriver struct {
 	migrations *source.Migrations
+	namespaces map[string]*source.Migrations
 	fsys       fs.FS
 	path       string
 }
@@ -47,38 +87,113 @@ type PartialDriver struct {
 // Init prepares not initialized IoFS instance to read migrations from a
 // io/fs#FS instance and a relative path.
 func (d *PartialDriver) Init(fsys fs.FS, path string) error {
-	entries, err := fs.ReadDir(fsys, path)
+	return d.InitWithConfig(fsys, path, &Config{})
+}
+
+// InitWithConfig is like Init, but accepts a Config to enable recursive
+// scanning, namespaces or a custom Parser.
+func (d *PartialDriver) InitWithConfig(fsys fs.FS, path string, config *Config) error {
+	if config == nil {
+		config = &Config{}
+	}
+
+	namespaces := make(map[string]*source.Migrations)
+	if err := d.readDir(fsys, path, "", config, namespaces); err != nil {
+		return err
+	}
+
+	d.fsys = fsys
+	d.path = path
+	d.migrations = namespaces[""]
+	if d.migrations == nil {
+		d.migrations = source.NewMigrations()
+	}
+	if config.Namespaces {
+		d.namespaces = namespaces
+	}
+	return nil
+}
+
+// readDir reads the directory dir (relative to root) and appends all
+// migrations found to the namespace they belong to.
+func (d *PartialDriver) readDir(fsys fs.FS, root, dir string, config *Config, namespaces map[string]*source.Migrations) error {
+	entries, err := fs.ReadDir(fsys, path.Join(root, dir))
 	if err != nil {
 		return err
 	}
 
-	ms := source.NewMigrations()
+	namespace := ""
+	if config.Namespaces {
+		namespace = dir
+	}
+
+	parse := config.Parser
+	if parse == nil {
+		parse = source.DefaultParse
+	}
+
 	for _, e := range entries {
 		if e.IsDir() {
+			if config.Recursive || config.Namespaces {
+				if err := d.readDir(fsys, root, path.Join(dir, e.Name()), config, namespaces); err != nil {
+					return err
+				}
+			}
 			continue
 		}
-		m, err := source.DefaultParse(e.Name())
+		m, err := parse(e.Name())
 		if err != nil {
 			continue
 		}
+		m.Raw = path.Join(dir, m.Raw)
 		file, err := e.Info()
 		if err != nil {
 			return err
 		}
-		if !ms.Append(m) {
+		if namespaces[namespace] == nil {
+			namespaces[namespace] = source.NewMigrations()
+		}
+		if !namespaces[namespace].Append(m) {
 			return source.ErrDuplicateMigration{
 				Migration: *m,
 				FileInfo:  file,
 			}
 		}
 	}
-
-	d.fsys = fsys
-	d.path = path
-	d.migrations = ms
 	return nil
 }
 
+// Namespaces is part of source.Namespacer interface implementation.
+// It returns nil unless the driver was initialized with Config.Namespaces.
+func (d *PartialDriver) Namespaces() []string {
+	if d.namespaces == nil {
+		return nil
+	}
+	names := make([]string, 0, len(d.namespaces))
+	for name := range d.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Namespace is part of source.Namespacer interface implementation.
+func (d *PartialDriver) Namespace(name string) (source.Driver, error) {
+	ms, ok := d.namespaces[name]
+	if !ok {
+		return nil, &fs.PathError{
+			Op:   "namespace",
+			Path: path.Join(d.path, name),
+			Err:  fs.ErrNotExist,
+		}
+	}
+	return &namespaceDriver{PartialDriver{
+		migrations: ms,
+		fsys:       d.fsys,
+		path:       d.path,
+	}}, nil
+}
+
 // Close is part of source.Driver interface implementation.
 // Closes the file system if possible.
 func (d *PartialDriver) Close() error {
@@ -128,7 +243,7 @@ func (d *PartialDriver) Next(version uint) (nextVersion uint, err error) {
 // ReadUp is part of source.Driver interface implementation.
 func (d *PartialDriver) ReadUp(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := d.migrations.Up(version); ok {
-		body, err := d.open(path.Join(d.path, m.Raw))
+		body, err := d.read(m)
 		if err != nil {
 			return nil, "", err
 		}
@@ -144,7 +259,7 @@ func (d *PartialDriver) ReadUp(version uint) (r io.ReadCloser, identifier string
 // ReadDown is part of source.Driver interface implementation.
 func (d *PartialDriver) ReadDown(version uint) (r io.ReadCloser, identifier string, err error) {
 	if m, ok := d.migrations.Down(version); ok {
-		body, err := d.open(path.Join(d.path, m.Raw))
+		body, err := d.read(m)
 		if err != nil {
 			return nil, "", err
 		}
@@ -157,6 +272,42 @@ func (d *PartialDriver) ReadDown(version uint) (r io.ReadCloser, identifier stri
 	}
 }
 
+// Repeatables is part of source.RepeatableReader interface implementation.
+func (d *PartialDriver) Repeatables() ([]string, error) {
+	ms := d.migrations.Repeatables()
+	identifiers := make([]string, 0, len(ms))
+	for _, m := range ms {
+		identifiers = append(identifiers, m.Identifier)
+	}
+	return identifiers, nil
+}
+
+// ReadRepeatable is part of source.RepeatableReader interface implementation.
+func (d *PartialDriver) ReadRepeatable(identifier string) (r io.ReadCloser, err error) {
+	if m, ok := d.migrations.Repeatable(identifier); ok {
+		return d.open(path.Join(d.path, m.Raw))
+	}
+	return nil, &fs.PathError{
+		Op:   "read repeatable " + identifier,
+		Path: d.path,
+		Err:  fs.ErrNotExist,
+	}
+}
+
+// read opens the file of m. Single file migrations are split and only the
+// section for m.Direction is returned.
+func (d *PartialDriver) read(m *source.Migration) (io.ReadCloser, error) {
+	p := path.Join(d.path, m.Raw)
+	body, err := d.open(p)
+	if err != nil {
+		return nil, err
+	}
+	if !m.SingleFile {
+		return body, nil
+	}
+	return source.ReadSection(body, m.Direction, p)
+}
+
 func (d *PartialDriver) open(path string) (fs.File, error) {
 	f, err := d.fsys.Open(path)
 	if err == nil {
@@ -3,8 +3,15 @@
 package iofs_test
 
 import (
+	"errors"
+	"io"
+	stdfs "io/fs"
+	"io/ioutil"
+	"reflect"
 	"testing"
+	"testing/fstest"
 
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 	st "github.com/golang-migrate/migrate/v4/source/testing"
 )
@@ -18,3 +25,121 @@ func Test(t *testing.T) {
 
 	st.Test(t, d)
 }
+
+func TestRecursive(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_foobar.up.sql":           &fstest.MapFile{Data: []byte("1 up")},
+		"migrations/http/2_foobar.up.json":     &fstest.MapFile{Data: []byte("2 up")},
+		"migrations/http/sub/3_foobar.up.json": &fstest.MapFile{Data: []byte("3 up")},
+	}
+
+	d, err := iofs.NewWithConfig(fsys, "migrations", &iofs.Config{Recursive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := d.Next(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 3 {
+		t.Fatalf("expected next version 3, got %v", next)
+	}
+	r, identifier, err := d.ReadUp(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if identifier != "foobar" {
+		t.Fatalf("expected identifier foobar, got %v", identifier)
+	}
+	if ns := d.(source.Namespacer).Namespaces(); ns != nil {
+		t.Fatalf("expected no namespaces, got %v", ns)
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_foobar.up.sql":       &fstest.MapFile{Data: []byte("1 up")},
+		"migrations/http/1_foobar.up.json": &fstest.MapFile{Data: []byte("http 1 up")},
+		"migrations/http/2_foobar.up.json": &fstest.MapFile{Data: []byte("http 2 up")},
+		"migrations/seed/1_foobar.up.sql":  &fstest.MapFile{Data: []byte("seed 1 up")},
+	}
+
+	d, err := iofs.NewWithConfig(fsys, "migrations", &iofs.Config{Namespaces: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := d.(source.Namespacer)
+	if got := ns.Namespaces(); !reflect.DeepEqual(got, []string{"", "http", "seed"}) {
+		t.Fatalf("unexpected namespaces %v", got)
+	}
+	if _, err := d.Next(1); !errors.Is(err, stdfs.ErrNotExist) {
+		t.Fatalf("expected root namespace to end at version 1, got %v", err)
+	}
+
+	http, err := ns.Namespace("http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next, err := http.Next(1); err != nil || next != 2 {
+		t.Fatalf("expected next version 2, got %v (%v)", next, err)
+	}
+	r, _, err := http.ReadUp(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != "http 2 up" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	if _, err := ns.Namespace("missing"); !errors.Is(err, stdfs.ErrNotExist) {
+		t.Fatalf("expected stdfs.ErrNotExist, got %v", err)
+	}
+}
+
+func TestSingleFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/1_foobar.sql":    &fstest.MapFile{Data: []byte("-- +migrate Up\n1 up\n-- +migrate Down\n1 down\n")},
+		"migrations/2_foobar.up.sql": &fstest.MapFile{Data: []byte("2 up")},
+	}
+
+	d, err := iofs.New(fsys, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, err := d.First(); err != nil || first != 2 {
+		t.Fatalf("expected single file to be ignored by default, got first version %v (%v)", first, err)
+	}
+
+	d, err = iofs.NewWithConfig(fsys, "migrations", &iofs.Config{Parser: source.SingleFileParse})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		read func(uint) (io.ReadCloser, string, error)
+		body string
+	}{
+		{d.ReadUp, "1 up\n"},
+		{d.ReadDown, "1 down\n"},
+	} {
+		r, _, err := v.read(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v.body {
+			t.Fatalf("expected %q, got %q", v.body, b)
+		}
+	}
+	if next, err := d.Next(1); err != nil || next != 2 {
+		t.Fatalf("expected next version 2, got %v (%v)", next, err)
+	}
+}
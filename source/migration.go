@@ -29,19 +29,29 @@ type Migration struct {
 	// Raw holds the raw location path to this migration in source.
 	// ReadUp and ReadDown will use this.
 	Raw string
+
+	// Repeatable is true for repeatable migrations. They have no version
+	// and are identified by Identifier only.
+	Repeatable bool
+
+	// SingleFile is true for migrations keeping up and down in the same
+	// file, see SplitSections. Append registers them for both directions.
+	SingleFile bool
 }
 
 // Migrations wraps Migration and has an internal index
 // to keep track of Migration order.
 type Migrations struct {
-	index      uintSlice
-	migrations map[uint]map[Direction]*Migration
+	index       uintSlice
+	migrations  map[uint]map[Direction]*Migration
+	repeatables map[string]*Migration
 }
 
 func NewMigrations() *Migrations {
 	return &Migrations{
-		index:      make(uintSlice, 0),
-		migrations: make(map[uint]map[Direction]*Migration),
+		index:       make(uintSlice, 0),
+		migrations:  make(map[uint]map[Direction]*Migration),
+		repeatables: make(map[string]*Migration),
 	}
 }
 
@@ -50,10 +60,32 @@ func (i *Migrations) Append(m *Migration) (ok bool) {
 		return false
 	}
 
+	if m.Repeatable {
+		// reject duplicate identifiers
+		if _, dup := i.repeatables[m.Identifier]; dup {
+			return false
+		}
+		i.repeatables[m.Identifier] = m
+		return true
+	}
+
 	if i.migrations[m.Version] == nil {
 		i.migrations[m.Version] = make(map[Direction]*Migration)
 	}
 
+	if m.SingleFile {
+		// reject single files next to up or down files of the same version
+		if len(i.migrations[m.Version]) > 0 {
+			return false
+		}
+		up, down := *m, *m
+		up.Direction, down.Direction = Up, Down
+		i.migrations[m.Version][Up] = &up
+		i.migrations[m.Version][Down] = &down
+		i.buildIndex()
+		return true
+	}
+
 	// reject duplicate versions
 	if _, dup := i.migrations[m.Version][m.Direction]; dup {
 		return false
@@ -114,6 +146,21 @@ func (i *Migrations) Down(version uint) (m *Migration, ok bool) {
 	return nil, false
 }
 
+// Repeatables returns all repeatable migrations sorted by identifier.
+func (i *Migrations) Repeatables() []*Migration {
+	ms := make([]*Migration, 0, len(i.repeatables))
+	for _, m := range i.repeatables {
+		ms = append(ms, m)
+	}
+	sort.Slice(ms, func(a, b int) bool { return ms[a].Identifier < ms[b].Identifier })
+	return ms
+}
+
+func (i *Migrations) Repeatable(identifier string) (m *Migration, ok bool) {
+	m, ok = i.repeatables[identifier]
+	return m, ok
+}
+
 func (i *Migrations) findPos(version uint) int {
 	if len(i.index) > 0 {
 		ix := i.index.Search(version)
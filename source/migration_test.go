@@ -44,3 +44,27 @@ func TestFindPos(t *testing.T) {
 		t.Errorf("expected 2, got %v", p)
 	}
 }
+
+func TestAppendSingleFile(t *testing.T) {
+	m := NewMigrations()
+	if !m.Append(&Migration{Version: 1, Identifier: "foo", Direction: Up, Raw: "1_foo.sql", SingleFile: true}) {
+		t.Fatal("expected single file migration to be appended")
+	}
+	up, ok := m.Up(1)
+	if !ok || up.Direction != Up || up.Raw != "1_foo.sql" {
+		t.Fatalf("unexpected up migration %+v", up)
+	}
+	down, ok := m.Down(1)
+	if !ok || down.Direction != Down || down.Raw != "1_foo.sql" {
+		t.Fatalf("unexpected down migration %+v", down)
+	}
+	if m.Append(&Migration{Version: 1, Identifier: "foo", Direction: Up, Raw: "1_foo.up.sql"}) {
+		t.Fatal("expected up migration of the same version to be rejected")
+	}
+
+	m = NewMigrations()
+	m.Append(&Migration{Version: 1, Identifier: "foo", Direction: Down, Raw: "1_foo.down.sql"})
+	if m.Append(&Migration{Version: 1, Identifier: "foo", Direction: Up, Raw: "1_foo.sql", SingleFile: true}) {
+		t.Fatal("expected single file migration next to down migration to be rejected")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -15,13 +16,58 @@ var (
 	DefaultRegex = Regex
 )
 
+// Parser turns a file name into a Migration. It returns ErrParse if the
+// file name doesn't follow the parser's naming convention. Source drivers
+// ignore files that can't be parsed.
+type Parser func(raw string) (*Migration, error)
+
+// Parsers maps the names accepted by the x-parser URL option to Parser.
+var Parsers = map[string]Parser{
+	"default":     Parse,
+	"single-file": SingleFileParse,
+	"flyway":      FlywayParse,
+	"goose":       GooseParse,
+	"dbmate":      DbmateParse,
+}
+
+// ParserByName returns the Parser registered in Parsers under name.
+// An empty name returns DefaultParse.
+func ParserByName(name string) (Parser, error) {
+	if name == "" {
+		return DefaultParse, nil
+	}
+	p, ok := Parsers[name]
+	if !ok {
+		return nil, fmt.Errorf("source: unknown parser %q", name)
+	}
+	return p, nil
+}
+
 // Regex matches the following pattern:
 //  123_name.up.ext
 //  123_name.down.ext
 var Regex = regexp.MustCompile(`^([0-9]+)_(.*)\.(` + string(Down) + `|` + string(Up) + `)\.(.*)$`)
 
-// Parse returns Migration for matching Regex pattern.
+// RepeatableRegex matches the following pattern:
+//  R_name.up.ext
+var RepeatableRegex = regexp.MustCompile(`^R_(.+)\.(` + string(Up) + `)\.(.*)$`)
+
+// SingleFileRegex matches the following pattern:
+//  123_name.ext
+// The file holds both the up and the down migration, see SplitSections.
+var SingleFileRegex = regexp.MustCompile(`^([0-9]+)_(.*)\.([^.]+)$`)
+
+// Parse returns Migration for matching Regex or RepeatableRegex pattern.
 func Parse(raw string) (*Migration, error) {
+	if m := RepeatableRegex.FindStringSubmatch(raw); len(m) == 4 {
+		return &Migration{
+			Identifier: m[1],
+			Direction:  Direction(m[2]),
+			Raw:        raw,
+			Repeatable: true,
+		}, nil
+	}
+
 	m := Regex.FindStringSubmatch(raw)
 	if len(m) == 5 {
 		versionUint64, err := strconv.ParseUint(m[1], 10, 64)
@@ -37,3 +83,125 @@ func Parse(raw string) (*Migration, error) {
 	}
 	return nil, ErrParse
 }
+
+// SingleFileParse returns Migration for matching Regex, RepeatableRegex or
+// SingleFileRegex pattern. Single file migrations are opt-in, Parse ignores
+// them so that other files next to the migrations, e.g. 1_seed.json, aren't
+// mistaken for migrations.
+func SingleFileParse(raw string) (*Migration, error) {
+	if m, err := Parse(raw); err != ErrParse {
+		return m, err
+	}
+
+	m := SingleFileRegex.FindStringSubmatch(raw)
+	if len(m) == 4 && m[3] != string(Up) && m[3] != string(Down) {
+		versionUint64, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &Migration{
+			Version:    uint(versionUint64),
+			Identifier: m[2],
+			Direction:  Up,
+			Raw:        raw,
+			SingleFile: true,
+		}, nil
+	}
+	return nil, ErrParse
+}
+
+// FlywayRegex matches Flyway's naming convention:
+//  V123__name.ext
+//  U123__name.ext
+//  R__name.ext
+// Only integer versions are supported.
+var FlywayRegex = regexp.MustCompile(`^(?:([VU])([0-9]+)|R)__(.+)\.([^.]+)$`)
+
+// FlywayParse returns Migration for matching FlywayRegex pattern.
+// Versioned migrations (V) are up migrations, undo migrations (U) are down
+// migrations and R__ files are repeatable migrations.
+func FlywayParse(raw string) (*Migration, error) {
+	m := FlywayRegex.FindStringSubmatch(raw)
+	if len(m) != 5 {
+		return nil, ErrParse
+	}
+	if m[1] == "" {
+		return &Migration{
+			Identifier: m[3],
+			Direction:  Up,
+			Raw:        raw,
+			Repeatable: true,
+		}, nil
+	}
+	versionUint64, err := strconv.ParseUint(m[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	direction := Up
+	if m[1] == "U" {
+		direction = Down
+	}
+	return &Migration{
+		Version:    uint(versionUint64),
+		Identifier: m[3],
+		Direction:  direction,
+		Raw:        raw,
+	}, nil
+}
+
+// GooseRegex matches goose's and dbmate's naming convention:
+//  20170506082420_name.sql
+var GooseRegex = regexp.MustCompile(`^([0-9]+)_(.+)\.sql$`)
+
+// GooseParse returns Migration for matching GooseRegex pattern.
+// goose keeps up and down in the same file, separated by
+// -- +goose Up and -- +goose Down.
+func GooseParse(raw string) (*Migration, error) {
+	m := GooseRegex.FindStringSubmatch(raw)
+	if len(m) != 3 {
+		return nil, ErrParse
+	}
+	versionUint64, err := strconv.ParseUint(m[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &Migration{
+		Version:    uint(versionUint64),
+		Identifier: m[2],
+		Direction:  Up,
+		Raw:        raw,
+		SingleFile: true,
+	}, nil
+}
+
+// DbmateParse returns Migration for matching GooseRegex pattern, dbmate
+// uses the same file names as goose and separates up and down by
+// -- migrate:up and -- migrate:down.
+func DbmateParse(raw string) (*Migration, error) {
+	return GooseParse(raw)
+}
+
+// Tags returns the tags qualifying a migration identifier. Tags follow the
+// name of the migration after the first dot, or after an @, and are
+// separated by dots:
+//  demo.dev           => [dev]
+//  fix_orders.prod.eu => [prod eu]
+//  demo@dev           => [dev]
+//  fix@prod.eu        => [prod eu]
+//  create_users       => []
+func Tags(identifier string) []string {
+	i := strings.Index(identifier, "@")
+	if i < 0 {
+		i = strings.Index(identifier, ".")
+	}
+	if i < 0 {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(identifier[i+1:], ".") {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
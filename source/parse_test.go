@@ -1,6 +1,7 @@
 package source
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -60,6 +61,21 @@ func TestParse(t *testing.T) {
 				Raw:        "20170412214116_date_foobar.up.sql",
 			},
 		},
+		{
+			name:      "R_refresh_views.up.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Identifier: "refresh_views",
+				Direction:  Up,
+				Raw:        "R_refresh_views.up.sql",
+				Repeatable: true,
+			},
+		},
+		{
+			name:            "R_refresh_views.down.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
 		{
 			name:            "-1_foobar.up.sql",
 			expectErr:       ErrParse,
@@ -104,3 +120,204 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSingleFileParse(t *testing.T) {
+	tt := []struct {
+		name            string
+		expectErr       error
+		expectMigration *Migration
+	}{
+		{
+			name:      "1_foobar.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Version:    1,
+				Identifier: "foobar",
+				Direction:  Up,
+				Raw:        "1_foobar.sql",
+				SingleFile: true,
+			},
+		},
+		{
+			name:      "1_foobar.down.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Version:    1,
+				Identifier: "foobar",
+				Direction:  Down,
+				Raw:        "1_foobar.down.sql",
+			},
+		},
+		{
+			name:      "R_refresh_views.up.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Identifier: "refresh_views",
+				Direction:  Up,
+				Raw:        "R_refresh_views.up.sql",
+				Repeatable: true,
+			},
+		},
+		{
+			name:            "1_foobar.up",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+		{
+			name:            "foobar.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+	}
+
+	for i, v := range tt {
+		f, err := SingleFileParse(v.name)
+
+		if err != v.expectErr {
+			t.Errorf("expected %v, got %v, in %v", v.expectErr, err, i)
+		}
+
+		if v.expectMigration != nil && *f != *v.expectMigration {
+			t.Errorf("expected %+v, got %+v, in %v", *v.expectMigration, *f, i)
+		}
+	}
+}
+
+func TestFlywayParse(t *testing.T) {
+	tt := []struct {
+		name            string
+		expectErr       error
+		expectMigration *Migration
+	}{
+		{
+			name:      "V1__create_users.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Version:    1,
+				Identifier: "create_users",
+				Direction:  Up,
+				Raw:        "V1__create_users.sql",
+			},
+		},
+		{
+			name:      "U1__create_users.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Version:    1,
+				Identifier: "create_users",
+				Direction:  Down,
+				Raw:        "U1__create_users.sql",
+			},
+		},
+		{
+			name:      "R__refresh_views.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Identifier: "refresh_views",
+				Direction:  Up,
+				Raw:        "R__refresh_views.sql",
+				Repeatable: true,
+			},
+		},
+		{
+			name:            "V1_create_users.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+		{
+			name:            "V1.1__create_users.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+		{
+			name:            "1_foobar.up.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+	}
+
+	for i, v := range tt {
+		f, err := FlywayParse(v.name)
+
+		if err != v.expectErr {
+			t.Errorf("expected %v, got %v, in %v", v.expectErr, err, i)
+		}
+
+		if v.expectMigration != nil && *f != *v.expectMigration {
+			t.Errorf("expected %+v, got %+v, in %v", *v.expectMigration, *f, i)
+		}
+	}
+}
+
+func TestGooseParse(t *testing.T) {
+	tt := []struct {
+		name            string
+		expectErr       error
+		expectMigration *Migration
+	}{
+		{
+			name:      "20170506082420_create_table.sql",
+			expectErr: nil,
+			expectMigration: &Migration{
+				Version:    20170506082420,
+				Identifier: "create_table",
+				Direction:  Up,
+				Raw:        "20170506082420_create_table.sql",
+				SingleFile: true,
+			},
+		},
+		{
+			name:            "20170506082420_create_table.go",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+		{
+			name:            "create_table.sql",
+			expectErr:       ErrParse,
+			expectMigration: nil,
+		},
+	}
+
+	for i, v := range tt {
+		f, err := GooseParse(v.name)
+
+		if err != v.expectErr {
+			t.Errorf("expected %v, got %v, in %v", v.expectErr, err, i)
+		}
+
+		if v.expectMigration != nil && *f != *v.expectMigration {
+			t.Errorf("expected %+v, got %+v, in %v", *v.expectMigration, *f, i)
+		}
+	}
+}
+
+func TestParserByName(t *testing.T) {
+	for _, name := range []string{"", "default", "flyway", "goose", "dbmate"} {
+		if p, err := ParserByName(name); err != nil || p == nil {
+			t.Errorf("expected parser for %q, got %v", name, err)
+		}
+	}
+	if _, err := ParserByName("foo"); err == nil {
+		t.Error("expected error for unknown parser")
+	}
+}
+
+func TestTags(t *testing.T) {
+	tt := []struct {
+		identifier string
+		expected   []string
+	}{
+		{identifier: "create_table", expected: nil},
+		{identifier: "demo.dev", expected: []string{"dev"}},
+		{identifier: "fix_orders.prod.eu", expected: []string{"prod", "eu"}},
+		{identifier: "demo@dev", expected: []string{"dev"}},
+		{identifier: "fix@prod.eu", expected: []string{"prod", "eu"}},
+		{identifier: "fix@.prod.", expected: []string{"prod"}},
+	}
+
+	for i, v := range tt {
+		if tags := Tags(v.identifier); !reflect.DeepEqual(tags, v.expected) {
+			t.Errorf("expected %v, got %v, in %v", v.expected, tags, i)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// sectionMarker matches the lines that start the up and down sections of a
+// single file migration:
+//  -- +migrate Up
+//  -- +goose Down
+//  -- migrate:up
+var sectionMarker = regexp.MustCompile(`(?i)^--\s*(?:\+(?:migrate|goose)\s+(up|down)|migrate:(up|down))(?:\s|$)`)
+
+// ErrSection is returned by SplitSections if a single file migration
+// contains statements before its first section marker or repeats a section.
+type ErrSection struct {
+	Line int
+	Err  string
+}
+
+func (e ErrSection) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// SplitSections splits the body of a single file migration into its up and
+// down sections. SQL-like files use comment markers (-- +migrate Up,
+// -- +goose Up, -- migrate:up and their down counterparts), JSON files are
+// objects with "up" and/or "down" keys.
+//
+// Comment lines before the first marker are prepended to both sections so
+// header directives apply to either direction. A body without any marker is
+// returned as up section. A nil section means the file has none.
+func SplitSections(body []byte) (up, down []byte, err error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+		if up, down, ok := splitJSONSections(trimmed); ok {
+			return up, down, nil
+		}
+	}
+
+	lines := strings.SplitAfter(string(body), "\n")
+	marked := false
+	for _, text := range lines {
+		if sectionMarker.MatchString(strings.TrimSpace(text)) {
+			marked = true
+			break
+		}
+	}
+	if !marked {
+		return body, nil, nil
+	}
+
+	var (
+		header   strings.Builder
+		sections = map[Direction]*strings.Builder{}
+		current  *strings.Builder
+	)
+	for i, text := range lines {
+		if m := sectionMarker.FindStringSubmatch(strings.TrimSpace(text)); m != nil {
+			direction := Direction(strings.ToLower(m[1] + m[2]))
+			if _, dup := sections[direction]; dup {
+				return nil, nil, ErrSection{Line: i + 1, Err: "duplicate " + string(direction) + " section"}
+			}
+			current = &strings.Builder{}
+			current.WriteString(header.String())
+			sections[direction] = current
+			continue
+		}
+		if current == nil {
+			if t := strings.TrimSpace(text); t != "" && !strings.HasPrefix(t, "--") {
+				return nil, nil, ErrSection{Line: i + 1, Err: "statement before first section marker"}
+			}
+			header.WriteString(text)
+			continue
+		}
+		current.WriteString(text)
+	}
+
+	if b, ok := sections[Up]; ok {
+		up = []byte(b.String())
+	}
+	if b, ok := sections[Down]; ok {
+		down = []byte(b.String())
+	}
+	return up, down, nil
+}
+
+// splitJSONSections splits a JSON object with "up" and/or "down" keys. ok is
+// false if body is any other JSON value.
+func splitJSONSections(body []byte) (up, down []byte, ok bool) {
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(body, &sections); err != nil || len(sections) == 0 {
+		return nil, nil, false
+	}
+	for key := range sections {
+		if key != string(Up) && key != string(Down) {
+			return nil, nil, false
+		}
+	}
+	if v, ok := sections[string(Up)]; ok {
+		up = []byte(v)
+	}
+	if v, ok := sections[string(Down)]; ok {
+		down = []byte(v)
+	}
+	return up, down, true
+}
+
+// ReadSection reads a single file migration from r and returns the section
+// for direction. r is closed. If the file has no or an empty section, an
+// *os.PathError wrapping os.ErrNotExist is returned, which migrate treats
+// like a missing migration file.
+func ReadSection(r io.ReadCloser, direction Direction, path string) (io.ReadCloser, error) {
+	body, err := ioutil.ReadAll(r)
+	if cerr := r.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	up, down, err := SplitSections(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	section := up
+	if direction == Down {
+		section = down
+	}
+	if len(bytes.TrimSpace(section)) == 0 {
+		return nil, &os.PathError{
+			Op:   "read " + string(direction) + " section",
+			Path: path,
+			Err:  os.ErrNotExist,
+		}
+	}
+	return ioutil.NopCloser(bytes.NewReader(section)), nil
+}
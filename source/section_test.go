@@ -0,0 +1,110 @@
+package source
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSplitSections(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		expectUp   string
+		expectDown string
+		expectErr  bool
+	}{
+		{
+			name:       "sql-migrate",
+			body:       "-- +migrate Up\nCREATE TABLE t (id int);\n-- +migrate Down\nDROP TABLE t;\n",
+			expectUp:   "CREATE TABLE t (id int);\n",
+			expectDown: "DROP TABLE t;\n",
+		},
+		{
+			name:       "goose",
+			body:       "-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n-- +goose StatementEnd\n\n-- +goose Down\nSELECT 2;\n",
+			expectUp:   "-- +goose StatementBegin\nSELECT 1;\n-- +goose StatementEnd\n\n",
+			expectDown: "SELECT 2;\n",
+		},
+		{
+			name:       "dbmate",
+			body:       "-- migrate:up\nSELECT 1;\n-- migrate:down\nSELECT 2;",
+			expectUp:   "SELECT 1;\n",
+			expectDown: "SELECT 2;",
+		},
+		{
+			name:     "header and up only",
+			body:     "-- migrate:no-transaction\n\n-- +migrate Up\nSELECT 1;\n",
+			expectUp: "-- migrate:no-transaction\n\nSELECT 1;\n",
+		},
+		{
+			name:     "no markers",
+			body:     "SELECT 1;\n",
+			expectUp: "SELECT 1;\n",
+		},
+		{
+			name:       "json",
+			body:       `{"up": [{"create": "t"}], "down": [{"drop": "t"}]}`,
+			expectUp:   `[{"create": "t"}]`,
+			expectDown: `[{"drop": "t"}]`,
+		},
+		{
+			name:     "json without sections",
+			body:     `{"create": "t"}`,
+			expectUp: `{"create": "t"}`,
+		},
+		{
+			name:      "statement before marker",
+			body:      "SELECT 0;\n-- +migrate Up\nSELECT 1;\n",
+			expectErr: true,
+		},
+		{
+			name:      "duplicate section",
+			body:      "-- +migrate Up\nSELECT 1;\n-- +migrate Up\nSELECT 2;\n",
+			expectErr: true,
+		},
+	}
+
+	for _, v := range tt {
+		t.Run(v.name, func(t *testing.T) {
+			up, down, err := SplitSections([]byte(v.body))
+			if v.expectErr {
+				if err == nil {
+					t.Fatal("expected err")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(up) != v.expectUp {
+				t.Errorf("expected up %q, got %q", v.expectUp, up)
+			}
+			if string(down) != v.expectDown {
+				t.Errorf("expected down %q, got %q", v.expectDown, down)
+			}
+		})
+	}
+}
+
+func TestReadSection(t *testing.T) {
+	body := "-- +migrate Up\nSELECT 1;\n-- +migrate Down\n\n"
+
+	r, err := ReadSection(ioutil.NopCloser(strings.NewReader(body)), Up, "1_foo.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "SELECT 1;\n" {
+		t.Fatalf("unexpected up section %q", b)
+	}
+
+	if _, err := ReadSection(ioutil.NopCloser(strings.NewReader(body)), Down, "1_foo.sql"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected empty down section to not exist, got %v", err)
+	}
+}
@@ -83,3 +83,18 @@ func (s *Stub) ReadDown(version uint) (r io.ReadCloser, identifier string, err e
 	}
 	return nil, "", &os.PathError{Op: fmt.Sprintf("read down version %v", version), Path: s.Url, Err: os.ErrNotExist}
 }
+
+func (s *Stub) Repeatables() ([]string, error) {
+	identifiers := make([]string, 0)
+	for _, m := range s.Migrations.Repeatables() {
+		identifiers = append(identifiers, m.Identifier)
+	}
+	return identifiers, nil
+}
+
+func (s *Stub) ReadRepeatable(identifier string) (r io.ReadCloser, err error) {
+	if m, ok := s.Migrations.Repeatable(identifier); ok {
+		return ioutil.NopCloser(bytes.NewBufferString(m.Raw)), nil
+	}
+	return nil, &os.PathError{Op: fmt.Sprintf("read repeatable %v", identifier), Path: s.Url, Err: os.ErrNotExist}
+}
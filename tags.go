@@ -0,0 +1,215 @@
+package migrate
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// WithTags restricts the migrations run by m to the ones selected by tags and
+// excludeTags, see MatchTags, and returns m. Migrations are tagged by a
+// qualifier following an @ in their name (e.g. 4_demo@dev.up.sql) or by a
+// "-- migrate:tags=dev,demo" directive in their header.
+// Migrations that are not selected are skipped: they are not run, but the
+// version still moves past them. The database driver must record skipped
+// migrations, see database.SkipRecorder, otherwise ErrTagsNotSupported is
+// returned. Skipped migrations are not reverted by Down and Up applies them
+// once they are selected, e.g. by a later Up without tags.
+func (m *Migrate) WithTags(tags []string, excludeTags []string) *Migrate {
+	m.tags = tags
+	m.excludeTags = excludeTags
+	return m
+}
+
+// MatchTags reports whether a migration tagged with migrationTags is selected
+// by tags and excludeTags. Untagged migrations are always selected. Tagged
+// migrations are selected if tags is empty or if they carry one of tags,
+// unless they carry one of excludeTags.
+func MatchTags(migrationTags []string, tags []string, excludeTags []string) bool {
+	if len(migrationTags) == 0 {
+		return true
+	}
+	for _, t := range migrationTags {
+		if containsTag(excludeTags, t) {
+			return false
+		}
+	}
+	if len(tags) == 0 {
+		return true
+	}
+	for _, t := range migrationTags {
+		if containsTag(tags, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// filtersTags reports whether m selects migrations by tags.
+func (m *Migrate) filtersTags() bool {
+	return len(m.tags) > 0 || len(m.excludeTags) > 0
+}
+
+// skipByTags reports whether the up migration migr is not selected by the
+// tags of m. It reads the header of migr, so it must be called before migr is
+// run. The body of a skipped migration is drained.
+func (m *Migrate) skipByTags(migr *Migration) (bool, error) {
+	if migr.Body == nil || !m.filtersTags() {
+		return false, nil
+	}
+
+	options, body, err := database.ParseDirectives(migr.BufferedBody)
+	if err != nil {
+		return false, err
+	}
+	migr.BufferedBody = body
+
+	tags := append(source.Tags(migr.Identifier), options.Tags...)
+	if MatchTags(tags, m.tags, m.excludeTags) {
+		return false, nil
+	}
+
+	_, err = io.Copy(ioutil.Discard, migr.BufferedBody)
+	return true, err
+}
+
+// skippedVersions returns the versions recorded as skipped by the database
+// driver. It returns ErrTagsNotSupported if m selects migrations by tags, but
+// the driver doesn't implement database.SkipRecorder.
+func (m *Migrate) skippedVersions() (map[uint]bool, error) {
+	sr, ok := m.databaseDrv.(database.SkipRecorder)
+	if !ok {
+		if m.filtersTags() {
+			return nil, ErrTagsNotSupported
+		}
+		return nil, nil
+	}
+	versions, err := sr.SkippedVersions()
+	if err != nil {
+		return nil, err
+	}
+	skipped := make(map[uint]bool, len(versions))
+	for _, v := range versions {
+		skipped[uint(v)] = true
+	}
+	return skipped, nil
+}
+
+// skipOnDown reports whether the down migration migr reverts a version that
+// was skipped and thus never applied. Its body is drained.
+func skipOnDown(migr *Migration, skipped map[uint]bool) (bool, error) {
+	if !skipped[migr.Version] {
+		return false, nil
+	}
+	if migr.Body != nil {
+		if _, err := io.Copy(ioutil.Discard, migr.BufferedBody); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// recordSkip records the up migration migr as skipped in the ledger of
+// drivers implementing database.SkipRecorder. A version recorded as skipped
+// before is removed once it is applied or reverted past.
+func (m *Migrate) recordSkip(migr *Migration, skip bool, skipped map[uint]bool) error {
+	sr, ok := m.databaseDrv.(database.SkipRecorder)
+	if !ok {
+		return nil
+	}
+	if skip && !migr.isDown() {
+		skipped[migr.Version] = true
+		return sr.Skip(int(migr.Version), migr.Identifier)
+	}
+	if skipped[migr.Version] {
+		delete(skipped, migr.Version)
+		return sr.Unskip(int(migr.Version))
+	}
+	return nil
+}
+
+// upSkipped applies the migrations up to curVersion that were skipped before
+// and are selected by the tags of m now. It reports whether any was applied.
+func (m *Migrate) upSkipped(curVersion int) (bool, error) {
+	sr, ok := m.databaseDrv.(database.SkipRecorder)
+	if !ok {
+		return false, nil
+	}
+	versions, err := sr.SkippedVersions()
+	if err != nil {
+		return false, err
+	}
+	sort.Ints(versions)
+
+	applied := false
+	for _, v := range versions {
+		if m.stop() {
+			break
+		}
+		if !m.before(v, curVersion) && v != curVersion {
+			// the version walk reaches it
+			continue
+		}
+
+		migr, err := m.newMigration(uint(v), v)
+		if err != nil {
+			return applied, err
+		}
+		go func() {
+			if err := migr.Buffer(); err != nil {
+				m.logErr(err)
+			}
+		}()
+
+		skip, err := m.skipByTags(migr)
+		if err != nil {
+			return applied, err
+		}
+		if skip {
+			continue
+		}
+
+		if err := m.runAt(migr, curVersion); err != nil {
+			return applied, err
+		}
+		if err := sr.Unskip(v); err != nil {
+			return applied, err
+		}
+		if err := m.setVersion(curVersion, false); err != nil {
+			return applied, err
+		}
+		applied = true
+		m.logInfo("Applied previously skipped "+migr.LogString(), migrationFields(migr)...)
+	}
+	return applied, nil
+}
+
+// runAt runs migr out of the order of the version walk. The database is
+// marked dirty at curVersion while it runs.
+func (m *Migrate) runAt(migr *Migration, curVersion int) error {
+	if err := m.setVersion(curVersion, true); err != nil {
+		return err
+	}
+
+	if migr.Body != nil {
+		m.logDebug("Read and execute "+migr.LogString(), migrationFields(migr)...)
+		return m.observeRun(migr, func() error { return m.run(migr.BufferedBody) })
+	}
+	if migr.Func != nil {
+		m.logDebug("Execute "+migr.LogString(), migrationFields(migr)...)
+		return m.observeRun(migr, func() error { return m.runFunc(migr.Func) })
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"testing"
+)
+
+func TestMatchTags(t *testing.T) {
+	tt := []struct {
+		migrationTags []string
+		tags          []string
+		excludeTags   []string
+		expected      bool
+	}{
+		{migrationTags: nil, tags: []string{"dev"}, excludeTags: []string{"dev"}, expected: true},
+		{migrationTags: []string{"dev"}, tags: nil, excludeTags: nil, expected: true},
+		{migrationTags: []string{"dev"}, tags: []string{"dev"}, excludeTags: nil, expected: true},
+		{migrationTags: []string{"dev"}, tags: []string{"prod"}, excludeTags: nil, expected: false},
+		{migrationTags: []string{"dev", "demo"}, tags: []string{"demo"}, excludeTags: nil, expected: true},
+		{migrationTags: []string{"dev"}, tags: nil, excludeTags: []string{"dev"}, expected: false},
+		{migrationTags: []string{"dev", "demo"}, tags: []string{"dev"}, excludeTags: []string{"demo"}, expected: false},
+	}
+
+	for i, v := range tt {
+		if ok := MatchTags(v.migrationTags, v.tags, v.excludeTags); ok != v.expected {
+			t.Errorf("expected %v, got %v, in %v", v.expected, ok, i)
+		}
+	}
+}
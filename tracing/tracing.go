@@ -0,0 +1,213 @@
+// Package tracing traces migration runs and exports the spans in the
+// OpenTelemetry protocol (OTLP), either to an OTLP/HTTP endpoint such as the
+// OpenTelemetry Collector or to a local file.
+//
+// A Tracer implements migrate.Observer. The first span started is the root
+// span, all other spans are its children:
+//  t := tracing.New("migrate", &tracing.OTLPExporter{URL: "http://localhost:4318/v1/traces"})
+//  m.Observer = t
+//  root := t.Start("migrate up")
+//  err := m.Up()
+//  t.End(root, err)
+//  t.Flush()
+//
+// If the environment variable TRACEPARENT holds a W3C trace context, e.g. set
+// by a deployment pipeline, the root span continues its trace.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+// SpanKind is the kind of a span, see the OpenTelemetry specification.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindClient   SpanKind = 3
+)
+
+// Span is an operation, e.g. a migration or an HTTP request. A span must not
+// be used concurrently.
+type Span struct {
+	Name         string
+	Kind         SpanKind
+	TraceID      [16]byte
+	SpanID       [8]byte
+	ParentSpanID [8]byte
+	Start        time.Time
+	End          time.Time
+	Attributes   map[string]interface{}
+
+	// Err is the error of the operation, if any.
+	Err error
+}
+
+// SetAttribute sets the attribute key of s. value should be a string, bool,
+// integer or float.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.Attributes[key] = value
+}
+
+// Exporter exports ended spans.
+type Exporter interface {
+	Export(serviceName string, spans []*Span) error
+}
+
+// Tracer records spans and exports them on Flush. It is safe for concurrent
+// use.
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+
+	// traceID and parentSpanID of the root span, random or from TRACEPARENT
+	traceID      [16]byte
+	parentSpanID [8]byte
+
+	mu    sync.Mutex
+	root  *Span
+	ended []*Span
+}
+
+// New returns a new Tracer exporting spans of the service serviceName, e.g.
+// migrate, to exporter.
+func New(serviceName string, exporter Exporter) *Tracer {
+	t := &Tracer{serviceName: serviceName, exporter: exporter}
+	if traceID, spanID, ok := parseTraceparent(os.Getenv("TRACEPARENT")); ok {
+		t.traceID, t.parentSpanID = traceID, spanID
+	} else {
+		randomID(t.traceID[:])
+	}
+	return t
+}
+
+// Start starts a span. It is a child of the root span, or the root span
+// itself if no root span is running.
+func (t *Tracer) Start(name string) *Span {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.start(name)
+}
+
+func (t *Tracer) start(name string) *Span {
+	s := &Span{
+		Name:       name,
+		Kind:       KindInternal,
+		TraceID:    t.traceID,
+		Start:      time.Now(),
+		Attributes: map[string]interface{}{},
+	}
+	randomID(s.SpanID[:])
+	if t.root != nil {
+		s.ParentSpanID = t.root.SpanID
+	} else {
+		s.ParentSpanID = t.parentSpanID
+		t.root = s
+	}
+	return s
+}
+
+// End ends s with the error of the operation, if any.
+func (t *Tracer) End(s *Span, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end(s, err)
+}
+
+func (t *Tracer) end(s *Span, err error) {
+	s.End = time.Now()
+	s.Err = err
+	t.ended = append(t.ended, s)
+	if s == t.root {
+		t.root = nil
+	}
+}
+
+// Inject sets the W3C traceparent header of h to the context of s, so that
+// the receiver of a request can continue the trace.
+func (t *Tracer) Inject(s *Span, h http.Header) {
+	h.Set("traceparent", fmt.Sprintf("00-%x-%x-01", s.TraceID, s.SpanID))
+}
+
+// Flush exports the ended spans.
+func (t *Tracer) Flush() error {
+	t.mu.Lock()
+	spans := t.ended
+	t.ended = nil
+	t.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+	return t.exporter.Export(t.serviceName, spans)
+}
+
+// MigrationRun implements migrate.Observer and records a span for the
+// migration.
+func (t *Tracer) MigrationRun(databaseName string, migr *migrate.Migration, duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.start("migration " + migr.Direction())
+	s.Start = s.Start.Add(-duration)
+	s.SetAttribute("db.system", databaseName)
+	s.SetAttribute("migrate.version", int64(migr.Version))
+	s.SetAttribute("migrate.target_version", int64(migr.TargetVersion))
+	s.SetAttribute("migrate.identifier", migr.Identifier)
+	s.SetAttribute("migrate.direction", migr.Direction())
+	s.SetAttribute("migrate.bytes_read", migr.BytesRead)
+	t.end(s, err)
+}
+
+// LockAcquired implements migrate.Observer and records a span for the lock
+// acquisition.
+func (t *Tracer) LockAcquired(databaseName string, wait time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.start("lock")
+	s.Start = s.Start.Add(-wait)
+	s.SetAttribute("db.system", databaseName)
+	t.end(s, err)
+}
+
+// VersionChanged implements migrate.Observer and sets the version of the
+// database as attribute of the root span.
+func (t *Tracer) VersionChanged(databaseName string, version int, dirty bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.root == nil {
+		return
+	}
+	t.root.SetAttribute("migrate.version", int64(version))
+	t.root.SetAttribute("migrate.dirty", dirty)
+}
+
+// parseTraceparent returns the trace and span ID of a W3C traceparent header,
+// e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceparent(traceparent string) (traceID [16]byte, spanID [8]byte, ok bool) {
+	parts := strings.Split(traceparent, "-")
+	if len(parts) < 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil {
+		return traceID, spanID, false
+	}
+	if _, err := hex.Decode(spanID[:], []byte(parts[2])); err != nil {
+		return traceID, spanID, false
+	}
+	return traceID, spanID, traceID != [16]byte{} && spanID != [8]byte{}
+}
+
+func randomID(id []byte) {
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+}
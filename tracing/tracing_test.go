@@ -0,0 +1,156 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+)
+
+type recordingExporter struct {
+	spans []*Span
+}
+
+func (e *recordingExporter) Export(serviceName string, spans []*Span) error {
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func TestTracer(t *testing.T) {
+	e := &recordingExporter{}
+	tr := New("migrate", e)
+
+	root := tr.Start("migrate up")
+	tr.LockAcquired("stub", time.Second, nil)
+	tr.MigrationRun("stub", &migrate.Migration{Identifier: "create_users", Version: 2, TargetVersion: 1, BytesRead: 42}, time.Second, errors.New("syntax error"))
+	tr.VersionChanged("stub", 2, true)
+	tr.End(root, nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(e.spans) != 3 {
+		t.Fatalf("Incorrect number of spans was: %v", len(e.spans))
+	}
+	lock, migration := e.spans[0], e.spans[1]
+	if e.spans[2] != root {
+		t.Errorf("Root span was not exported last")
+	}
+	for _, s := range []*Span{lock, migration} {
+		if s.TraceID != root.TraceID || s.ParentSpanID != root.SpanID {
+			t.Errorf("Span %v is not a child of the root span", s.Name)
+		}
+		if d := s.End.Sub(s.Start); d < time.Second {
+			t.Errorf("Incorrect duration of span %v was: %v", s.Name, d)
+		}
+	}
+	if root.ParentSpanID != [8]byte{} {
+		t.Errorf("Root span has a parent")
+	}
+	if migration.Name != "migration down" || migration.Attributes["migrate.identifier"] != "create_users" ||
+		migration.Attributes["migrate.bytes_read"] != int64(42) || migration.Err == nil {
+		t.Errorf("Incorrect migration span was: %+v", migration)
+	}
+	if root.Attributes["migrate.version"] != int64(2) || root.Attributes["migrate.dirty"] != true {
+		t.Errorf("Incorrect root attributes were: %v", root.Attributes)
+	}
+
+	// the next span is a new root span
+	if s := tr.Start("migrate down"); s.ParentSpanID != [8]byte{} {
+		t.Errorf("Span after the root span ended has a parent")
+	}
+}
+
+func TestTracerTraceparent(t *testing.T) {
+	defer os.Unsetenv("TRACEPARENT")
+	os.Setenv("TRACEPARENT", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	tr := New("migrate", &recordingExporter{})
+	root := tr.Start("migrate up")
+	h := http.Header{}
+	tr.Inject(root, h)
+	if !strings.HasPrefix(h.Get("traceparent"), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Trace was not continued: %v", h.Get("traceparent"))
+	}
+	if root.ParentSpanID != [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7} {
+		t.Errorf("Incorrect parent span was: %x", root.ParentSpanID)
+	}
+
+	for _, invalid := range []string{"", "00-4bf92f3577b34da6a3ce929d0e0e4736", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		if _, _, ok := parseTraceparent(invalid); ok {
+			t.Errorf("Invalid traceparent %q was accepted", invalid)
+		}
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("Authorization") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	tr := New("migrate", &OTLPExporter{URL: ts.URL, Header: http.Header{"Authorization": {"secret"}}})
+	s := tr.Start("migrate up")
+	s.SetAttribute("migrate.steps", 3)
+	tr.End(s, nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans[0].Spans) != 1 {
+		t.Fatalf("Incorrect request was: %s", body)
+	}
+	if attrs := req.ResourceSpans[0].Resource.Attributes; len(attrs) != 1 || attrs[0].Value["stringValue"] != "migrate" {
+		t.Errorf("Incorrect resource was: %s", body)
+	}
+	span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if span.Name != "migrate up" || span.ParentSpanID != "" || span.Status.Code != 1 || len(span.TraceID) != 32 ||
+		len(span.Attributes) != 1 || span.Attributes[0].Value["intValue"] != "3" {
+		t.Errorf("Incorrect span was: %s", body)
+	}
+
+	// nothing to export
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr = New("migrate", &OTLPExporter{URL: ts.URL})
+	tr.End(tr.Start("migrate up"), nil)
+	if err := tr.Flush(); err == nil {
+		t.Error("Expected error for rejected export")
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	var b bytes.Buffer
+	tr := New("migrate", &FileExporter{W: &b})
+	tr.End(tr.Start("migrate up"), errors.New("no change"))
+	tr.End(tr.Start("migrate down"), nil)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Incorrect number of lines was: %v", len(lines))
+	}
+	if !strings.Contains(lines[0], `"status":{"code":2,"message":"no change"}`) || strings.Count(lines[0], `"spanId"`) != 2 {
+		t.Errorf("Incorrect line was: %v", lines[0])
+	}
+}